@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// ErrAssertionFailed indicates at least one -assert expression didn't hold.
+var ErrAssertionFailed = errors.New("assertion failed")
+
+// comparators in the order they're matched, so two-character operators take
+// precedence over their one-character prefixes.
+var comparators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// assertion expects the count of a protocol's events to compare to a
+// threshold, e.g., SSH>=1000.
+type assertion struct {
+	protocol   p.Protocol
+	comparator string
+	threshold  int
+}
+
+// String returns the assertion as an expression.
+func (a assertion) String() string {
+	return fmt.Sprintf("%s%s%d", a.protocol, a.comparator, a.threshold)
+}
+
+// holds reports whether the count satisfies the assertion.
+func (a assertion) holds(count int) bool {
+	switch a.comparator {
+	case ">=":
+		return count >= a.threshold
+	case "<=":
+		return count <= a.threshold
+	case "==":
+		return count == a.threshold
+	case "!=":
+		return count != a.threshold
+	case ">":
+		return count > a.threshold
+	default:
+		return count < a.threshold
+	}
+}
+
+// parseAssertions parses comma-separated expressions of a protocol name, a
+// comparator (>=, <=, ==, !=, >, <), and a non-negative threshold.
+func parseAssertions(exprs string) ([]assertion, error) {
+	if exprs == "" {
+		return nil, nil
+	}
+
+	var assertions []assertion
+	for _, expr := range strings.Split(exprs, ",") {
+		expr = strings.TrimSpace(expr)
+
+		var a assertion
+		i := -1
+		for _, c := range comparators {
+			if i = strings.Index(expr, c); i > 0 {
+				a.comparator = c
+				break
+			}
+		}
+		if a.comparator == "" {
+			return nil, fmt.Errorf("assertion %q lacks a comparator; use one of %s", expr, strings.Join(comparators, ", "))
+		}
+
+		var err error
+		a.protocol, err = p.ParseProtocol(strings.TrimSpace(expr[:i]))
+		if err != nil {
+			return nil, fmt.Errorf("assertion %q: %w", expr, err)
+		}
+		a.threshold, err = strconv.Atoi(strings.TrimSpace(expr[i+len(a.comparator):]))
+		if err != nil || a.threshold < 0 {
+			return nil, fmt.Errorf("assertion %q: threshold must be a non-negative integer", expr)
+		}
+
+		assertions = append(assertions, a)
+	}
+
+	return assertions, nil
+}
+
+// checkAssertions evaluates the assertions against the findings' protocol
+// counts, writing a PASS or FAIL line for each to w. It returns
+// ErrAssertionFailed if any assertion fails.
+func checkAssertions(w io.Writer, assertions []assertion, f *findings) error {
+	ew := &errWriter{w: w}
+	failed := 0
+	for _, a := range assertions {
+		count := 0
+		if o, ok := f.ByProtocol[a.protocol]; ok {
+			count = o.Occurrence
+		}
+
+		result := "PASS"
+		if !a.holds(count) {
+			result = "FAIL"
+			failed++
+		}
+		ew.printf("%s %s (%s=%d)\n", result, a, a.protocol, count)
+	}
+	if ew.err != nil {
+		return ew.err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d", ErrAssertionFailed, failed, len(assertions))
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_parseAssertions(t *testing.T) {
+	Convey("Given assertions using each comparator", t, func() {
+		exprs := "SSH>=2, http>0,SMTP<=1,TELNET==1,SSH!=3,HTTP<2"
+
+		Convey("When parsing them", func() {
+			assertions, err := parseAssertions(exprs)
+
+			Convey("It should parse each protocol, comparator, and threshold", func() {
+				So(err, ShouldBeNil)
+				So(assertions, ShouldResemble, []assertion{
+					{protocol: p.SSH, comparator: ">=", threshold: 2},
+					{protocol: p.HTTP, comparator: ">", threshold: 0},
+					{protocol: p.SMTP, comparator: "<=", threshold: 1},
+					{protocol: p.TELNET, comparator: "==", threshold: 1},
+					{protocol: p.SSH, comparator: "!=", threshold: 3},
+					{protocol: p.HTTP, comparator: "<", threshold: 2},
+				})
+			})
+		})
+	})
+
+	Convey("Given malformed assertions", t, func() {
+		for _, exprs := range []string{"SSH", "FTP>1", "SSH>=many", "SSH>-1", ">=1"} {
+			Convey("When parsing "+exprs, func() {
+				_, err := parseAssertions(exprs)
+
+				Convey("It should return an error", func() {
+					So(err, ShouldBeError)
+				})
+			})
+		}
+	})
+}
+
+func Test_checkAssertions(t *testing.T) {
+	Convey("Given findings of two SSH events and one each of SMTP, TELNET, and HTTP", t, func() {
+		f := new(findings)
+		for _, e := range validEvents {
+			f.Add(e)
+		}
+
+		Convey("When checking assertions that hold", func() {
+			assertions, err := parseAssertions("SSH>=2,HTTP>0,TELNET==1")
+			So(err, ShouldBeNil)
+			buf := new(bytes.Buffer)
+			err = checkAssertions(buf, assertions, f)
+
+			Convey("It should pass each of them", func() {
+				So(err, ShouldBeNil)
+				So(buf.String(), ShouldEqual, "PASS SSH>=2 (SSH=2)\nPASS HTTP>0 (HTTP=1)\nPASS TELNET==1 (TELNET=1)\n")
+			})
+		})
+
+		Convey("When checking assertions of which some fail", func() {
+			assertions, err := parseAssertions("SSH>=1000,SMTP>0,HTTP<1")
+			So(err, ShouldBeNil)
+			buf := new(bytes.Buffer)
+			err = checkAssertions(buf, assertions, f)
+
+			Convey("It should report the failures", func() {
+				So(errors.Is(err, ErrAssertionFailed), ShouldBeTrue)
+				So(err.Error(), ShouldEqual, "assertion failed: 2 of 3")
+				So(buf.String(), ShouldEqual, "FAIL SSH>=1000 (SSH=2)\nPASS SMTP>0 (SMTP=1)\nFAIL HTTP<1 (HTTP=1)\n")
+			})
+		})
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// benchmarkResult summarizes a benchmark run.
+type benchmarkResult struct {
+	Events  int
+	Elapsed time.Duration
+	Mallocs uint64 // heap allocations
+	Bytes   uint64 // heap bytes allocated
+}
+
+// String implements the fmt.Stringer interface.
+func (b benchmarkResult) String() string {
+	return fmt.Sprintf("processed %d events in %s: %.0f events/s, %.1f allocs/event, %.0f B/event",
+		b.Events, b.Elapsed, b.Throughput(),
+		float64(b.Mallocs)/float64(b.Events), float64(b.Bytes)/float64(b.Events),
+	)
+}
+
+// Throughput returns the events processed per second.
+func (b benchmarkResult) Throughput() float64 {
+	if b.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(b.Events) / b.Elapsed.Seconds()
+}
+
+// benchmark runs n synthetic events through the parse, validate, and aggregate
+// pipeline in memory, measuring the time and heap allocations it takes.
+func benchmark(rng *rand.Rand, n int) (benchmarkResult, error) {
+	if n < 1 {
+		return benchmarkResult{}, fmt.Errorf("benchmark requires at least 1 event")
+	}
+
+	// Marshal the events up front so only the pipeline is measured.
+	datagrams := make([][]byte, 0, n)
+	for _, e := range generateEvents(rng, n, uint32(time.Now().Unix())) {
+		b, err := e.MarshalBinary()
+		if err != nil {
+			return benchmarkResult{}, err
+		}
+		datagrams = append(datagrams, b)
+	}
+
+	var (
+		before, after runtime.MemStats
+		f             = new(findings)
+	)
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	for _, b := range datagrams {
+		e := new(p.Event)
+		if _, err := e.ReadFrom(bytes.NewReader(b)); err != nil {
+			return benchmarkResult{}, err
+		}
+		if !e.Valid() {
+			return benchmarkResult{}, fmt.Errorf("synthetic event %s is invalid", e.EventUUID.String())
+		}
+		f.Add(e)
+	}
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return benchmarkResult{
+		Events:  f.total(),
+		Elapsed: elapsed,
+		Mallocs: after.Mallocs - before.Mallocs,
+		Bytes:   after.TotalAlloc - before.TotalAlloc,
+	}, nil
+}
+
+// runBenchmark runs the benchmark of n events, profiling it to the given CPU
+// and memory profile paths, if any, and writes the result to w.
+func runBenchmark(w io.Writer, rng *rand.Rand, n int, cpuProfile, memProfile string) error {
+	stopCPUProfile, err := startCPUProfile(cpuProfile)
+	if err != nil {
+		return err
+	}
+	res, err := benchmark(rng, n)
+	if stopErr := stopCPUProfile(); stopErr != nil {
+		log.Warnf("stopping CPU profile: %v", stopErr)
+	}
+	if err != nil {
+		return err
+	}
+	if err = writeMemProfile(memProfile); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, res)
+
+	return err
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_benchmark(t *testing.T) {
+	Convey("Given a seeded random source", t, func() {
+		rng := rand.New(rand.NewSource(1))
+
+		Convey("When running a tiny benchmark", func() {
+			res, err := benchmark(rng, 100)
+
+			Convey("It should report non-zero throughput", func() {
+				So(err, ShouldBeNil)
+				So(res.Events, ShouldEqual, 100)
+				So(res.Throughput(), ShouldBeGreaterThan, 0)
+				So(res.Mallocs, ShouldBeGreaterThan, 0)
+				So(res.String(), ShouldContainSubstring, "processed 100 events")
+			})
+		})
+
+		Convey("When running a benchmark without events", func() {
+			_, err := benchmark(rng, 0)
+
+			Convey("It should return an error", func() {
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func Test_runBenchmark(t *testing.T) {
+	Convey("Given a seeded random source", t, func() {
+		rng := rand.New(rand.NewSource(1))
+
+		Convey("When running a profiled benchmark", func() {
+			var out bytes.Buffer
+			dir := t.TempDir()
+			cpu, mem := filepath.Join(dir, "cpu.pprof"), filepath.Join(dir, "mem.pprof")
+			err := runBenchmark(&out, rng, 100, cpu, mem)
+
+			Convey("It should write the result and both profiles", func() {
+				So(err, ShouldBeNil)
+				So(out.String(), ShouldStartWith, "processed 100 events")
+				for _, path := range []string{cpu, mem} {
+					fi, err := os.Stat(path)
+					So(err, ShouldBeNil)
+					So(fi.Size(), ShouldBeGreaterThan, 0)
+				}
+			})
+		})
+
+		Convey("When the CPU profile can't be created", func() {
+			var out bytes.Buffer
+			err := runBenchmark(&out, rng, 100, filepath.Join(t.TempDir(), "missing", "cpu.pprof"), "")
+
+			Convey("It should return the error rather than exit", func() {
+				So(err, ShouldBeError)
+				So(out.Len(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When the benchmark fails", func() {
+			err := runBenchmark(io.Discard, rng, 0, "", "")
+
+			Convey("It should return the error", func() {
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func Test_generateEvents(t *testing.T) {
+	Convey("Given a seeded random source", t, func() {
+		rng := rand.New(rand.NewSource(1))
+
+		Convey("When generating events", func() {
+			events := generateEvents(rng, 20, 1600000000)
+
+			Convey("It should generate valid events that survive a round trip", func() {
+				So(events, ShouldHaveLength, 20)
+				for _, e := range events {
+					So(e.Valid(), ShouldBeTrue)
+
+					b, err := e.MarshalBinary()
+					So(err, ShouldBeNil)
+
+					actual := new(p.Event)
+					_, err = actual.ReadFrom(bytes.NewReader(b))
+					So(err, ShouldBeNil)
+					So(actual.Valid(), ShouldBeTrue)
+					So(p.CompareEvents(actual, e), ShouldEqual, 0)
+					So(actual.Payload, ShouldResemble, e.Payload)
+				}
+			})
+		})
+	})
+}
@@ -5,16 +5,23 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"flag"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"math"
+	"math/rand"
 	"net"
 	"net/netip"
 	"os"
 	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 
 	log "github.com/sirupsen/logrus"
@@ -34,24 +41,239 @@ findings answering the following questions:
 	* What are the top 30 user-agents in HTTP events?
 	* What are the top 20 emails in SMTP?
 	* Who are the top 15 submitters?
+	* Which -cluster-prefix subnets are most active?
 	* What events did <ip-detail> submit?
 
 `
-	labelColor       = 32
 	minDatagramBytes = 512
 	maxDatagramBytes = 65535
+
+	// defaultIdleTimeout ends collection in -auto-count mode when the user
+	// didn't specify an idle timeout.
+	defaultIdleTimeout = 2 * time.Second
+
+	// defaultIntroTimeout is how long collection waits for the first datagram
+	// after the introduction before giving up on the server.
+	defaultIntroTimeout = 5 * time.Second
+
+	// minInvalidRateSample is the number of events collection reads before
+	// it evaluates -max-invalid-rate, so a few early invalid events don't
+	// abort it.
+	minInvalidRateSample = 100
+
+	// drainTimeout bounds how long collection spends parsing the datagrams
+	// still buffered when its context is canceled.
+	drainTimeout = 500 * time.Millisecond
+
+	// maxCachedDatagrams caps the number of datagrams buffered between
+	// reading and parsing, regardless of how the cache is expressed.
+	maxCachedDatagrams = 1 << 16
 )
 
+// ErrInvalidRate indicates collection aborted because too many events were
+// malformed or invalid.
+var ErrInvalidRate = errors.New("too many malformed or invalid events")
+
+// themes maps each -theme name to the ANSI SGR foreground color code used for
+// labels. The monochrome theme uses the reset code, rendering labels in the
+// terminal's default color.
+var themes = map[string]int{
+	"blue":    34,
+	"cyan":    36,
+	"green":   32,
+	"magenta": 35,
+	"mono":    0,
+	"yellow":  33,
+}
+
+// config holds the client's runtime options.
+type config struct {
+	AcrossProtocols   []string
+	Address           string
+	ArchiveRetries    int
+	Assert            string
+	AutoCount         bool
+	CACert            string
+	Cache             int
+	CacheDatagrams    int
+	Canonical         bool
+	Cardinality       bool
+	CheckCharset      bool
+	CheckUUID         bool
+	Classify          bool
+	ClientCert        string
+	ClientKey         string
+	ClusterPrefix     int
+	ClusterPrefix6    int
+	Compressed        bool
+	Continuous        bool
+	Corrupt           float64
+	CPUProfile        string
+	CRCSample         int
+	Datagrams         int
+	Dedup             string
+	DedupReport       bool
+	DetailIP          netip.Addr
+	DOT               string
+	EventsOut         string
+	Expect            string
+	Explain           bool
+	ExplainInvalid    bool
+	Extract           string
+	ExtractProtocol   string
+	FieldWidths       string
+	Flatten           map[string]string
+	Format            string
+	GeoIP             string
+	Head              int
+	IdleTimeout       time.Duration
+	InvalidJSON       string
+	IntroTimeout      time.Duration
+	KeepRaw           bool
+	LabelColor        int
+	LimitBytes        int64
+	ListUUIDs         bool
+	LittleEndian      bool
+	Location          *time.Location
+	LowMemory         bool
+	MaskCredentials   bool
+	MaxInvalidRate    float64
+	MaxPayloadBytes   int
+	MemProfile        string
+	Network           string
+	NoReport          bool
+	NormalizeKeys     bool
+	OpenMetrics       string
+	PayloadBase64     bool
+	PayloadSizes      bool
+	ProgressPrecision int
+	Quiet             bool
+	Rand              *rand.Rand
+	Reconnects        int
+	Report            bool
+	ReportInterval    time.Duration
+	Reporter          reporter
+	Replay            string
+	ReplayCount       int
+	ReplayLoop        bool
+	Reputation        string
+	Sections          []string
+	SequentialUUIDs   bool
+	Sentinel          []byte
+	Size              int
+	SkewThreshold     time.Duration
+	SkipEmpty         bool
+	SockBuf           int
+	Strict            bool
+	Summary           bool
+	Syslog            string
+	Tail              int
+	TLS               bool
+	TimeFormat        string
+	Topology          bool
+	Watch             time.Duration
+}
+
 func main() {
 	var (
-		address   = flag.String("address", "localhost:1035", "event server host:port")
-		cache     = flag.Int("cache", 20, "MB of RAM to use for caching datagrams (min 1)")
-		datagrams = flag.Int("datagrams", 37529, "datagrams to read from event server")
-		detailIP  = flag.String("ip-detail", "1.2.3.4", "detail events submitted by a given IP")
-		size      = flag.Int("datagram-size", minDatagramBytes,
+		across         = flag.String("across-protocols", "", "comma-separated payload keys (email, password, user-agent, username) whose top values to rank across all protocols in the report")
+		address        = flag.String("address", "localhost:1035", "event server host:port")
+		archiveRetries = flag.Int("archive-retries", defaultArchiveRetries, "retry writing -events-out this many times, backing off, should the disk fill")
+		assert         = flag.String("assert", "", "comma-separated protocol count assertions (e.g., SSH>=1000,HTTP>0) checked after collection, exiting non-zero should any fail")
+		autoCount      = flag.Bool("auto-count", false, "ignore -datagrams and read until the server goes idle for -idle-timeout")
+		bench          = flag.Int("benchmark", 0, "process this many synthetic events in memory, print throughput and allocation stats, and exit (0 disables)")
+		caCert         = flag.String("ca-cert", "", "with -tls, verify the server certificate against the CA certificates in this PEM file (defaults to the system CAs)")
+		cache          = flag.Int("cache", 20, "MB of RAM to use for caching datagrams (min 1)")
+		cacheDatagrams = flag.Int("cache-datagrams", 0,
+			fmt.Sprintf("datagrams to cache, overriding -cache (max %d)", maxCachedDatagrams),
+		)
+		canonical    = flag.Bool("canonical", false, "sort -events-out events by time stamp and UUID for reproducible archives")
+		cardinality  = flag.Bool("cardinality", false, "count the distinct values and occurrences of each aggregated payload key by protocol in the report")
+		checkCharset = flag.Bool("check-charset", false, "report events whose payloads contain control characters, other unprintable characters, or invalid UTF-8, apart from checksum failures")
+		checkUUID    = flag.Bool("check-uuid", false, "warn when event UUIDs are not RFC 4122 version 1 with a MAC node")
+		classify     = flag.Bool("classify", false, "break down payload values by classification (e.g., numeric-only passwords, bot user-agents) in the report")
+		clientCert   = flag.String("client-cert", "", "with -tls, present this PEM certificate to the server for mutual TLS (requires -client-key)")
+		clientKey    = flag.String("client-key", "", "with -tls, the PEM private key of -client-cert")
+		clusterLen   = flag.Int("cluster-prefix", 24, "IPv4 prefix length used to aggregate IPv4 submitters into subnets (1-32; 0 disables)")
+		clusterLen6  = flag.Int("cluster-prefix6", 64, "IPv6 prefix length used to aggregate IPv6 submitters into subnets (1-128; 0 disables)")
+		compressed   = flag.Bool("compressed", false, "gunzip datagrams that begin with the gzip magic bytes before parsing them (udp only)")
+		continuous   = flag.Bool("continuous", false, "ignore -datagrams and read until interrupted, printing the report every -report-interval")
+		corrupt      = flag.Float64("corrupt", 0, "flip a random bit in this fraction (0-1) of datagrams before parsing them, to test the handling of corrupt events")
+		cpuProfile   = flag.String("cpuprofile", "", "write a CPU profile of event collection to the given file (pairs well with -benchmark)")
+		crcSample    = flag.Int("crc-sample", 0, "compare the stored, IEEE, and Castagnoli checksums of up to this many invalid events in the report, revealing an emitter using another CRC polynomial (0 disables)")
+		datagrams    = flag.Int("datagrams", 37529, "datagrams to read from event server")
+		dedup        = flag.String("dedup", "", "collapse duplicate events by these comma-separated modes: uuid, fingerprint (payload and fields but UUID and checksum)")
+		dedupReport  = flag.Bool("dedup-report", false, "analyze retransmissions in the report: how many copies of each event arrived, by protocol and submitter (requires -dedup)")
+		dotOut       = flag.String("dot", "", "write a Graphviz DOT graph of submitters and the protocols of their events to the given file")
+		detailIP     = flag.String("ip-detail", "1.2.3.4", "detail events submitted by a given IP")
+		explain      = flag.Bool("explain", false, "print a field-by-field breakdown of the first event received")
+		explainInv   = flag.Bool("explain-invalid", false, "summarize the malformed and invalid events in the report by failure mode, submitter, and protocol")
+		eventsOut    = flag.String("events-out", "", "write the valid events' binary equivalents to the given file")
+		expect       = flag.String("expect", "", "report events whose checksums differ from those in the given file of UUID and checksum pairs, and expected events never received")
+		extract      = flag.String("extract", "", "print the unique values of this payload key, most frequent first, one per line, instead of the report")
+		extractProto = flag.String("extract-protocol", "", "with -extract, only extract values from events of this protocol")
+		fieldWidths  = flag.String("field-widths", "", "override the byte widths of event fields for emitter builds with a nonstandard layout, as comma-separated field=width pairs (e.g., NodeID=4); fields are NodeID, TimeStamp, Size (1, 2, 4, or 8), Submitter (4 or 16), and CheckSum (2 or 4)")
+		flatten      = flag.String("flatten", "", "comma-separated payload keys whose values hold nested key=value pairs, separated by semicolons, to promote into the payload as key.nested, each optionally renamed by a prefix (e.g., user-agent=ua)")
+		flushEvery   = flag.Duration("flush-interval", flushInterval, "flush buffered export files and -list-uuids output this often, as well as on exit (0 flushes only once the buffer fills)")
+		format       = flag.String("format", "terminal", "render the report for the terminal or as GitHub-flavored Markdown tables for pasting into tickets and wikis: terminal or markdown")
+		geoIP        = flag.String("geoip", "", "report the top countries of submitters, resolved by this CSV file of network and country code pairs (e.g., 203.0.113.0/24,AU)")
+		head         = flag.Int("head", 0, "print a preview of the first N events collected instead of the report (see -report)")
+		idle         = flag.Duration("idle-timeout", 0,
+			fmt.Sprintf("stop reading and report after receiving no datagrams for this duration (0 disables; %s with -auto-count)", defaultIdleTimeout),
+		)
+		introTimeout = flag.Duration("intro-timeout", defaultIntroTimeout, "give up if the server sends nothing within this long after the introduction (0 waits indefinitely)")
+		invalidJSON  = flag.String("invalid-json", "", "write each malformed or invalid event to the given file as a line of JSON detailing the failure")
+		keepRaw      = flag.Bool("keep-raw", false, "retain each event's complete original datagram for byte-perfect forwarding, at the cost of memory")
+		limitBytes   = flag.Int64("limit-bytes", 0, "stop reading and report after ingesting this many bytes in total (0 disables)")
+		listUUIDs    = flag.Bool("list-uuids", false, "print the UUID of each collected event to stdout, one per line, instead of a progress bar")
+		littleEnd    = flag.Bool("little-endian", false, "parse events from a legacy emitter that sends little-endian integers")
+		size         = flag.Int("datagram-size", minDatagramBytes,
 			fmt.Sprintf("maximum UDP datagram size (min %d; max %d)", minDatagramBytes, maxDatagramBytes),
 		)
-		verbose = flag.Bool("v", false, "enable verbose (debug) output")
+		lowMemory = flag.Bool("low-memory", false,
+			"retain only aggregate counts and the -ip-detail submitter's events (incompatible with -events-out)",
+		)
+		maskCreds    = flag.Bool("mask-credentials", false, "mask report passwords and usernames with asterisks, e.g., for screen-sharing")
+		maxInvalid   = flag.Float64("max-invalid-rate", 0, fmt.Sprintf("abort collection should more than this fraction (0-1) of events be malformed or invalid, once %d have arrived (0 disables)", minInvalidRateSample))
+		maxPayload   = flag.Int("max-payload-bytes", maxDatagramBytes, "discard events whose Size exceeds this many payload bytes before allocating their payloads, counting them as oversized (0 disables)")
+		memProfile   = flag.String("memprofile", "", "write a memory profile taken after aggregation to the given file (pairs well with -benchmark)")
+		network      = flag.String("network", "udp", "transport used to reach the event server: udp, tcp, or unixgram (with a socket path as the -address)")
+		noReport     = flag.Bool("no-report", false, "skip the report, only collecting events for the configured outputs (e.g., -events-out)")
+		normalize    = flag.Bool("normalize-keys", false, "lowercase and trim payload keys so case variations aggregate together")
+		openMetrics  = flag.String("openmetrics", "", "write the event counts by protocol, top submitter, and discard reason to the given file in the OpenMetrics text format")
+		outputBuffer = flag.Int("output-buffer", defaultOutputBuffer, "bytes of export files and -list-uuids output to buffer, batching writes (0 writes unbuffered)")
+		payloadB64   = flag.Bool("payload-base64", false, "base64-decode event payloads before parsing them")
+		payloadSizes = flag.Bool("payload-sizes", false, "summarize the payload sizes of each protocol in the report")
+		progPrec     = flag.Int("progress-precision", 1, "the number of decimal places in the progress bar's percentage (0-6)")
+		quiet        = flag.Bool("quiet", false, "suppress all output but the report and errors")
+		reconnects   = flag.Int("reconnects", 3, "with -network tcp, consecutive attempts to reconnect after the server closes the connection")
+		report       = flag.Bool("report", false, "with -head or -tail, print the report after the preview")
+		reputation   = flag.String("reputation", "", "mark submitters in this file of known-bad IP addresses and CIDR networks, one per line, as KNOWN BAD in the report")
+		reportWidth  = flag.Int("report-width", 0, "render the report and progress bar this many columns wide, regardless of the terminal (0 detects the terminal's width)")
+		replay       = flag.String("replay", "", "replay the events of a capture written by -events-out, one per datagram, in place of the event server")
+		replayCount  = flag.Int("replay-count", 0, "with -replay-loop, the number of times to replay the capture (0 replays until interrupted)")
+		replayLoop   = flag.Bool("replay-loop", false, "with -replay, restart from the beginning of the capture upon reaching its end, for sustained load")
+		reportEvery  = flag.Duration("report-interval", 10*time.Second, "with -continuous, how often to print the report")
+		seed         = flag.Int64("seed", 0, "seed for all random choices (e.g., -corrupt, -benchmark), for reproducible runs (0 seeds from the clock)")
+		sections     = flag.String("sections", "", "comma-separated report sections to render, in order (empty renders all): "+sectionNames())
+		sentinel     = flag.String("sentinel", "", "stop collecting upon receiving a datagram equal to this string (empty disables)")
+		sequential   = flag.Bool("sequential-uuids", false, "report runs of sequential or near-sequential event UUIDs, which suggest the emitter's UUIDs are predictable")
+		skew         = flag.Duration("skew-threshold", 0,
+			"report events whose version 1 UUID time and time stamp differ by more than this duration (0 disables)",
+		)
+		skipEmpty  = flag.Bool("skip-empty", false, "discard valid events whose payloads are empty")
+		sockBuf    = flag.Int("sockbuf", 0, "bytes to request for the socket receive buffer, reducing drops under heavy load (0 leaves the OS default)")
+		strict     = flag.Bool("strict", false, "abort collection upon the first malformed datagram")
+		summary    = flag.Bool("summary", false, "print a one-line summary of key=value counts instead of the report")
+		syslogAddr = flag.String("syslog", "", "send findings to the syslog server at network:host:port (e.g., udp:localhost:514)")
+		tail       = flag.Int("tail", 0, "print a preview of the last N events collected instead of the report (see -report)")
+		theme      = flag.String("theme", "green", "label color theme: "+themeNames())
+		useTLS     = flag.Bool("tls", false, "with -network tcp, connect to the server over TLS")
+		timeFormat = flag.String("time-format", timeFormatDateTime, "render report times as epoch, date, datetime, or a Go time layout (e.g., 2006-01-02T15:04:05Z07:00)")
+		timezone   = flag.String("timezone", "Local", "IANA time zone used to render times (e.g., UTC, America/Chicago)")
+		topology   = flag.Bool("topology", false, "cross-tabulate the emitters' node IDs against the protocols of their events in the report")
+		verbose    = flag.Bool("v", false, "enable verbose (debug) output")
+		watchEvery = flag.Duration("watch", 0, "re-run collection and the report at this interval until interrupted (0 disables)")
 	)
 	flag.Usage = func() {
 		_, _ = fmt.Fprint(flag.CommandLine.Output(), desc)
@@ -60,89 +282,680 @@ func main() {
 	}
 	flag.Parse()
 
-	if *verbose {
+	// Report a reader closing stdout early (e.g., | head) as an EPIPE error
+	// rather than dying of SIGPIPE, so main can exit quietly.
+	signal.Ignore(syscall.SIGPIPE)
+
+	switch {
+	case *quiet:
+		log.SetLevel(log.ErrorLevel)
+	case *verbose:
 		log.SetLevel(log.DebugLevel)
 	}
 
+	rng, rngSeed := newRand(*seed)
+	log.Debugf("seeding random choices with %d", rngSeed)
+
+	if *bench > 0 {
+		if err := runBenchmark(os.Stdout, rng, *bench, *cpuProfile, *memProfile); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	detailAddr, err := netip.ParseAddr(*detailIP)
 	if err != nil {
 		log.Warnf("parsing detail IP: %v", err)
 	}
 
-	if err = run(*address, *datagrams, *size, *cache, detailAddr); err != nil {
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		log.Warnf("loading time zone: %v; defaulting to local time", err)
+		loc = time.Local
+	}
+
+	labelColor, ok := themes[strings.ToLower(*theme)]
+	if !ok {
+		log.Warnf("unknown theme %q; defaulting to green", *theme)
+		labelColor = themes["green"]
+	}
+
+	clusterPrefix := *clusterLen
+	if clusterPrefix < 0 || clusterPrefix > 32 {
+		log.Warnf("%d is not a valid IPv4 prefix length; defaulting to 24", clusterPrefix)
+		clusterPrefix = 24
+	}
+	clusterPrefix6 := *clusterLen6
+	if clusterPrefix6 < 0 || clusterPrefix6 > 128 {
+		log.Warnf("%d is not a valid IPv6 prefix length; defaulting to 64", clusterPrefix6)
+		clusterPrefix6 = 64
+	}
+
+	if *reportWidth < 0 {
+		log.Warnf("%d is not a valid report width; detecting the terminal's width", *reportWidth)
+	} else {
+		fixedColumns = *reportWidth
+	}
+
+	if *outputBuffer < 0 {
+		log.Warnf("%d is not a valid output buffer size; defaulting to %d", *outputBuffer, defaultOutputBuffer)
+	} else {
+		outputBufferSize = *outputBuffer
+	}
+	flushInterval = *flushEvery
+
+	progressPrecision := *progPrec
+	if progressPrecision < 0 || progressPrecision > 6 {
+		log.Warnf("%d is not a valid progress precision; defaulting to 1", progressPrecision)
+		progressPrecision = 1
+	}
+
+	var selectedSections []string
+	if *sections != "" {
+		selectedSections = strings.Split(*sections, ",")
+	}
+	var acrossProtocols []string
+	if *across != "" {
+		for _, key := range strings.Split(*across, ",") {
+			acrossProtocols = append(acrossProtocols, strings.TrimSpace(key))
+		}
+	}
+
+	var flattenKeys map[string]string
+	if *flatten != "" {
+		flattenKeys = make(map[string]string)
+		for _, key := range strings.Split(*flatten, ",") {
+			key, prefix, ok := strings.Cut(key, "=")
+			key = strings.TrimSpace(key)
+			if !ok {
+				prefix = key
+			}
+			flattenKeys[key] = strings.TrimSpace(prefix)
+		}
+	}
+
+	cfg := config{
+		AcrossProtocols:   acrossProtocols,
+		Address:           *address,
+		ArchiveRetries:    *archiveRetries,
+		Assert:            *assert,
+		AutoCount:         *autoCount,
+		CACert:            *caCert,
+		Cache:             *cache,
+		CacheDatagrams:    *cacheDatagrams,
+		Canonical:         *canonical,
+		Cardinality:       *cardinality,
+		CheckCharset:      *checkCharset,
+		CheckUUID:         *checkUUID,
+		Classify:          *classify,
+		ClientCert:        *clientCert,
+		ClientKey:         *clientKey,
+		ClusterPrefix:     clusterPrefix,
+		ClusterPrefix6:    clusterPrefix6,
+		Compressed:        *compressed,
+		Continuous:        *continuous,
+		Corrupt:           *corrupt,
+		CPUProfile:        *cpuProfile,
+		CRCSample:         *crcSample,
+		Datagrams:         *datagrams,
+		Dedup:             *dedup,
+		DedupReport:       *dedupReport,
+		DetailIP:          detailAddr,
+		DOT:               *dotOut,
+		EventsOut:         *eventsOut,
+		Expect:            *expect,
+		Explain:           *explain,
+		ExplainInvalid:    *explainInv,
+		Extract:           *extract,
+		ExtractProtocol:   *extractProto,
+		FieldWidths:       *fieldWidths,
+		Flatten:           flattenKeys,
+		Format:            *format,
+		GeoIP:             *geoIP,
+		Head:              *head,
+		IdleTimeout:       *idle,
+		InvalidJSON:       *invalidJSON,
+		IntroTimeout:      *introTimeout,
+		KeepRaw:           *keepRaw,
+		LabelColor:        labelColor,
+		LimitBytes:        *limitBytes,
+		ListUUIDs:         *listUUIDs,
+		LittleEndian:      *littleEnd,
+		Location:          loc,
+		LowMemory:         *lowMemory,
+		MaskCredentials:   *maskCreds,
+		MaxInvalidRate:    *maxInvalid,
+		MaxPayloadBytes:   *maxPayload,
+		MemProfile:        *memProfile,
+		Network:           *network,
+		NoReport:          *noReport,
+		NormalizeKeys:     *normalize,
+		OpenMetrics:       *openMetrics,
+		PayloadBase64:     *payloadB64,
+		PayloadSizes:      *payloadSizes,
+		ProgressPrecision: progressPrecision,
+		Quiet:             *quiet,
+		Rand:              rng,
+		Reconnects:        *reconnects,
+		Report:            *report,
+		ReportInterval:    *reportEvery,
+		Replay:            *replay,
+		ReplayCount:       *replayCount,
+		ReplayLoop:        *replayLoop,
+		Reputation:        *reputation,
+		Sections:          selectedSections,
+		SequentialUUIDs:   *sequential,
+		Sentinel:          []byte(*sentinel),
+		Size:              *size,
+		SkewThreshold:     *skew,
+		SkipEmpty:         *skipEmpty,
+		SockBuf:           *sockBuf,
+		Strict:            *strict,
+		Summary:           *summary,
+		Syslog:            *syslogAddr,
+		Tail:              *tail,
+		TLS:               *useTLS,
+		TimeFormat:        *timeFormat,
+		Topology:          *topology,
+		Watch:             *watchEvery,
+	}
+
+	if cfg.Watch > 0 {
+		err = watch(cfg, 0)
+	} else {
+		err = run(cfg)
+	}
+	switch {
+	case errors.Is(err, ErrAssertionFailed):
+		log.Error(err)
+		os.Exit(1)
+	case brokenPipe(err):
+		log.Debugf("output reader closed the pipe early: %v", err)
+	case errors.Is(err, ErrNoEvents):
+		log.Warnf("no data collected; is the event server running at %q?", cfg.Address)
+	case errors.Is(err, ErrMissingProtocol):
+		log.Warnf("the report is incomplete: %v", err)
+	case err != nil:
 		log.Error(err)
 	}
 }
 
-func collectEvents(
-	ctx context.Context, conn net.Conn, datagrams, size, cache int,
-) ([]*p.Event, error) {
+// collectEvents reads datagrams from conn, parses them, and adds the valid
+// events to the findings. In continuous mode, it reads until the context is
+// done, handing the findings to the configured reporter, if not nil, every
+// report interval.
+func collectEvents(ctx context.Context, conn net.Conn, cfg config, f *findings) error {
 	switch {
-	case datagrams < 1:
-		return nil, fmt.Errorf("no datagrams read from the server")
-	case size < minDatagramBytes:
-		log.Warnf("%d is below the minimum datagram size; defaulting to %d", size, minDatagramBytes)
-		size = minDatagramBytes
-	case size > maxDatagramBytes:
-		log.Warnf("%d exceeds the maximum datagram size; defaulting to %d", size, maxDatagramBytes)
-		size = maxDatagramBytes
+	case cfg.Continuous:
+		// Only an interruption ends continuous collection.
+		cfg.IdleTimeout = 0
+	case cfg.AutoCount && cfg.IdleTimeout <= 0:
+		log.Debugf("auto-counting datagrams; defaulting to a %s idle timeout", defaultIdleTimeout)
+		cfg.IdleTimeout = defaultIdleTimeout
+	case !cfg.AutoCount && cfg.Datagrams < 1:
+		return fmt.Errorf("no datagrams read from the server")
+	}
+
+	if cfg.Corrupt < 0 || cfg.Corrupt > 1 {
+		return fmt.Errorf("corruption rate %v is not between 0 and 1", cfg.Corrupt)
+	}
+	if cfg.MaxInvalidRate < 0 || cfg.MaxInvalidRate > 1 {
+		return fmt.Errorf("maximum invalid rate %v is not between 0 and 1", cfg.MaxInvalidRate)
+	}
+	if cfg.MaxPayloadBytes < 0 {
+		return fmt.Errorf("maximum payload bytes %d is negative", cfg.MaxPayloadBytes)
+	}
+
+	switch {
+	case cfg.Size < minDatagramBytes:
+		log.Warnf("%d is below the minimum datagram size; defaulting to %d", cfg.Size, minDatagramBytes)
+		cfg.Size = minDatagramBytes
+	case cfg.Size > maxDatagramBytes:
+		log.Warnf("%d exceeds the maximum datagram size; defaulting to %d", cfg.Size, maxDatagramBytes)
+		cfg.Size = maxDatagramBytes
 	}
 
 	// Decouple datagram reading from parsing, since the latter will likely take
-	// longer on some systems (e.g., Linux in Docker on an M1 Mac). At minimum,
-	// use 1MB of RAM to cache incoming datagrams.
-	if cache < 1 {
-		cache = 1
+	// longer on some systems (e.g., Linux in Docker on an M1 Mac).
+	capacity := cacheCapacity(cfg.Cache, cfg.CacheDatagrams, cfg.Size)
+	log.Debugf("caching up to %d datagrams", capacity)
+	chDatagrams := make(chan io.Reader, capacity)
+	if cfg.Network == "tcp" {
+		var order binary.ByteOrder = binary.BigEndian
+		if cfg.LittleEndian {
+			order = binary.LittleEndian
+		}
+		l, err := fieldLayout(cfg.FieldWidths, p.Version1)
+		if err != nil {
+			return err
+		}
+		if l == nil {
+			l = &p.Version1Layout
+		}
+		dial := func(ctx context.Context) (net.Conn, error) { return dialServer(ctx, cfg) }
+		go readStream(ctx, conn, chDatagrams, order, *l, dial, cfg.Reconnects, cfg.IdleTimeout, cfg.LimitBytes)
+	} else {
+		go readDatagrams(ctx, conn, chDatagrams, cfg.Size, cfg.IdleTimeout, cfg.LimitBytes)
 	}
-	chDatagrams := make(chan io.Reader, (cache<<20)/size)
-	go readDatagrams(ctx, conn, chDatagrams, size)
 
 	// The server needs to know our address before it can emit events to us.
 	// Since UDP is stateless, we need to reach out first. We're already
 	// listening, minimizing the chance we'll miss any datagrams.
-	n, err := conn.Write([]byte("Feed me, Seymour!"))
+	err := introduce(conn)
+	if err != nil {
+		return err
+	}
+
+	dedup, err := newDeduper(cfg.Dedup, cfg.DedupReport)
 	if err != nil {
-		return nil, fmt.Errorf("writing introduction: %w", err)
+		return err
+	}
+
+	layout, err := fieldLayout(cfg.FieldWidths, p.Version1)
+	if err != nil {
+		return err
+	}
+
+	var corrupt *corrupter
+	if cfg.Corrupt > 0 {
+		rng := cfg.Rand
+		if rng == nil {
+			var seed int64
+			rng, seed = newRand(0)
+			log.Debugf("seeding random choices with %d", seed)
+		}
+		log.Infof("corrupting %.1f%% of datagrams", cfg.Corrupt*100)
+		corrupt = newCorrupter(cfg.Corrupt, rng)
 	}
-	log.Debugf("wrote %d-byte introduction to the server", n)
 
 	var (
-		events []*p.Event
-		ok     bool
-		r      io.Reader
+		corrupted  int
+		counted    bool   // whether the datagrams were checked for a count header
+		digest     uint32 // of the checksums of the events read, for a digest trailer
+		empty      int
+		events     int // read, whether or not they're malformed or invalid
+		explained  bool
+		invalid    int
+		malformed  int
+		negotiated bool
+		oversized  int
+		version    uint8 // zero until negotiated, meaning version 1
+		ok         bool
+		received   int
+		r          io.Reader
+
+		// Once the context is canceled, collection drains the datagrams
+		// already buffered, until drainBy, rather than losing them.
+		draining bool
+		drainBy  time.Time
 	)
 
+	// Fail fast if the server never answers the introduction.
+	var introTimeout <-chan time.Time
+	if cfg.IntroTimeout > 0 {
+		t := time.NewTimer(cfg.IntroTimeout)
+		defer t.Stop()
+		introTimeout = t.C
+	}
+
+	var tick <-chan time.Time
+	if cfg.Continuous && cfg.Reporter != nil && cfg.ReportInterval > 0 {
+		t := time.NewTicker(cfg.ReportInterval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	// Listed UUIDs are batched, flushing periodically and once collection
+	// ends, so none are lost.
+	var uuids *flushWriter
+	if cfg.ListUUIDs {
+		uuids = newFlushWriter(os.Stdout, outputBufferSize, flushInterval)
+		defer func() { _ = uuids.Close() }()
+	}
+
+	// A count header may reveal the number of datagrams only after
+	// collection begins, so tracking progress may begin then too.
+	var tracker *progressTracker
+	trackProgress := func() {
+		if tracker == nil && !cfg.Quiet && !cfg.AutoCount && !cfg.Continuous && !cfg.ListUUIDs && cfg.Extract == "" {
+			tracker = newProgressTracker(cfg.Datagrams, progressInterval, func(step, total int) {
+				progress(step, total, cfg.LabelColor, cfg.ProgressPrecision)
+			})
+		}
+	}
+	trackProgress()
+	defer func() { tracker.Stop() }()
+
 OUTER:
-	for i := 1; i <= datagrams; i++ {
-		select {
-		case <-ctx.Done():
-			break OUTER
-		case r, ok = <-chDatagrams:
-			if !ok {
-				log.Debug("datagram channel closed")
+	// In auto-count mode, read until readDatagrams closes the channel, which
+	// it does once the server goes idle. In continuous mode, read until the
+	// context is done. Either way, a canceled context stops reading only after
+	// the buffered datagrams are drained.
+	for i := 1; cfg.AutoCount || cfg.Continuous || i <= cfg.Datagrams; i++ {
+		if draining {
+			if f.now().After(drainBy) {
+				log.Warnf("abandoning %d buffered datagrams after draining for %s", len(chDatagrams), drainTimeout)
 				break OUTER
 			}
+
+			select {
+			case r, ok = <-chDatagrams:
+				if !ok {
+					log.Debug("datagram channel closed")
+					break OUTER
+				}
+			default:
+				log.Debug("drained buffered datagrams")
+				break OUTER
+			}
+		} else {
+			select {
+			case <-ctx.Done():
+				log.Debugf("context canceled; draining %d buffered datagrams", len(chDatagrams))
+				draining = true
+				drainBy = f.now().Add(drainTimeout)
+				i--
+
+				continue
+			case <-introTimeout:
+				return fmt.Errorf("%w (waited %s)", ErrNoResponse, cfg.IntroTimeout)
+			case <-tick:
+				switch err := cfg.Reporter.Report(f); {
+				case brokenPipe(err):
+					// Nobody reads the reports any longer (e.g., | head).
+					return fmt.Errorf("reporting: %w", err)
+				case errors.Is(err, ErrNoEvents):
+					log.Debug("no events to report yet")
+				case errors.Is(err, ErrMissingProtocol):
+					log.Debugf("the report is incomplete so far: %v", err)
+				case err != nil:
+					log.Warnf("generating report: %v", err)
+				}
+
+				continue
+			case r, ok = <-chDatagrams:
+				if !ok {
+					log.Debug("datagram channel closed")
+					break OUTER
+				}
+				introTimeout = nil
+			}
 		}
 
-		progress(i, datagrams)
+		if !negotiated {
+			negotiated = true
+			if v, ok := versionDatagram(r); ok {
+				version = v
+				log.Infof("server negotiated protocol version %d", version)
+				if layout, err = fieldLayout(cfg.FieldWidths, version); err != nil {
+					return err
+				}
 
-		e := new(p.Event)
-		switch _, err = e.ReadFrom(r); {
-		case err != nil:
-			return nil, err
-		case !e.Valid():
-			log.Warnf("event %s is invalid; discarding it", e.EventUUID.String())
+				// The version datagram doesn't count toward the datagrams.
+				i--
+				continue
+			}
+		}
+
+		if !counted {
+			counted = true
+			if n, ok := countDatagram(r); ok {
+				log.Infof("server announced %d datagrams", n)
+				if !cfg.Continuous {
+					cfg.Datagrams, cfg.AutoCount = n, false
+					if tracker != nil {
+						tracker.SetTotal(n)
+					} else {
+						trackProgress()
+					}
+				}
+
+				// Nor does the count header.
+				i--
+				continue
+			}
+		}
+
+		if expected, ok := digestDatagram(r); ok {
+			if digest == expected {
+				log.Infof("stream digest %08x matches the server's", digest)
+			} else {
+				log.Warnf("stream digest %08x differs from the server's %08x; datagrams were possibly lost or reordered", digest, expected)
+			}
+			if !cfg.Continuous {
+				break OUTER
+			}
+
+			// The next stream begins its own digest. The trailer doesn't
+			// count toward the datagrams.
+			digest = 0
+			i--
 			continue
 		}
 
-		events = append(events, e)
+		// The sentinel ends the stream rather than counting as a datagram.
+		if len(cfg.Sentinel) > 0 {
+			if b, ok := r.(interface{ Bytes() []byte }); ok && bytes.Equal(b.Bytes(), cfg.Sentinel) {
+				log.Debug("received sentinel datagram")
+				break OUTER
+			}
+		}
+
+		received++
+		tracker.Add(1)
+		receivedAt := f.now()
+
+		// Retain a copy of the datagram before parsing consumes it, so the
+		// event doesn't pin the whole read buffer.
+		var raw []byte
+		if cfg.KeepRaw {
+			if b, ok := r.(interface{ Bytes() []byte }); ok {
+				raw = bytes.Clone(b.Bytes())
+			}
+		}
+
+		if cfg.Compressed {
+			if r, err = decompress(r); err != nil {
+				if cfg.Strict {
+					return err
+				}
+				malformed++
+				log.Warnf("discarding malformed datagram: %v", err)
+
+				continue
+			}
+		}
+
+		if corrupt != nil {
+			var flipped bool
+			if r, flipped = corrupt.corrupt(r); flipped {
+				corrupted++
+			}
+		}
+
+		// The server occasionally packs several events into one datagram, so
+		// read events until the datagram is exhausted.
+	EVENTS:
+		for {
+			e := &p.Event{
+				Base64Payload:   cfg.PayloadBase64,
+				Flatten:         cfg.Flatten,
+				Layout:          layout,
+				Raw:             raw,
+				MaxPayloadBytes: cfg.MaxPayloadBytes,
+				NormalizeKeys:   cfg.NormalizeKeys,
+				ReceivedAt:      receivedAt,
+				Version:         version,
+			}
+			if cfg.LittleEndian {
+				e.ByteOrder = binary.LittleEndian
+			}
+
+			events++
+			var pse *p.PayloadSizeError
+			switch _, err = e.ReadFrom(r); {
+			case err != nil && cfg.Strict:
+				return err
+			case errors.As(err, &pse):
+				// An oversized event is well-formed as far as it was read,
+				// but its payload isn't worth the memory.
+				oversized++
+				log.Warnf("discarding oversized event %s: %v", e.EventUUID.String(), err)
+				if cfg.InvalidJSON != "" || cfg.ExplainInvalid || cfg.CRCSample > 0 {
+					f.Invalid = append(f.Invalid, rejection{Event: e, Err: err})
+				}
+				break EVENTS
+			case err != nil:
+				// One malformed datagram shouldn't cost us everything we've
+				// collected so far.
+				malformed++
+				log.Warnf("discarding malformed datagram: %v", err)
+				if cfg.InvalidJSON != "" || cfg.ExplainInvalid || cfg.CRCSample > 0 {
+					f.Invalid = append(f.Invalid, rejection{Event: e, Err: err})
+				}
+				break EVENTS
+			case cfg.Explain && !explained:
+				explained = true
+				if err = e.Explain(os.Stdout); err != nil {
+					return fmt.Errorf("explaining event: %w", err)
+				}
+			}
+			digest = streamDigest(digest, e.CheckSum)
+
+			switch {
+			case !e.Valid():
+				invalid++
+				log.Warnf("event %s is invalid; discarding it", e.EventUUID.String())
+				if cfg.InvalidJSON != "" || cfg.ExplainInvalid || cfg.CRCSample > 0 {
+					f.Invalid = append(f.Invalid, rejection{Event: e})
+				}
+			case cfg.SkipEmpty && len(e.Payload) == 0:
+				empty++
+				log.Debugf("event %s has an empty payload; discarding it", e.EventUUID.String())
+			case dedup.duplicate(e):
+				log.Debugf("event %s is a duplicate; collapsing it", e.EventUUID.String())
+			default:
+				if cfg.CheckUUID && !e.EventUUID.NodeIsMAC() {
+					log.Warnf("event %s UUID is not RFC 4122 version 1 with a MAC node (node %q)",
+						e.EventUUID.String(), e.EventUUID.NodeString(),
+					)
+				}
+
+				f.Add(e)
+
+				if uuids != nil {
+					_, _ = fmt.Fprintln(uuids, e.EventUUID.String())
+				}
+			}
+
+			if rem, ok := r.(interface{ Len() int }); !ok || rem.Len() == 0 {
+				break
+			}
+		}
+
+		if cfg.MaxInvalidRate > 0 && events >= minInvalidRateSample {
+			if bad := invalid + malformed + oversized; float64(bad)/float64(events) > cfg.MaxInvalidRate {
+				return fmt.Errorf("%w: %d of %d events (%s) exceed the %s limit; is the emitter broken or the address wrong?",
+					ErrInvalidRate, bad, events, percent(bad, events), strconv.FormatFloat(100*cfg.MaxInvalidRate, 'f', 1, 64)+"%",
+				)
+			}
+		}
 	}
 
-	return events, nil
+	tracker.Stop()
+	if !cfg.AutoCount && !cfg.Continuous && received < cfg.Datagrams {
+		if tracker != nil && received > 0 {
+			// Finish the progress bar's line.
+			fmt.Fprintln(os.Stderr)
+		}
+		log.Infof("received %d of %d datagrams; reporting on what arrived", received, cfg.Datagrams)
+	}
+	if corrupted > 0 {
+		log.Infof("corrupted %d datagrams", corrupted)
+	}
+	f.Version = version
+	if f.Version == 0 {
+		f.Version = p.Version1
+	}
+	if dedup != nil {
+		f.Duplicates = dedup.Collapsed
+		f.Copies = dedup.Copies
+		log.Info(dedup)
+	}
+	f.Discarded = map[string]int{"invalid": invalid, "malformed": malformed, "oversized": oversized}
+	if malformed > 0 {
+		log.Warnf("discarded %d malformed datagrams", malformed)
+	}
+	if oversized > 0 {
+		log.Warnf("discarded %d events exceeding %d payload bytes", oversized, cfg.MaxPayloadBytes)
+	}
+	if invalid > 0 {
+		log.Warnf("discarded %d invalid events", invalid)
+	}
+	if empty > 0 {
+		log.Infof("discarded %d events with empty payloads", empty)
+	}
+
+	return nil
 }
 
-// columns returns the number of columns in the current terminal window.
-func columns() int {
+// cacheCapacity returns the number of datagrams to cache between reading and
+// parsing. A positive datagram count takes precedence over the cache size in
+// MB. At minimum, use 1MB of RAM to cache incoming datagrams. In either case,
+// the capacity never exceeds maxCachedDatagrams.
+func cacheCapacity(cacheMB, cacheDatagrams, size int) int {
+	capacity := cacheDatagrams
+	if capacity < 1 {
+		switch {
+		case cacheMB < 1:
+			cacheMB = 1
+		case cacheMB > math.MaxInt>>20:
+			// Shifting would overflow.
+			cacheMB = math.MaxInt >> 20
+		}
+		capacity = (cacheMB << 20) / size
+	}
+
+	if capacity > maxCachedDatagrams {
+		capacity = maxCachedDatagrams
+	}
+
+	return capacity
+}
+
+// fieldLayout returns the layout of the protocol version with the field widths
+// overridden, or nil if there are none to override, so events follow their
+// version's layout.
+func fieldLayout(widths string, version uint8) (*p.Layout, error) {
+	if widths == "" {
+		return nil, nil
+	}
+
+	base := p.Version1Layout
+	if version == p.Version2 {
+		base = p.Version2Layout
+	}
+	l, err := p.ParseLayout(base, widths)
+	if err != nil {
+		return nil, fmt.Errorf("-field-widths: %w", err)
+	}
+
+	return &l, nil
+}
+
+// fixedColumns, if positive, overrides the terminal width columns detects, as
+// -report-width does for reproducible output.
+var fixedColumns int
+
+// columns returns the number of columns in the terminal window of f, or zero if
+// f isn't a terminal. A positive fixedColumns takes precedence.
+func columns(f *os.File) int {
+	if fixedColumns > 0 {
+		return fixedColumns
+	}
+
 	var sz struct {
 		_    uint16
 		cols uint16
@@ -157,7 +970,7 @@ func columns() int {
 	// macOS and Linux.
 	_, _, _ = syscall.Syscall(
 		syscall.SYS_IOCTL,
-		os.Stdout.Fd(),
+		f.Fd(),
 		uintptr(syscall.TIOCGWINSZ),
 		uintptr(unsafe.Pointer(&sz)),
 	)
@@ -165,13 +978,36 @@ func columns() int {
 	return int(sz.cols)
 }
 
-// progress writes a progress bar to os.Stdout.
-func progress(step, total int) {
+// themeNames returns the sorted, comma-separated names of the label themes.
+func themeNames() string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// progressColumns returns the number of columns available to the progress
+// bar. It's a variable so tests can render the bar without a terminal.
+var progressColumns = func() int { return columns(os.Stderr) }
+
+// progress writes a progress bar to os.Stderr, labeled in the given ANSI color,
+// with the percentage complete to the given number of decimal places. Keeping
+// the bar off os.Stdout keeps its carriage returns out of a redirected report.
+func progress(step, total, color, precision int) {
+	pct := strconv.FormatFloat(100*float64(step)/float64(total), 'f', precision, 64)
+	pctWidth := len("100")
+	if precision > 0 {
+		pctWidth += 1 + precision
+	}
+
 	var (
 		// Calculating the columns with each call allows the graph to resize as
 		// the terminal resizes while running. Most users won't notice, but it's
 		// a detail that makes me happy and the performance hit is negligible.
-		width = columns() - 35
+		width = progressColumns() - 30 - pctWidth
 		done  = width * step / total
 		todo  = width - done
 	)
@@ -182,38 +1018,204 @@ func progress(step, total int) {
 	}
 
 	if step == 1 {
-		fmt.Println()
+		fmt.Fprintln(os.Stderr)
 	}
-	fmt.Printf(
-		"\r\u001b[%[1]dmProgress:\u001b[0m |%[2]s%[3]s| \u001b[%[1]dm%5.1[4]f%% Complete\u001b[0m",
-		labelColor,
+	fmt.Fprintf(os.Stderr,
+		"\r\u001b[%[1]dmProgress:\u001b[0m |%[2]s%[3]s| \u001b[%[1]dm%[4]*[5]s%% Complete\u001b[0m",
+		color,
 		strings.Repeat("#", done),
 		strings.Repeat("-", todo),
-		100*float64(step)/float64(total),
+		pctWidth,
+		pct,
 	)
 	if step == total {
-		fmt.Println()
-		fmt.Println()
+		fmt.Fprint(os.Stderr, "\n\n")
+	}
+}
+
+// readDatagram reads a single datagram from conn into b, reporting whether the
+// datagram was larger than b and therefore truncated.
+func readDatagram(conn net.Conn, b []byte) (n int, truncated bool, err error) {
+	if uc, ok := conn.(*net.UDPConn); ok {
+		// The kernel tells us if it discarded part of the datagram.
+		var flags int
+		n, _, flags, _, err = uc.ReadMsgUDP(b, nil)
+
+		return n, flags&syscall.MSG_TRUNC != 0, err
+	}
+
+	if uc, ok := conn.(interface {
+		ReadMsgUnix(b, oob []byte) (n, oobn, flags int, addr *net.UnixAddr, err error)
+	}); ok {
+		var flags int
+		n, _, flags, _, err = uc.ReadMsgUnix(b, nil)
+
+		return n, flags&syscall.MSG_TRUNC != 0, err
+	}
+
+	// A replayed capture knows the size of each event it replays.
+	if dr, ok := conn.(interface {
+		ReadDatagram(b []byte) (n int, truncated bool, err error)
+	}); ok {
+		return dr.ReadDatagram(b)
+	}
+
+	// Otherwise, we have to guess. A read that exactly fills the buffer likely
+	// means the datagram was larger than the buffer.
+	n, err = conn.Read(b)
+
+	return n, err == nil && n == len(b), err
+}
+
+// versionDatagram returns the protocol version the server sends in response to
+// the introduction: a datagram of a single version byte. The boolean is false
+// if the datagram isn't a version datagram, in which case the server predates
+// version negotiation and sends version 1 events.
+func versionDatagram(datagram io.Reader) (uint8, bool) {
+	b, ok := datagram.(interface{ Bytes() []byte })
+	if !ok || len(b.Bytes()) != 1 {
+		return 0, false
+	}
+
+	switch v := b.Bytes()[0]; v {
+	case p.Version1, p.Version2:
+		return v, true
+	default:
+		log.Warnf("unsupported protocol version %d; assuming version %d", v, p.Version1)
+
+		return p.Version1, true
+	}
+}
+
+// countMagic prefixes the count header some emitter versions send before their
+// events, following any version datagram: the magic and the number of
+// datagrams to come as a big-endian uint32.
+const countMagic = "ECNT"
+
+// countDatagram returns the number of datagrams a count header announces. The
+// boolean is false if the datagram isn't a count header, in which case it's
+// an event.
+func countDatagram(datagram io.Reader) (int, bool) {
+	b, ok := datagram.(interface{ Bytes() []byte })
+	if !ok || len(b.Bytes()) != len(countMagic)+4 || !bytes.HasPrefix(b.Bytes(), []byte(countMagic)) {
+		return 0, false
 	}
+
+	return int(binary.BigEndian.Uint32(b.Bytes()[len(countMagic):])), true
+}
+
+// digestMagic prefixes the digest trailer some emitter versions send after
+// their events: the magic and, as a big-endian uint32, the CRC-32 (IEEE) of
+// the big-endian checksums of the events sent, in order (see streamDigest).
+const digestMagic = "EDGT"
+
+// digestDatagram returns the stream digest a digest trailer carries. The
+// boolean is false if the datagram isn't a digest trailer.
+func digestDatagram(datagram io.Reader) (uint32, bool) {
+	b, ok := datagram.(interface{ Bytes() []byte })
+	if !ok || len(b.Bytes()) != len(digestMagic)+4 || !bytes.HasPrefix(b.Bytes(), []byte(digestMagic)) {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint32(b.Bytes()[len(digestMagic):]), true
+}
+
+// streamDigest returns the running digest updated with the next event's
+// checksum. Since it depends on the order of the checksums, a digest differing
+// from the server's reveals dropped, reordered, and corrupted events alike.
+func streamDigest(digest, checkSum uint32) uint32 {
+	return crc32.Update(digest, crc32.IEEETable, binary.BigEndian.AppendUint32(nil, checkSum))
+}
+
+// setReadBuffer requests a socket receive buffer of the given size for conn,
+// logging the size the OS allocated, which it may cap or otherwise adjust.
+func setReadBuffer(conn net.Conn, size int) error {
+	sc, ok := conn.(interface {
+		syscall.Conn
+		SetReadBuffer(bytes int) error
+	})
+	if !ok {
+		return fmt.Errorf("connection doesn't support setting its receive buffer")
+	}
+
+	if err := sc.SetReadBuffer(size); err != nil {
+		return fmt.Errorf("setting socket receive buffer: %w", err)
+	}
+
+	// Reading back the allocated size is best-effort.
+	actual, err := readBufferSize(sc)
+	if err != nil {
+		log.Debugf("reading socket receive buffer size: %v", err)
+		log.Infof("requested a %d-byte socket receive buffer", size)
+
+		return nil
+	}
+	log.Infof("requested a %d-byte socket receive buffer; the OS allocated %d bytes", size, actual)
+
+	return nil
+}
+
+// readBufferSize returns the size of the connection's socket receive buffer.
+func readBufferSize(conn syscall.Conn) (int, error) {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		size    int
+		sockErr error
+	)
+	if err = rc.Control(func(fd uintptr) {
+		size, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+	}); err != nil {
+		return 0, err
+	}
+
+	return size, sockErr
 }
 
 // readDatagrams reads datagrams up to the given size, and writes them wrapped
-// in a bytes.Buffer to the datagrams channel.
-func readDatagrams(ctx context.Context, conn net.Conn, chDatagrams chan<- io.Reader, size int) {
+// in a bytes.Buffer to the datagrams channel. A positive idle duration closes
+// the channel once no datagram follows the last within it; the wait for the
+// first is the intro timeout's. Likewise, a positive limit closes the channel
+// once the datagrams read total at least that many bytes.
+func readDatagrams(ctx context.Context, conn net.Conn, chDatagrams chan<- io.Reader, size int, idle time.Duration,
+	limit int64,
+) {
 	defer close(chDatagrams)
 
 	log.Debug("reading datagrams from the server")
 
+	var (
+		total    int64
+		received bool
+	)
 	for {
+		if idle > 0 && received {
+			if err := conn.SetReadDeadline(time.Now().Add(idle)); err != nil {
+				log.Errorf("setting read deadline: %v", err)
+				return
+			}
+		}
+
 		b := make([]byte, size)
-		n, err := conn.Read(b)
+		n, truncated, err := readDatagram(conn, b)
 		switch {
 		case errors.Is(err, net.ErrClosed):
 			log.Debug("connection closed")
 			return
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			log.Debugf("no datagrams received in %s", idle)
+			return
 		case err != nil:
 			log.Errorf("reading %d bytes from socket: %v", n, err)
 			continue
+		case truncated:
+			// The remainder of the datagram is lost. Skip it rather than
+			// handing a partial event to the parser.
+			log.Warn("datagram truncated; increase -datagram-size")
+			continue
 		}
 
 		select {
@@ -221,14 +1223,35 @@ func readDatagrams(ctx context.Context, conn net.Conn, chDatagrams chan<- io.Rea
 			return
 		case chDatagrams <- bytes.NewBuffer(b[:n]):
 		}
+		received = true
+
+		total += int64(n)
+		if limit > 0 && total >= limit {
+			log.Warnf("ingested %d bytes, reaching the %d-byte limit; reporting on what arrived", total, limit)
+			return
+		}
 	}
 }
 
+// runResult is the outcome of a run, beyond its report.
+type runResult struct {
+	Duplicates map[string]int // duplicate events collapsed by each dedup mode
+}
+
 // run establishes a connection to the event server, reads and parses events,
 // and renders a report of findings.
-func run(address string, datagrams, size, cache int, ipDetail netip.Addr) error {
-	if address == "" {
-		return fmt.Errorf("server address is required")
+func run(cfg config) error {
+	_, err := runWithResult(cfg)
+
+	return err
+}
+
+// runWithResult is run, returning its result.
+func runWithResult(cfg config) (runResult, error) {
+	var res runResult
+
+	if cfg.Address == "" && cfg.Replay == "" {
+		return res, fmt.Errorf("server address is required")
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -236,34 +1259,325 @@ func run(address string, datagrams, size, cache int, ipDetail netip.Addr) error
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
 	go func() {
-		<-c
-		cancel()
-		log.Debug("context canceled")
+		select {
+		case <-c:
+			cancel()
+			log.Debug("context canceled")
+		case <-ctx.Done():
+		}
 	}()
 
-	var d net.Dialer
-	conn, err := d.DialContext(ctx, "udp", address)
+	if cfg.LowMemory && cfg.EventsOut != "" {
+		return res, fmt.Errorf("-events-out requires retaining all events and is unavailable with -low-memory")
+	}
+	if cfg.LowMemory && cfg.Extract != "" {
+		return res, fmt.Errorf("-extract requires retaining all events and is unavailable with -low-memory")
+	}
+	if cfg.LowMemory && cfg.SequentialUUIDs {
+		return res, fmt.Errorf("-sequential-uuids requires retaining all events and is unavailable with -low-memory")
+	}
+	if cfg.LowMemory && cfg.Expect != "" {
+		return res, fmt.Errorf("-expect requires retaining all events and is unavailable with -low-memory")
+	}
+	if cfg.NoReport && cfg.Report {
+		return res, fmt.Errorf("-report and -no-report are mutually exclusive")
+	}
+	switch cfg.Format {
+	case "", "terminal":
+	case "markdown":
+		if cfg.Syslog != "" {
+			return res, fmt.Errorf("-format markdown and -syslog are mutually exclusive")
+		}
+	default:
+		return res, fmt.Errorf("-format %q is neither terminal nor markdown", cfg.Format)
+	}
+	if err := checkTimeFormat(cfg.TimeFormat); err != nil {
+		return res, fmt.Errorf("-time-format: %w", err)
+	}
+	switch {
+	case cfg.ReplayLoop && cfg.Replay == "":
+		return res, fmt.Errorf("-replay-loop requires -replay")
+	case cfg.ReplayCount < 0:
+		return res, fmt.Errorf("-replay-count must be at least 0")
+	case cfg.ReplayCount > 0 && !cfg.ReplayLoop:
+		return res, fmt.Errorf("-replay-count requires -replay-loop")
+	case cfg.Replay != "" && cfg.Network == "tcp":
+		return res, fmt.Errorf("-replay replays datagrams and is unavailable with -network tcp")
+	}
+	if cfg.DedupReport && cfg.Dedup == "" {
+		return res, fmt.Errorf("-dedup-report requires -dedup")
+	}
+	for _, key := range cfg.AcrossProtocols {
+		if !aggregated(key) {
+			return res, fmt.Errorf("-across-protocols: payload key %q isn't one of %s", key, strings.Join(aggregatedKeys, ", "))
+		}
+	}
+	assertions, err := parseAssertions(cfg.Assert)
 	if err != nil {
-		return fmt.Errorf("dialing %q: %w", address, err)
+		return res, err
+	}
+	if _, err = selectSections(cfg.Sections); err != nil {
+		return res, fmt.Errorf("-sections: %w", err)
+	}
+	var extractProtocols []p.Protocol
+	if cfg.ExtractProtocol != "" {
+		proto, err := p.ParseProtocol(cfg.ExtractProtocol)
+		if err != nil {
+			return res, err
+		}
+		extractProtocols = append(extractProtocols, proto)
+	}
+
+	var expected map[string]uint32
+	if cfg.Expect != "" {
+		var err error
+		if expected, err = readExpectationsFile(cfg.Expect); err != nil {
+			return res, fmt.Errorf("reading expectations: %w", err)
+		}
+	}
+
+	var geo geoResolver
+	if cfg.GeoIP != "" {
+		t, err := readGeoIPFile(cfg.GeoIP)
+		if err != nil {
+			return res, fmt.Errorf("reading geoIP networks: %w", err)
+		}
+		geo = newGeoCache(t)
+	}
+
+	var bad *reputation
+	if cfg.Reputation != "" {
+		var err error
+		if bad, err = readReputationFile(cfg.Reputation); err != nil {
+			return res, fmt.Errorf("reading reputation list: %w", err)
+		}
+	}
+
+	switch cfg.Network {
+	case "":
+		cfg.Network = "udp"
+	case "udp", "tcp", "unixgram":
+	default:
+		return res, fmt.Errorf("unsupported network %q; use udp, tcp, or unixgram", cfg.Network)
+	}
+	if cfg.Compressed && cfg.Network == "tcp" {
+		return res, fmt.Errorf("-compressed decompresses individual datagrams and requires -network udp or unixgram")
+	}
+	if cfg.TLS && cfg.Network != "tcp" {
+		return res, fmt.Errorf("-tls requires -network tcp")
+	}
+
+	var conn net.Conn
+	if cfg.Replay != "" {
+		// Fail fast on a missing capture rather than upon the first read.
+		if _, err := os.Stat(cfg.Replay); err != nil {
+			return res, fmt.Errorf("replaying capture: %w", err)
+		}
+
+		loops := 1
+		if cfg.ReplayLoop {
+			loops = cfg.ReplayCount
+		}
+		conn = newReplayConn(cfg.Replay, loops)
+	} else {
+		var err error
+		if conn, err = dialServer(ctx, cfg); err != nil {
+			return res, fmt.Errorf("dialing %q: %w", cfg.Address, err)
+		}
 	}
 	defer func() { _ = conn.Close() }()
 
-	log.Infof("collecting events from %q", address)
-	events, err := collectEvents(ctx, conn, datagrams, size, cache)
+	if cfg.SockBuf > 0 {
+		if err = setReadBuffer(conn, cfg.SockBuf); err != nil {
+			return res, err
+		}
+	}
+
+	f := &findings{
+		AcrossProtocols: cfg.AcrossProtocols,
+		Cardinality:     cfg.Cardinality,
+		CheckCharset:    cfg.CheckCharset,
+		Classify:        cfg.Classify,
+		ClusterPrefix:   cfg.ClusterPrefix,
+		ClusterPrefix6:  cfg.ClusterPrefix6,
+		CRCSample:       cfg.CRCSample,
+		Detail:          cfg.DetailIP,
+		ExplainInvalid:  cfg.ExplainInvalid,
+		Expected:        expected,
+		Geo:             geo,
+		LabelColor:      cfg.LabelColor,
+		Location:        cfg.Location,
+		LowMemory:       cfg.LowMemory,
+		MaskCredentials: cfg.MaskCredentials,
+		PayloadSizes:    cfg.PayloadSizes,
+		Reputation:      bad,
+		Sections:        cfg.Sections,
+		SequentialUUIDs: cfg.SequentialUUIDs,
+		SkewThreshold:   cfg.SkewThreshold,
+		TimeFormat:      cfg.TimeFormat,
+		Topology:        cfg.Topology,
+		Width:           columns(os.Stdout),
+	}
+
+	// Without a reporter, collection feeds only the export sinks.
+	var rep reporter
+	if !cfg.NoReport {
+		rep = terminalReporter{w: os.Stdout}
+		if cfg.Format == "markdown" {
+			rep = markdownReporter{w: os.Stdout}
+		}
+	}
+	if rep != nil && cfg.Syslog != "" {
+		sr, err := newSyslogReporter(cfg.Syslog)
+		if err != nil {
+			log.Warnf("connecting to syslog: %v; writing report to stderr", err)
+			rep = terminalReporter{w: os.Stderr}
+		} else {
+			defer func() { _ = sr.Close() }()
+			rep = sr
+		}
+	}
+
+	stopCPUProfile, err := startCPUProfile(cfg.CPUProfile)
 	if err != nil {
-		return fmt.Errorf("collecting events: %w", err)
+		return res, err
 	}
 
-	log.Infof("received %d events", len(events))
+	log.Infof("collecting events from %q", cfg.Address)
+	cfg.Reporter = rep
+	err = collectEvents(ctx, conn, cfg, f)
+	if stopErr := stopCPUProfile(); stopErr != nil {
+		log.Warnf("stopping CPU profile: %v", stopErr)
+	}
+	if err != nil {
+		return res, fmt.Errorf("collecting events: %w", err)
+	}
+	res.Duplicates = f.Duplicates
+
+	if f.ByProtocol == nil {
+		f.populate()
+	}
+	if err = writeMemProfile(cfg.MemProfile); err != nil {
+		return res, err
+	}
+
+	log.Infof("received %d events (%d with empty payloads) using protocol version %d", f.total(), f.Empty, f.Version)
 	fmt.Print()
 
-	report, err := (&findings{Events: events}).report(ipDetail)
-	if err != nil {
-		return fmt.Errorf("generating report: %w", err)
+	if cfg.EventsOut != "" {
+		events := f.Events
+		if cfg.Canonical {
+			events = canonicalEvents(events)
+		}
+		if err = writeEventsFile(cfg.EventsOut, events, cfg.ArchiveRetries); err != nil {
+			return res, fmt.Errorf("writing events: %w", err)
+		}
+		log.Infof("wrote %d events to %q", len(f.Events), cfg.EventsOut)
 	}
 
-	fmt.Printf("\n\n%s\n\n", report)
+	if cfg.InvalidJSON != "" {
+		err = writeFile(cfg.InvalidJSON, func(w io.Writer) error { return writeRejectionsJSON(w, f.Invalid) })
+		if err != nil {
+			return res, fmt.Errorf("writing invalid events: %w", err)
+		}
+		log.Infof("wrote %d invalid events to %q", len(f.Invalid), cfg.InvalidJSON)
+	}
+
+	if cfg.DOT != "" {
+		if err = writeFile(cfg.DOT, f.WriteDOT); err != nil {
+			return res, fmt.Errorf("writing DOT graph: %w", err)
+		}
+		log.Infof("wrote DOT graph to %q", cfg.DOT)
+	}
+
+	if cfg.OpenMetrics != "" {
+		if err = writeFile(cfg.OpenMetrics, f.WriteOpenMetrics); err != nil {
+			return res, fmt.Errorf("writing OpenMetrics: %w", err)
+		}
+		log.Infof("wrote OpenMetrics to %q", cfg.OpenMetrics)
+	}
+
+	// A failed assertion doesn't preempt the output, but it's the result.
+	var assertErr error
+	if len(assertions) > 0 {
+		assertErr = checkAssertions(os.Stderr, assertions, f)
+	}
+
+	if cfg.Extract != "" {
+		values := extractValues(f.Events, cfg.Extract, extractProtocols...)
+		if err = writeValues(os.Stdout, values); err != nil {
+			return res, fmt.Errorf("writing extracted values: %w", err)
+		}
+		log.Infof("extracted %d unique %q values", len(values), cfg.Extract)
+
+		return res, assertErr
+	}
+
+	if cfg.Summary {
+		fmt.Println(f.OneLine())
+
+		return res, assertErr
+	}
+
+	if cfg.Head > 0 || cfg.Tail > 0 {
+		s, err := f.preview(cfg.Head, cfg.Tail)
+		if err != nil {
+			return res, fmt.Errorf("previewing events: %w", err)
+		}
+		fmt.Printf("\n\n%s\n\n", s)
+
+		if !cfg.Report {
+			return res, assertErr
+		}
+	}
+
+	if rep == nil {
+		log.Debug("skipping the report")
+
+		return res, assertErr
+	}
+	if err = rep.Report(f); err != nil {
+		return res, errors.Join(fmt.Errorf("generating report: %w", err), assertErr)
+	}
+
+	return res, assertErr
+}
+
+// watch calls run every interval, clearing the screen before each subsequent
+// cycle, until interrupted. A positive cycles value limits the number of
+// cycles. An error in one cycle is logged and doesn't end the watch.
+func watch(cfg config, cycles int) error {
+	if cfg.Watch <= 0 {
+		return fmt.Errorf("watch interval must be positive")
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+
+	for i := 1; cycles <= 0 || i <= cycles; i++ {
+		if i > 1 {
+			select {
+			case <-c:
+				log.Debug("watch interrupted")
+				return nil
+			case <-time.After(cfg.Watch):
+			}
+
+			// Move the cursor home and clear the screen.
+			fmt.Print("\u001b[H\u001b[2J")
+		}
+
+		if err := run(cfg); err != nil {
+			if brokenPipe(err) {
+				// Nobody reads the output any longer (e.g., | head).
+				return err
+			}
+			log.Errorf("watch cycle %d: %v", i, err)
+		}
+	}
 
 	return nil
 }
@@ -2,19 +2,59 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"math"
 	"net"
 	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	. "github.com/smartystreets/goconvey/convey"
 
 	p "github.com/awoodbeck/event-emitter-client/protocol"
 )
 
+func Test_cacheCapacity(t *testing.T) {
+	Convey("Given cache settings", t, func() {
+		Convey("When calling the cacheCapacity function", func() {
+			Convey("It should divide the MB cache by the datagram size", func() {
+				So(cacheCapacity(20, 0, 512), ShouldEqual, 40960)
+			})
+
+			Convey("It should use at least 1MB of cache", func() {
+				So(cacheCapacity(0, 0, 512), ShouldEqual, 2048)
+			})
+
+			Convey("It should prefer an explicit datagram count", func() {
+				So(cacheCapacity(20, 100, 512), ShouldEqual, 100)
+			})
+
+			Convey("It should cap the capacity given a pathologically small size", func() {
+				So(cacheCapacity(20, 0, 1), ShouldEqual, maxCachedDatagrams)
+			})
+
+			Convey("It should cap the capacity given a large datagram count", func() {
+				So(cacheCapacity(20, maxCachedDatagrams+1, 512), ShouldEqual, maxCachedDatagrams)
+			})
+
+			Convey("It should not overflow given an enormous MB cache", func() {
+				So(cacheCapacity(math.MaxInt, 0, 512), ShouldEqual, maxCachedDatagrams)
+			})
+		})
+	})
+}
+
 func Test_collectEvents(t *testing.T) {
 	Convey("Given a net.Conn to an event server", t, func() {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -25,7 +65,9 @@ func Test_collectEvents(t *testing.T) {
 
 		Convey("When calling the collectEvents function", func() {
 			Convey("It should return a slice of expected events", func() {
-				actual, err := collectEvents(ctx, conn, eventCount, 512, 0)
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: 512}, f)
+				actual := f.Events
 				So(err, ShouldBeNil)
 
 				// slice contains the events in the order they were sent by the
@@ -36,11 +78,38 @@ func Test_collectEvents(t *testing.T) {
 				}
 
 				// which should be the same order they were received
-				So(actual, ShouldResemble, expected)
+				So(withoutReceivedAt(actual), ShouldResemble, expected)
+			})
+
+			Convey("It should draw the progress bar on stderr, keeping stdout clean", func() {
+				columns := progressColumns
+				progressColumns = func() int { return 80 }
+				defer func() { progressColumns = columns }()
+
+				var stderr string
+				stdout, err := captureStdout(func() error {
+					var err error
+					stderr, err = capture(&os.Stderr, func() error {
+						return collectEvents(ctx, conn, config{
+							Datagrams:         eventCount,
+							ProgressPrecision: 1,
+							Size:              512,
+						}, new(findings))
+					})
+
+					return err
+				})
+				So(err, ShouldBeNil)
+				So(stderr, ShouldContainSubstring, "\r")
+				So(stderr, ShouldContainSubstring, "100.0% Complete")
+				So(stdout, ShouldNotContainSubstring, "\r")
+				So(stdout, ShouldNotContainSubstring, "Progress:")
 			})
 
 			Convey("It should succeed even if the datagram size is too small", func() {
-				actual, err := collectEvents(ctx, conn, eventCount, minDatagramBytes-1, 0)
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: minDatagramBytes - 1}, f)
+				actual := f.Events
 				So(err, ShouldBeNil)
 
 				expected := make([]*p.Event, 0, eventCount)
@@ -48,11 +117,13 @@ func Test_collectEvents(t *testing.T) {
 					expected = append(expected, conn.events[i%len(conn.events)])
 				}
 
-				So(actual, ShouldResemble, expected)
+				So(withoutReceivedAt(actual), ShouldResemble, expected)
 			})
 
 			Convey("It should succeed even if the datagram size is too large", func() {
-				actual, err := collectEvents(ctx, conn, eventCount, maxDatagramBytes+1, 0)
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: maxDatagramBytes + 1}, f)
+				actual := f.Events
 				So(err, ShouldBeNil)
 
 				expected := make([]*p.Event, 0, eventCount)
@@ -60,11 +131,25 @@ func Test_collectEvents(t *testing.T) {
 					expected = append(expected, conn.events[i%len(conn.events)])
 				}
 
-				So(actual, ShouldResemble, expected)
+				So(withoutReceivedAt(actual), ShouldResemble, expected)
+			})
+
+			Convey("It should still collect events whose UUIDs fail the check", func() {
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{
+					CheckUUID: true,
+					Datagrams: eventCount,
+					Size:      512,
+				}, f)
+				actual := f.Events
+				So(err, ShouldBeNil)
+				So(actual, ShouldHaveLength, eventCount)
 			})
 
 			Convey("It should return a slice even on short read of events", func() {
-				actual, err := collectEvents(ctx, conn, eventCount+1, 512, 0)
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount + 1, Size: 512}, f)
+				actual := f.Events
 				So(err, ShouldBeNil)
 
 				expected := make([]*p.Event, 0, eventCount)
@@ -72,31 +157,696 @@ func Test_collectEvents(t *testing.T) {
 					expected = append(expected, conn.events[i%len(conn.events)])
 				}
 
-				So(actual, ShouldResemble, expected)
+				So(withoutReceivedAt(actual), ShouldResemble, expected)
+			})
+
+			Convey("It should collect every event until the server goes idle when auto-counting", func() {
+				addr, err := udpServer(validEvents)
+				So(err, ShouldBeNil)
+
+				udpConn, err := net.Dial("udp", addr.String())
+				So(err, ShouldBeNil)
+				defer func() { _ = udpConn.Close() }()
+
+				f := new(findings)
+				err = collectEvents(ctx, udpConn, config{
+					AutoCount:   true,
+					IdleTimeout: 250 * time.Millisecond,
+					Size:        512,
+				}, f)
+				So(err, ShouldBeNil)
+				So(withoutReceivedAt(f.Events), ShouldResemble, validEvents)
+			})
+
+			Convey("It should record when it received each event", func() {
+				before := time.Now()
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: 512}, f)
+				So(err, ShouldBeNil)
+				So(f.Events, ShouldHaveLength, eventCount)
+				for _, e := range f.Events {
+					So(e.ReceivedAt, ShouldHappenOnOrBetween, before, time.Now())
+				}
+				So(f.Latency.Count, ShouldEqual, eventCount)
+			})
+
+			Convey("It should collect every event packed into one datagram", func() {
+				packed := new(bytes.Buffer)
+				_, err := archiveEvents(packed, []*p.Event{validEvents[1], validEvents[3]}, 0, 0)
+				So(err, ShouldBeNil)
+
+				addr, err := udpDatagramServer([][]byte{packed.Bytes()}, 1)
+				So(err, ShouldBeNil)
+
+				udpConn, err := net.Dial("udp", addr.String())
+				So(err, ShouldBeNil)
+				defer func() { _ = udpConn.Close() }()
+
+				f := new(findings)
+				err = collectEvents(ctx, udpConn, config{Datagrams: 1, Quiet: true, Size: 512}, f)
+				So(err, ShouldBeNil)
+				So(withoutReceivedAt(f.Events), ShouldResemble, []*p.Event{validEvents[1], validEvents[3]})
+			})
+
+			Convey("It should collect events from a mix of gzipped and plain datagrams", func() {
+				datagrams := make([][]byte, 0, len(validEvents))
+				for i, e := range validEvents {
+					b, err := e.MarshalBinary()
+					So(err, ShouldBeNil)
+
+					if i%2 == 0 {
+						buf := new(bytes.Buffer)
+						zw := gzip.NewWriter(buf)
+						_, err = zw.Write(b)
+						So(err, ShouldBeNil)
+						So(zw.Close(), ShouldBeNil)
+						b = buf.Bytes()
+					}
+					datagrams = append(datagrams, b)
+				}
+
+				addr, err := udpDatagramServer(datagrams, 1)
+				So(err, ShouldBeNil)
+
+				udpConn, err := net.Dial("udp", addr.String())
+				So(err, ShouldBeNil)
+				defer func() { _ = udpConn.Close() }()
+
+				f := new(findings)
+				err = collectEvents(ctx, udpConn, config{
+					Compressed: true,
+					Datagrams:  len(datagrams),
+					Quiet:      true,
+					Size:       512,
+					Strict:     true,
+				}, f)
+				So(err, ShouldBeNil)
+				So(withoutReceivedAt(f.Events), ShouldResemble, validEvents)
+			})
+
+			Convey("It should read version 2 events after the server negotiates version 2", func() {
+				v2 := &p.Event{
+					Version:      p.Version2,
+					NodeID:       4,
+					TimeStamp:    1602720000,
+					Size:         14,
+					PayloadBytes: []byte("username:aiden"),
+					Protocol:     p.SSH,
+					IP:           netip.MustParseAddr("2001:db8::1"),
+				}
+				v2.CheckSum = v2.ComputeCheckSum()
+				b, err := v2.MarshalBinary()
+				So(err, ShouldBeNil)
+
+				addr, err := udpDatagramServer([][]byte{{p.Version2}, b, b}, 1)
+				So(err, ShouldBeNil)
+
+				udpConn, err := net.Dial("udp", addr.String())
+				So(err, ShouldBeNil)
+				defer func() { _ = udpConn.Close() }()
+
+				f := new(findings)
+				err = collectEvents(ctx, udpConn, config{Datagrams: 2, Quiet: true, Size: 512, Strict: true}, f)
+				So(err, ShouldBeNil)
+				So(f.Version, ShouldEqual, p.Version2)
+				So(f.Events, ShouldHaveLength, 2)
+				So(f.Events[0].IP, ShouldResemble, v2.IP)
+				So(f.Submitters, ShouldContainKey, v2.IP)
+			})
+
+			Convey("It should collect the number of datagrams a count header announces", func() {
+				header := binary.BigEndian.AppendUint32([]byte(countMagic), 3)
+				datagrams := [][]byte{header}
+				for _, e := range validEvents {
+					b, err := e.MarshalBinary()
+					So(err, ShouldBeNil)
+					datagrams = append(datagrams, b)
+				}
+
+				addr, err := udpDatagramServer(datagrams, 1)
+				So(err, ShouldBeNil)
+
+				udpConn, err := net.Dial("udp", addr.String())
+				So(err, ShouldBeNil)
+				defer func() { _ = udpConn.Close() }()
+
+				columns := progressColumns
+				progressColumns = func() int { return 80 }
+				defer func() { progressColumns = columns }()
+
+				f := new(findings)
+				stderr, err := capture(&os.Stderr, func() error {
+					return collectEvents(ctx, udpConn, config{
+						AutoCount:         true,
+						ProgressPrecision: 1,
+						Size:              512,
+						Strict:            true,
+					}, f)
+				})
+				So(err, ShouldBeNil)
+				So(withoutReceivedAt(f.Events), ShouldResemble, validEvents[:3])
+				So(stderr, ShouldContainSubstring, "100.0% Complete")
+			})
+
+			Convey("It should discard and count events exceeding the maximum payload bytes", func() {
+				var datagrams [][]byte
+				for _, e := range validEvents {
+					b, err := e.MarshalBinary()
+					So(err, ShouldBeNil)
+					datagrams = append(datagrams, b)
+				}
+
+				addr, err := udpDatagramServer(datagrams, 1)
+				So(err, ShouldBeNil)
+
+				udpConn, err := net.Dial("udp", addr.String())
+				So(err, ShouldBeNil)
+				defer func() { _ = udpConn.Close() }()
+
+				f := new(findings)
+				err = collectEvents(ctx, udpConn, config{Datagrams: len(validEvents), MaxPayloadBytes: 100, Size: 512}, f)
+				So(err, ShouldBeNil)
+				So(f.Events, ShouldHaveLength, 4)
+				for i, e := range f.Events {
+					So(e.EventUUID, ShouldResemble, validEvents[i].EventUUID)
+				}
+				So(f.Discarded["oversized"], ShouldEqual, 1)
+				So(f.Discarded["malformed"], ShouldEqual, 0)
+			})
+
+			Convey("It should compare its stream digest with a digest trailer's", func() {
+				var datagrams [][]byte
+				var digest uint32
+				for _, e := range validEvents {
+					b, err := e.MarshalBinary()
+					So(err, ShouldBeNil)
+					datagrams = append(datagrams, b)
+					digest = streamDigest(digest, e.CheckSum)
+				}
+				trailer := binary.BigEndian.AppendUint32([]byte(digestMagic), digest)
+
+				collect := func(datagrams [][]byte) (*findings, string) {
+					addr, err := udpDatagramServer(datagrams, 1)
+					So(err, ShouldBeNil)
+
+					udpConn, err := net.Dial("udp", addr.String())
+					So(err, ShouldBeNil)
+					defer func() { _ = udpConn.Close() }()
+
+					var logs bytes.Buffer
+					log.SetOutput(&logs)
+					defer log.SetOutput(os.Stderr)
+
+					f := new(findings)
+					So(collectEvents(ctx, udpConn, config{AutoCount: true, Size: 512, Strict: true}, f), ShouldBeNil)
+
+					return f, logs.String()
+				}
+
+				Convey("It should confirm a match given every event", func() {
+					f, logs := collect(append(datagrams[:len(datagrams):len(datagrams)], trailer))
+					So(withoutReceivedAt(f.Events), ShouldResemble, validEvents)
+					So(logs, ShouldContainSubstring, "matches the server's")
+					So(logs, ShouldNotContainSubstring, "possibly lost")
+				})
+
+				Convey("It should warn of a mismatch given a dropped event", func() {
+					dropped := append([][]byte{}, datagrams[:2]...)
+					dropped = append(dropped, datagrams[3:]...)
+					f, logs := collect(append(dropped, trailer))
+					So(f.Events, ShouldHaveLength, len(validEvents)-1)
+					So(logs, ShouldContainSubstring, "possibly lost or reordered")
+				})
+			})
+
+			Convey("It should default to version 1 absent a version datagram", func() {
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: 512}, f)
+				So(err, ShouldBeNil)
+				So(f.Version, ShouldEqual, p.Version1)
+				So(f.Events, ShouldHaveLength, eventCount)
+			})
+
+			Convey("It should stop collecting upon receiving the sentinel", func() {
+				sentinel := []byte("That's all, folks!")
+				datagrams := make([][]byte, 0, len(validEvents)+2)
+				for _, e := range validEvents {
+					b, err := e.MarshalBinary()
+					So(err, ShouldBeNil)
+					datagrams = append(datagrams, b)
+				}
+
+				// An event following the sentinel shouldn't be collected.
+				datagrams = append(datagrams, sentinel, datagrams[0])
+
+				addr, err := udpDatagramServer(datagrams, 1)
+				So(err, ShouldBeNil)
+
+				udpConn, err := net.Dial("udp", addr.String())
+				So(err, ShouldBeNil)
+				defer func() { _ = udpConn.Close() }()
+
+				var logs bytes.Buffer
+				log.SetOutput(&logs)
+				defer log.SetOutput(os.Stderr)
+
+				f := new(findings)
+				err = collectEvents(ctx, udpConn, config{
+					Datagrams: len(datagrams) + 10,
+					Quiet:     true,
+					Sentinel:  sentinel,
+					Size:      512,
+				}, f)
+				So(err, ShouldBeNil)
+				So(withoutReceivedAt(f.Events), ShouldResemble, validEvents)
+
+				// Nor should the sentinel count as a received datagram.
+				So(logs.String(), ShouldContainSubstring,
+					fmt.Sprintf("received %d of %d datagrams", len(validEvents), len(datagrams)+10))
+			})
+
+			Convey("It should report every interval in continuous mode until the context is done", func() {
+				addr, err := udpServer(validEvents)
+				So(err, ShouldBeNil)
+
+				udpConn, err := net.Dial("udp", addr.String())
+				So(err, ShouldBeNil)
+				defer func() { _ = udpConn.Close() }()
+
+				ctx, cancel := context.WithTimeout(ctx, 350*time.Millisecond)
+				defer cancel()
+
+				rep := new(countingReporter)
+				f := new(findings)
+				err = collectEvents(ctx, udpConn, config{
+					Continuous:     true,
+					IdleTimeout:    50 * time.Millisecond,
+					Quiet:          true,
+					ReportInterval: 100 * time.Millisecond,
+					Reporter:       rep,
+					Size:           512,
+				}, f)
+				So(err, ShouldBeNil)
+				So(rep.reports, ShouldBeGreaterThanOrEqualTo, 2)
+				So(withoutReceivedAt(f.Events), ShouldResemble, validEvents)
+			})
+
+			Convey("It should stop continuous collection once the report's reader closes the pipe", func() {
+				addr, err := udpServer(validEvents)
+				So(err, ShouldBeNil)
+
+				udpConn, err := net.Dial("udp", addr.String())
+				So(err, ShouldBeNil)
+				defer func() { _ = udpConn.Close() }()
+
+				ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+				defer cancel()
+
+				err = collectEvents(ctx, udpConn, config{
+					Continuous:     true,
+					Quiet:          true,
+					ReportInterval: 50 * time.Millisecond,
+					Reporter:       terminalReporter{w: new(brokenPipeWriter)},
+					Size:           512,
+				}, new(findings))
+				So(brokenPipe(err), ShouldBeTrue)
+				So(ctx.Err(), ShouldBeNil)
 			})
 
 			Convey("It should return an empty slice when the context is canceled before reading", func() {
 				cancel()
-				actual, err := collectEvents(ctx, conn, eventCount, 512, 0)
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: 512}, f)
+				actual := f.Events
 				So(err, ShouldBeNil)
 				So(actual, ShouldBeEmpty)
 			})
 
 			Convey("It should return an empty slice when all that's receives is invalid events", func() {
 				conn.events = invalidEvents
-				actual, err := collectEvents(ctx, conn, eventCount, 512, 0)
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: 512}, f)
+				actual := f.Events
 				So(err, ShouldBeNil)
 				So(actual, ShouldBeEmpty)
 			})
 
+			Convey("It should print the UUID of each collected event when listing them", func() {
+				conn.events = append(append([]*p.Event{}, validEvents...), invalidEvents...)
+				f := new(findings)
+				stdout, err := captureStdout(func() error {
+					return collectEvents(ctx, conn, config{Datagrams: eventCount, ListUUIDs: true, Size: 512}, f)
+				})
+				So(err, ShouldBeNil)
+
+				expected := make([]string, 0, len(f.Events))
+				for _, e := range f.Events {
+					expected = append(expected, e.EventUUID.String())
+				}
+				So(strings.Split(strings.TrimSuffix(stdout, "\n"), "\n"), ShouldResemble, expected)
+			})
+
+			Convey("It should call the event hook only for valid events", func() {
+				conn.events = append(append([]*p.Event{}, validEvents...), invalidEvents...)
+				var calls int
+				f := &findings{OnEvent: func(*p.Event) { calls++ }}
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: 512}, f)
+				So(err, ShouldBeNil)
+				So(calls, ShouldEqual, len(f.Events))
+				So(calls, ShouldBeLessThan, eventCount)
+			})
+
+			Convey("It should count events with empty payloads", func() {
+				conn.events = []*p.Event{emptyPayloadEvent()}
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: 512}, f)
+				So(err, ShouldBeNil)
+				So(f.Events, ShouldHaveLength, eventCount)
+				So(f.Empty, ShouldEqual, eventCount)
+			})
+
+			Convey("It should discard events with empty payloads when skipping them", func() {
+				conn.events = []*p.Event{emptyPayloadEvent(), validEvents[0]}
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: 512, SkipEmpty: true}, f)
+				So(err, ShouldBeNil)
+				// The mockConn alternates between the two events.
+				So(f.Events, ShouldHaveLength, (eventCount+1)/2)
+				So(f.Empty, ShouldEqual, 0)
+				for _, e := range f.Events {
+					So(e.Payload, ShouldNotBeEmpty)
+				}
+			})
+
+			Convey("It should aggregate mixed-case payload keys when normalizing them", func() {
+				conn.events = []*p.Event{
+					payloadEvent(p.HTTP, "User-Agent:curl/7.68.0"),
+					payloadEvent(p.HTTP, "user-agent:curl/7.68.0"),
+				}
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, NormalizeKeys: true, Size: 512}, f)
+				So(err, ShouldBeNil)
+				So(f.UserAgents[p.HTTP]["curl/7.68.0"].Occurrence, ShouldEqual, eventCount)
+			})
+
+			Convey("It should discard a malformed datagram and keep the rest", func() {
+				conn.junkAt = 3
+				f := new(findings)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: 512}, f)
+				actual := f.Events
+				So(err, ShouldBeNil)
+
+				expected := make([]*p.Event, 0, eventCount-1)
+				for i := eventCount; i > 0; i-- {
+					if i == 3 {
+						continue
+					}
+					expected = append(expected, conn.events[i%len(conn.events)])
+				}
+
+				So(withoutReceivedAt(actual), ShouldResemble, expected)
+			})
+
+			Convey("It should return an error on a malformed datagram in strict mode", func() {
+				conn.junkAt = 3
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: 512, Strict: true}, new(findings))
+				So(err, ShouldBeError)
+			})
+
 			Convey("It should return an error if datagrams is zero", func() {
-				_, err := collectEvents(ctx, conn, 0, 512, 0)
+				err := collectEvents(ctx, conn, config{Datagrams: 0, Size: 512}, new(findings))
 				So(err, ShouldBeError)
 			})
 
 			Convey("It should return an error upon a conn.Write error", func() {
 				conn.wantWriteErr = fmt.Errorf("some error")
-				_, err := collectEvents(ctx, conn, eventCount, 512, 0)
+				err := collectEvents(ctx, conn, config{Datagrams: eventCount, Size: 512}, new(findings))
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func Test_collectEventsMaxInvalidRate(t *testing.T) {
+	Convey("Given mostly invalid events", t, func() {
+		events := append(append([]*p.Event{}, invalidEvents...), validEvents[0])
+		eventCount := 100 * minInvalidRateSample
+
+		Convey("When collecting them with a maximum invalid rate", func() {
+			conn := &mockConn{maxEvents: int64(eventCount), events: events}
+			f := new(findings)
+			err := collectEvents(context.Background(), conn, config{
+				Datagrams:      eventCount,
+				MaxInvalidRate: 0.5,
+				Quiet:          true,
+				Size:           512,
+			}, f)
+
+			Convey("It should abort once the minimum sample arrives", func() {
+				So(errors.Is(err, ErrInvalidRate), ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, fmt.Sprintf("of %d events", minInvalidRateSample))
+				So(f.total(), ShouldBeLessThan, minInvalidRateSample)
+			})
+		})
+
+		Convey("When collecting them with a tolerant maximum invalid rate", func() {
+			conn := &mockConn{maxEvents: int64(minInvalidRateSample), events: events}
+			err := collectEvents(context.Background(), conn, config{
+				Datagrams:      minInvalidRateSample,
+				MaxInvalidRate: 1,
+				Quiet:          true,
+				Size:           512,
+			}, new(findings))
+
+			Convey("It should collect every event", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When collecting them with an invalid maximum rate", func() {
+			conn := &mockConn{maxEvents: int64(minInvalidRateSample), events: events}
+			err := collectEvents(context.Background(), conn, config{
+				Datagrams:      minInvalidRateSample,
+				MaxInvalidRate: 1.5,
+				Size:           512,
+			}, new(findings))
+
+			Convey("It should return an error", func() {
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func Test_collectEventsKeepRaw(t *testing.T) {
+	Convey("Given replayed events", t, func() {
+		Convey("When collecting them, keeping their raw datagrams", func() {
+			conn := &mockConn{maxEvents: int64(len(validEvents)), events: validEvents}
+			f := new(findings)
+			err := collectEvents(context.Background(), conn, config{
+				Datagrams: len(validEvents),
+				KeepRaw:   true,
+				Quiet:     true,
+				Size:      512,
+			}, f)
+
+			Convey("It should retain each event's datagram exactly", func() {
+				So(err, ShouldBeNil)
+				So(f.Events, ShouldHaveLength, len(validEvents))
+				for _, e := range f.Events {
+					So(e.Raw, ShouldNotBeEmpty)
+					b, err := e.MarshalBinary()
+					So(err, ShouldBeNil)
+					So(e.Raw, ShouldResemble, b)
+					So(cap(e.Raw), ShouldBeLessThan, 512) // not the read buffer
+				}
+			})
+
+			Convey("It should exclude Raw from the binary representation and comparison", func() {
+				e := *f.Events[0]
+				e.Raw = append(bytes.Clone(e.Raw), 0xff)
+				b, err := e.MarshalBinary()
+				So(err, ShouldBeNil)
+				So(b, ShouldResemble, f.Events[0].Raw)
+				So(p.CompareEvents(&e, f.Events[0]), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When collecting them without keeping their raw datagrams", func() {
+			conn := &mockConn{maxEvents: int64(len(validEvents)), events: validEvents}
+			f := new(findings)
+			err := collectEvents(context.Background(), conn, config{
+				Datagrams: len(validEvents),
+				Quiet:     true,
+				Size:      512,
+			}, f)
+
+			Convey("It should leave Raw empty", func() {
+				So(err, ShouldBeNil)
+				for _, e := range f.Events {
+					So(e.Raw, ShouldBeNil)
+				}
+			})
+		})
+	})
+}
+
+func Test_collectEventsNoResponse(t *testing.T) {
+	Convey("Given a UDP address nobody listens on", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		l, err := net.ListenPacket("udp", "127.0.0.1:")
+		So(err, ShouldBeNil)
+		addr := l.LocalAddr().String()
+		So(l.Close(), ShouldBeNil)
+
+		conn, err := net.Dial("udp", addr)
+		So(err, ShouldBeNil)
+		defer func() { _ = conn.Close() }()
+
+		Convey("When collecting events with an introduction timeout", func() {
+			start := time.Now()
+			err := collectEvents(ctx, conn, config{
+				Datagrams:    1,
+				IntroTimeout: 100 * time.Millisecond,
+				Quiet:        true,
+				Size:         512,
+			}, new(findings))
+
+			Convey("It should give up promptly, asking whether the server is running", func() {
+				So(errors.Is(err, ErrNoResponse), ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, "is the server running?")
+				So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+			})
+		})
+
+		Convey("When auto-counting events with the default timeouts", func() {
+			err := collectEvents(ctx, conn, config{
+				AutoCount:    true,
+				IntroTimeout: defaultIntroTimeout,
+				Quiet:        true,
+				Size:         512,
+			}, new(findings))
+
+			Convey("It should give up on the server rather than going idle", func() {
+				So(errors.Is(err, ErrNoResponse), ShouldBeTrue)
+				So(errors.Is(err, ErrNoEvents), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func Test_collectEventsDrain(t *testing.T) {
+	Convey("Given datagrams buffered when collection is canceled", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var events []*p.Event
+		for i := 0; i < 10; i++ {
+			events = append(events, validEvents...)
+		}
+		conn := &cancelingConn{
+			buffered: make(chan struct{}),
+			cancel:   cancel,
+			closed:   make(chan struct{}),
+			events:   events,
+		}
+		defer close(conn.closed)
+
+		Convey("When collecting events", func() {
+			f := new(findings)
+			err := collectEvents(ctx, conn, config{
+				CacheDatagrams: len(events),
+				Datagrams:      len(events),
+				Quiet:          true,
+				Size:           512,
+			}, f)
+
+			Convey("It should drain and collect every buffered datagram", func() {
+				So(err, ShouldBeNil)
+				So(f.Events, ShouldHaveLength, len(events))
+			})
+		})
+
+		Convey("When collecting events on a clock passing the drain timeout with each event", func() {
+			clock := &fakeClock{now: time.Date(2020, 10, 15, 0, 0, 0, 0, time.UTC)}
+			f := &findings{
+				Clock:   clock,
+				OnEvent: func(*p.Event) { clock.advance(time.Second) },
+			}
+			err := collectEvents(ctx, conn, config{
+				CacheDatagrams: len(events),
+				Datagrams:      len(events),
+				Quiet:          true,
+				Size:           512,
+			}, f)
+
+			Convey("It should abandon the rest of the buffered datagrams by the clock", func() {
+				So(err, ShouldBeNil)
+				So(len(f.Events), ShouldBeLessThan, len(events))
+			})
+		})
+	})
+}
+
+func Test_columnsFixed(t *testing.T) {
+	Convey("Given a fixed report width", t, func() {
+		fixedColumns = 60
+		defer func() { fixedColumns = 0 }()
+
+		Convey("When detecting the columns of stdout and stderr", func() {
+			Convey("It should return the fixed width regardless of the terminal", func() {
+				So(columns(os.Stdout), ShouldEqual, 60)
+				So(columns(os.Stderr), ShouldEqual, 60)
+			})
+		})
+
+		Convey("When drawing the progress bar", func() {
+			stderr, err := capture(&os.Stderr, func() error {
+				progress(2, 4, 0, 1)
+
+				return nil
+			})
+
+			Convey("It should size the bar to the fixed width", func() {
+				So(err, ShouldBeNil)
+				line := strings.TrimPrefix(stderr, "\r")
+				bar := line[strings.Index(line, "|")+1 : strings.LastIndex(line, "|")]
+				So(len(bar), ShouldEqual, 60-30-len("100.0"))
+				So(bar, ShouldEqual, strings.Repeat("#", 12)+strings.Repeat("-", 13))
+			})
+		})
+	})
+}
+
+func Test_setReadBuffer(t *testing.T) {
+	Convey("Given a UDP connection", t, func() {
+		conn, err := net.Dial("udp", "localhost:1035")
+		So(err, ShouldBeNil)
+		defer func() { _ = conn.Close() }()
+
+		Convey("When setting its receive buffer size", func() {
+			err := setReadBuffer(conn, 1<<20)
+
+			Convey("It should succeed", func() {
+				So(err, ShouldBeNil)
+
+				// The OS may adjust the size, so only check it's set.
+				size, err := readBufferSize(conn.(*net.UDPConn))
+				So(err, ShouldBeNil)
+				So(size, ShouldBeGreaterThan, 0)
+			})
+		})
+	})
+
+	Convey("Given a connection without a socket", t, func() {
+		conn := new(mockConn)
+
+		Convey("When setting its receive buffer size", func() {
+			err := setReadBuffer(conn, 1<<20)
+
+			Convey("It should return an error", func() {
 				So(err, ShouldBeError)
 			})
 		})
@@ -113,7 +863,7 @@ func Test_readDatagrams(t *testing.T) {
 		Convey("When calling the readDatagrams function", func() {
 			Convey("It should read datagrams from the net.Conn", func() {
 				chDatagrams := make(chan io.Reader)
-				go readDatagrams(ctx, conn, chDatagrams, 512)
+				go readDatagrams(ctx, conn, chDatagrams, 512, 0, 0)
 
 				for i := 4; i > 0; i-- {
 					r := <-chDatagrams
@@ -133,7 +883,7 @@ func Test_readDatagrams(t *testing.T) {
 				conn.wantReadErr = fmt.Errorf("some error")
 
 				chDatagrams := make(chan io.Reader)
-				go readDatagrams(ctx, conn, chDatagrams, 512)
+				go readDatagrams(ctx, conn, chDatagrams, 512, 0, 0)
 
 				for {
 					r, ok := <-chDatagrams
@@ -147,11 +897,64 @@ func Test_readDatagrams(t *testing.T) {
 				}
 			})
 
+			Convey("It should close the channel once reaching the byte limit", func() {
+				chDatagrams := make(chan io.Reader)
+				go readDatagrams(ctx, conn, chDatagrams, 512, 0, 1)
+
+				_, ok := <-chDatagrams
+				So(ok, ShouldBeTrue)
+				_, ok = <-chDatagrams
+				So(ok, ShouldBeFalse)
+			})
+
+			Convey("It should skip datagrams larger than the buffer", func() {
+				chDatagrams := make(chan io.Reader)
+				go readDatagrams(ctx, conn, chDatagrams, 70, 0, 0)
+
+				// Of the four datagrams, only the 69-byte events fit in the
+				// 70-byte buffer.
+				for _, i := range []int{3, 1} {
+					r, ok := <-chDatagrams
+					So(ok, ShouldBeTrue)
+
+					mb, err := conn.events[i].MarshalBinary()
+					So(err, ShouldBeNil)
+					So(r.(*bytes.Buffer).Bytes(), ShouldResemble, mb)
+				}
+
+				_, ok := <-chDatagrams
+				So(ok, ShouldBeFalse)
+			})
+
+			Convey("It should skip UDP datagrams the kernel truncated", func() {
+				addr, err := udpServer(validEvents)
+				So(err, ShouldBeNil)
+
+				udpConn, err := net.Dial("udp", addr.String())
+				So(err, ShouldBeNil)
+				_, err = udpConn.Write([]byte("hello"))
+				So(err, ShouldBeNil)
+
+				chDatagrams := make(chan io.Reader)
+				go readDatagrams(ctx, udpConn, chDatagrams, 70, 0, 0)
+
+				for _, i := range []int{0, 1, 3} {
+					r := <-chDatagrams
+					mb, err := validEvents[i].MarshalBinary()
+					So(err, ShouldBeNil)
+					So(r.(*bytes.Buffer).Bytes(), ShouldResemble, mb)
+				}
+
+				_ = udpConn.Close()
+				_, ok := <-chDatagrams
+				So(ok, ShouldBeFalse)
+			})
+
 			Convey("It should return when the context is closed", func() {
 				done := make(chan struct{})
 
 				go func() {
-					readDatagrams(ctx, conn, make(chan io.Reader), 512)
+					readDatagrams(ctx, conn, make(chan io.Reader), 512, 0, 0)
 					close(done)
 				}()
 
@@ -173,18 +976,125 @@ func Test_run(t *testing.T) {
 				addr, err := udpServer(validEvents)
 				So(err, ShouldBeNil)
 
-				err = run(
-					addr.String(),
-					len(validEvents),
-					minDatagramBytes,
-					0,
-					netip.MustParseAddr("106.54.93.84"),
-				)
+				err = run(config{
+					Address:   addr.String(),
+					Datagrams: len(validEvents),
+					Size:      minDatagramBytes,
+					DetailIP:  netip.MustParseAddr("106.54.93.84"),
+				})
+				So(err, ShouldBeNil)
+			})
+
+			Convey("It should count the duplicates it collapses", func() {
+				events := append(validEvents[:len(validEvents):len(validEvents)], validEvents[:2]...)
+				addr, err := udpServer(events)
+				So(err, ShouldBeNil)
+
+				res, err := runWithResult(config{
+					Address:   addr.String(),
+					Datagrams: len(events),
+					Dedup:     "uuid",
+					NoReport:  true,
+					Quiet:     true,
+					Size:      minDatagramBytes,
+				})
+				So(err, ShouldBeNil)
+				So(res.Duplicates, ShouldResemble, map[string]int{dedupUUID: 2})
+			})
+
+			Convey("It should write only the report to stdout when quiet", func() {
+				addr, err := udpServer(validEvents)
+				So(err, ShouldBeNil)
+
+				stdout, err := captureStdout(func() error {
+					return run(config{
+						Address:   addr.String(),
+						Datagrams: len(validEvents),
+						Quiet:     true,
+						Size:      minDatagramBytes,
+					})
+				})
+				So(err, ShouldBeNil)
+
+				f := &findings{Events: validEvents}
+				report, err := f.report()
+				So(err, ShouldBeNil)
+
+				// The receive latencies vary from run to run, so compare the
+				// report preceding them, which closes it.
+				So(stdout, ShouldStartWith, "\n\n"+report)
+				So(stdout, ShouldContainSubstring, "How long did events take to arrive?")
+				So(stdout, ShouldEndWith, "\n\n")
+			})
+
+			Convey("It should still print the report, but fail, given a failing -assert", func() {
+				addr, err := udpServer(validEvents)
+				So(err, ShouldBeNil)
+
+				var stdout string
+				stderr, err := capture(&os.Stderr, func() error {
+					var err error
+					stdout, err = captureStdout(func() error {
+						return run(config{
+							Address:   addr.String(),
+							Assert:    "SSH>=2,HTTP>1",
+							Datagrams: len(validEvents),
+							Quiet:     true,
+							Size:      minDatagramBytes,
+						})
+					})
+
+					return err
+				})
+				So(errors.Is(err, ErrAssertionFailed), ShouldBeTrue)
+				So(stderr, ShouldContainSubstring, "PASS SSH>=2 (SSH=2)\nFAIL HTTP>1 (HTTP=1)\n")
+				So(stdout, ShouldContainSubstring, "passwords and users?")
+			})
+
+			Convey("It should only archive events, without the report, given -no-report", func() {
+				// HTTP events alone would leave most report sections empty.
+				http := validEvents[len(validEvents)-1:]
+				addr, err := udpServer(http)
+				So(err, ShouldBeNil)
+
+				path := filepath.Join(t.TempDir(), "events.bin")
+				stdout, err := captureStdout(func() error {
+					return run(config{
+						Address:   addr.String(),
+						Datagrams: len(http),
+						EventsOut: path,
+						NoReport:  true,
+						Quiet:     true,
+						Size:      minDatagramBytes,
+					})
+				})
+				So(err, ShouldBeNil)
+				So(stdout, ShouldBeEmpty)
+
+				b, err := os.ReadFile(path)
+				So(err, ShouldBeNil)
+				e := new(p.Event)
+				_, err = e.ReadFrom(bytes.NewReader(b))
 				So(err, ShouldBeNil)
+				So(withoutReceivedAt([]*p.Event{e}), ShouldResemble, http)
+			})
+
+			Convey("It should return an error given -events-out in low-memory mode", func() {
+				err := run(config{
+					Address:   "localhost:1035",
+					Datagrams: 1,
+					EventsOut: "events.bin",
+					LowMemory: true,
+				})
+				So(err, ShouldBeError)
 			})
 
 			Convey("It should return an error given an empty address", func() {
-				err := run("", 37529, minDatagramBytes, 0, netip.MustParseAddr("106.54.93.84"))
+				err := run(config{
+					Datagrams: 37529,
+					Size:      minDatagramBytes,
+					DetailIP:  netip.MustParseAddr("106.54.93.84"),
+				})
 				So(err, ShouldBeError)
 			})
 
@@ -192,28 +1102,60 @@ func Test_run(t *testing.T) {
 				addr, err := udpServer(validEvents)
 				So(err, ShouldBeNil)
 
-				err = run(
-					addr.String(),
-					0,
-					minDatagramBytes,
-					0,
-					netip.MustParseAddr("106.54.93.84"),
-				)
+				err = run(config{
+					Address:   addr.String(),
+					Datagrams: 0,
+					Size:      minDatagramBytes,
+					DetailIP:  netip.MustParseAddr("106.54.93.84"),
+				})
 				So(err, ShouldBeError)
 			})
 
+			Convey("It should report on what arrived before reaching the byte limit", func() {
+				addr, err := udpServer(validEvents)
+				So(err, ShouldBeNil)
+
+				stdout, err := captureStdout(func() error {
+					return run(config{
+						Address:    addr.String(),
+						Datagrams:  len(validEvents),
+						LimitBytes: 1,
+						Quiet:      true,
+						Size:       minDatagramBytes,
+					})
+				})
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+				So(stdout, ShouldContainSubstring, "TOTAL EVENTS")
+			})
+
+			Convey("It should report once the server goes idle given more datagrams than it sends", func() {
+				addr, err := udpServer(validEvents)
+				So(err, ShouldBeNil)
+
+				stdout, err := captureStdout(func() error {
+					return run(config{
+						Address:     addr.String(),
+						Datagrams:   len(validEvents) * 100,
+						IdleTimeout: 250 * time.Millisecond,
+						Quiet:       true,
+						Size:        minDatagramBytes,
+					})
+				})
+				So(err, ShouldBeNil)
+				So(stdout, ShouldContainSubstring, "Who are the top 15 subitters?")
+			})
+
 			Convey("It should return an error if encountering an error generating the report", func() {
 				events := validEvents[:len(validEvents)-1]
 				addr, err := udpServer(events)
 				So(err, ShouldBeNil)
 
-				err = run(
-					addr.String(),
-					len(events),
-					minDatagramBytes,
-					0,
-					netip.MustParseAddr("106.54.93.84"),
-				)
+				err = run(config{
+					Address:   addr.String(),
+					Datagrams: len(events),
+					Size:      minDatagramBytes,
+					DetailIP:  netip.MustParseAddr("106.54.93.84"),
+				})
 				So(err, ShouldBeError)
 			})
 
@@ -230,14 +1172,18 @@ func Test_run(t *testing.T) {
 				addr, err := udpServer(events)
 				So(err, ShouldBeNil)
 
-				err = run(
-					addr.String(),
-					len(events),
-					minDatagramBytes,
-					0,
-					netip.MustParseAddr("106.54.93.84"),
-				)
+				err = run(config{
+					Address:   addr.String(),
+					Datagrams: len(events),
+					Size:      minDatagramBytes,
+					DetailIP:  netip.MustParseAddr("106.54.93.84"),
+				})
 				So(err, ShouldBeError)
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+
+				var mpe *missingProtocolError
+				So(errors.As(err, &mpe), ShouldBeTrue)
+				So(mpe.Protocol, ShouldEqual, p.SSH)
 			})
 
 			Convey("It should return an error if the report has no TELNET events", func() {
@@ -253,14 +1199,18 @@ func Test_run(t *testing.T) {
 				addr, err := udpServer(events)
 				So(err, ShouldBeNil)
 
-				err = run(
-					addr.String(),
-					len(events),
-					minDatagramBytes,
-					0,
-					netip.MustParseAddr("106.54.93.84"),
-				)
+				err = run(config{
+					Address:   addr.String(),
+					Datagrams: len(events),
+					Size:      minDatagramBytes,
+					DetailIP:  netip.MustParseAddr("106.54.93.84"),
+				})
 				So(err, ShouldBeError)
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+
+				var mpe *missingProtocolError
+				So(errors.As(err, &mpe), ShouldBeTrue)
+				So(mpe.Protocol, ShouldEqual, p.TELNET)
 			})
 
 			Convey("It should return an error if the report has no HTTP events", func() {
@@ -276,14 +1226,18 @@ func Test_run(t *testing.T) {
 				addr, err := udpServer(events)
 				So(err, ShouldBeNil)
 
-				err = run(
-					addr.String(),
-					len(events),
-					minDatagramBytes,
-					0,
-					netip.MustParseAddr("106.54.93.84"),
-				)
+				err = run(config{
+					Address:   addr.String(),
+					Datagrams: len(events),
+					Size:      minDatagramBytes,
+					DetailIP:  netip.MustParseAddr("106.54.93.84"),
+				})
 				So(err, ShouldBeError)
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+
+				var mpe *missingProtocolError
+				So(errors.As(err, &mpe), ShouldBeTrue)
+				So(mpe.Protocol, ShouldEqual, p.HTTP)
 			})
 
 			Convey("It should return an error if the report has no SMTP events", func() {
@@ -299,38 +1253,143 @@ func Test_run(t *testing.T) {
 				addr, err := udpServer(events)
 				So(err, ShouldBeNil)
 
-				err = run(
-					addr.String(),
-					len(events),
-					minDatagramBytes,
-					0,
-					netip.MustParseAddr("106.54.93.84"),
-				)
+				err = run(config{
+					Address:   addr.String(),
+					Datagrams: len(events),
+					Size:      minDatagramBytes,
+					DetailIP:  netip.MustParseAddr("106.54.93.84"),
+				})
 				So(err, ShouldBeError)
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+
+				var mpe *missingProtocolError
+				So(errors.As(err, &mpe), ShouldBeTrue)
+				So(mpe.Protocol, ShouldEqual, p.SMTP)
+			})
+
+			Convey("It should still print the report with placeholders for the missing protocols", func() {
+				events := make([]*p.Event, 0, len(validEvents))
+				for _, e := range validEvents {
+					if e.Protocol == p.SSH || e.Protocol == p.SMTP {
+						continue
+					}
+
+					events = append(events, e)
+				}
+
+				addr, err := udpServer(events)
+				So(err, ShouldBeNil)
+
+				stdout, err := captureStdout(func() error {
+					return run(config{
+						Address:   addr.String(),
+						Datagrams: len(events),
+						Quiet:     true,
+						Size:      minDatagramBytes,
+					})
+				})
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, "no SSH events")
+				So(err.Error(), ShouldContainSubstring, "no SMTP events")
+				So(stdout, ShouldContainSubstring, "FOUND")
+				So(stdout, ShouldContainSubstring, "Who are the top 15 subitters?")
 			})
 		})
 	})
 }
 
-func udpServer(events []*p.Event) (net.Addr, error) {
-	s, err := net.ListenPacket("udp", "localhost:")
+func Test_watch(t *testing.T) {
+	Convey("Given the address of an event server serving two clients", t, func() {
+		addr, err := udpServerN(validEvents, 2)
+		So(err, ShouldBeNil)
+
+		Convey("When watching for two cycles", func() {
+			stdout, err := captureStdout(func() error {
+				return watch(config{
+					Address:   addr.String(),
+					Datagrams: len(validEvents),
+					Quiet:     true,
+					Size:      minDatagramBytes,
+					Watch:     10 * time.Millisecond,
+				}, 2)
+			})
+
+			Convey("It should render a report each cycle", func() {
+				So(err, ShouldBeNil)
+				So(strings.Count(stdout, "Who are the top 15 subitters?"), ShouldEqual, 2)
+				So(strings.Count(stdout, "\u001b[H\u001b[2J"), ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+// captureStdout returns everything written to os.Stdout while calling fn.
+func captureStdout(fn func() error) (string, error) {
+	return capture(&os.Stdout, fn)
+}
+
+// capture returns everything written to the file *f, such as os.Stderr, while
+// calling fn.
+func capture(f **os.File, fn func() error) (string, error) {
+	r, w, err := os.Pipe()
 	if err != nil {
-		return nil, fmt.Errorf("binding to udp localhost: %w", err)
+		return "", err
 	}
 
+	orig := *f
+	*f = w
+	defer func() { *f = orig }()
+
+	out := make(chan string)
 	go func() {
-		_, clientAddr, err := s.ReadFrom(make([]byte, 1024))
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+
+	err = fn()
+	_ = w.Close()
+
+	return <-out, err
+}
+
+func udpServer(events []*p.Event) (net.Addr, error) {
+	return udpServerN(events, 1)
+}
+
+// udpServerN sends the events to each of the given number of clients in turn,
+// closing after serving the last one.
+func udpServerN(events []*p.Event, clients int) (net.Addr, error) {
+	datagrams := make([][]byte, 0, len(events))
+	for _, event := range events {
+		b, err := event.MarshalBinary()
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
+		datagrams = append(datagrams, b)
+	}
+
+	return udpDatagramServer(datagrams, clients)
+}
 
-		for _, event := range events {
-			b, err := event.MarshalBinary()
+// udpDatagramServer sends the datagrams to each of the given number of clients
+// in turn, closing after serving the last one.
+func udpDatagramServer(datagrams [][]byte, clients int) (net.Addr, error) {
+	s, err := net.ListenPacket("udp", "localhost:")
+	if err != nil {
+		return nil, fmt.Errorf("binding to udp localhost: %w", err)
+	}
+
+	go func() {
+		for i := 0; i < clients; i++ {
+			_, clientAddr, err := s.ReadFrom(make([]byte, 1024))
 			if err != nil {
 				panic(err)
 			}
-			if _, err = s.WriteTo(b, clientAddr); err != nil {
-				panic(err)
+
+			for _, b := range datagrams {
+				if _, err = s.WriteTo(b, clientAddr); err != nil {
+					panic(err)
+				}
 			}
 		}
 
@@ -340,11 +1399,57 @@ func udpServer(events []*p.Event) (net.Addr, error) {
 	return s.LocalAddr(), nil
 }
 
+// countingReporter counts the reports it's asked to deliver.
+type countingReporter struct {
+	reports int
+}
+
+// Report implements the reporter interface.
+func (c *countingReporter) Report(*findings) error {
+	c.reports++
+
+	return nil
+}
+
+// withoutReceivedAt returns copies of the events with zero ReceivedAt fields,
+// for comparison with events that were never received.
+func withoutReceivedAt(events []*p.Event) []*p.Event {
+	copies := make([]*p.Event, 0, len(events))
+	for _, e := range events {
+		c := *e
+		c.ReceivedAt = time.Time{}
+		copies = append(copies, &c)
+	}
+
+	return copies
+}
+
+// emptyPayloadEvent returns a valid event with a zero-length payload.
+func emptyPayloadEvent() *p.Event { return payloadEvent(p.SSH, "") }
+
+// payloadEvent returns a valid event of the protocol with the raw payload.
+func payloadEvent(proto p.Protocol, payload string) *p.Event {
+	e := &p.Event{
+		NodeID:       validEvents[0].NodeID,
+		TimeStamp:    validEvents[0].TimeStamp,
+		Size:         uint16(len(payload)),
+		EventUUID:    validEvents[0].EventUUID,
+		PayloadBytes: []byte(payload),
+		Protocol:     proto,
+		Submitter:    validEvents[0].Submitter,
+	}
+	b, _ := e.MarshalBinary()
+	e.CheckSum = crc32.ChecksumIEEE(b[:len(b)-4])
+
+	return e
+}
+
 // mockConn implements a subset of the net.Conn interface.
 type mockConn struct {
 	net.Conn
 
 	events       []*p.Event
+	junkAt       int
 	maxEvents    int64
 	wantReadErr  error
 	wantWriteErr error
@@ -363,14 +1468,17 @@ func (c *mockConn) Read(b []byte) (int, error) {
 		return 0, c.wantReadErr
 	}
 
+	if c.junkAt > 0 && count == c.junkAt {
+		// a datagram too short to be an event
+		return copy(b, []byte{0xde, 0xad}), nil
+	}
+
 	mb, err := (c.events[count%len(c.events)]).MarshalBinary()
 	if err != nil {
 		return 0, err
 	}
 
-	copy(b, mb)
-
-	return len(mb), nil
+	return copy(b, mb), nil
 }
 
 // Write implements the io.Writer interface.
@@ -382,6 +1490,48 @@ func (c *mockConn) Write(b []byte) (int, error) {
 	return len(b), nil
 }
 
+// cancelingConn serves its events, then blocks until closed. It cancels the
+// collection context upon the introduction, but only once every event has
+// been read into the datagram buffer.
+type cancelingConn struct {
+	net.Conn
+
+	buffered chan struct{}
+	cancel   context.CancelFunc
+	closed   chan struct{}
+	events   []*p.Event
+	reads    int64
+}
+
+// Read implements the io.Reader interface.
+func (c *cancelingConn) Read(b []byte) (int, error) {
+	n := int(atomic.AddInt64(&c.reads, 1))
+	if n > len(c.events) {
+		// Reading again means the last event made it into the buffer.
+		if n == len(c.events)+1 {
+			close(c.buffered)
+		}
+		<-c.closed
+
+		return 0, net.ErrClosed
+	}
+
+	mb, err := c.events[n-1].MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+
+	return copy(b, mb), nil
+}
+
+// Write implements the io.Writer interface.
+func (c *cancelingConn) Write(b []byte) (int, error) {
+	<-c.buffered
+	c.cancel()
+
+	return len(b), nil
+}
+
 var invalidEvents = []*p.Event{
 	{
 		NodeID:    0x7,
@@ -432,7 +1582,27 @@ var invalidEvents = []*p.Event{
 	},
 }
 
-var validEvents = []*p.Event{
+// readBack returns the events as ReadFrom reads them off the wire, checksum
+// and all, so they compare equal to the events collection parses.
+func readBack(events []*p.Event) []*p.Event {
+	read := make([]*p.Event, 0, len(events))
+	for _, e := range events {
+		b, err := e.MarshalBinary()
+		if err != nil {
+			panic(err)
+		}
+
+		r := new(p.Event)
+		if _, err = r.ReadFrom(bytes.NewReader(b)); err != nil {
+			panic(err)
+		}
+		read = append(read, r)
+	}
+
+	return read
+}
+
+var validEvents = readBack([]*p.Event{
 	{
 		NodeID:    0xa,
 		TimeStamp: 0x5f879100,
@@ -453,6 +1623,7 @@ var validEvents = []*p.Event{
 		CheckSum:     0xa1c010c3,
 		PayloadBytes: []uint8{0x65, 0x6d, 0x61, 0x69, 0x6c, 0x3a, 0x63, 0x68, 0x6c, 0x6f, 0x65, 0x73, 0x6d, 0x69, 0x74, 0x68, 0x32, 0x36, 0x33, 0x40, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x6e, 0x65, 0x74},
 		IP:           netip.MustParseAddr("233.20.181.96"),
+		PayloadOrder: []string{"email"},
 	},
 	{
 		NodeID:    0x2,
@@ -478,7 +1649,8 @@ var validEvents = []*p.Event{
 			0x68, 0x2c, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x3a, 0x4a, 0x61, 0x63,
 			0x6b, 0x61, 0x6c, 0x6c, 0x61, 0x76, 0x61,
 		},
-		IP: netip.MustParseAddr("106.67.111.15"),
+		IP:           netip.MustParseAddr("106.67.111.15"),
+		PayloadOrder: []string{"username", "password"},
 	},
 	{
 		NodeID:    0x4,
@@ -504,7 +1676,8 @@ var validEvents = []*p.Event{
 			0x2c, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x3a, 0x53, 0x68, 0x72, 0x69,
 			0x65, 0x6b, 0x65, 0x72, 0x6c, 0x61, 0x76, 0x65, 0x6e, 0x64, 0x65, 0x72,
 		},
-		IP: netip.MustParseAddr("218.112.232.128"),
+		IP:           netip.MustParseAddr("218.112.232.128"),
+		PayloadOrder: []string{"username", "password"},
 	},
 	{
 		NodeID:    0x9,
@@ -530,7 +1703,8 @@ var validEvents = []*p.Event{
 			0x61, 0x6d, 0x2c, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x3a, 0x4c, 0x61,
 			0x73, 0x68, 0x65, 0x72, 0x66, 0x61, 0x6e,
 		},
-		IP: netip.MustParseAddr("130.21.96.80"),
+		IP:           netip.MustParseAddr("130.21.96.80"),
+		PayloadOrder: []string{"username", "password"},
 	},
 	{
 		NodeID:    0xb,
@@ -562,6 +1736,7 @@ var validEvents = []*p.Event{
 			0x69, 0x2f, 0x35, 0x33, 0x37, 0x2e, 0x33, 0x36, 0x20, 0x4f, 0x50, 0x52, 0x2f, 0x34,
 			0x37, 0x2e, 0x30, 0x2e, 0x32, 0x36, 0x33, 0x31, 0x2e, 0x35, 0x35,
 		},
-		IP: netip.MustParseAddr("71.193.249.225"),
+		IP:           netip.MustParseAddr("71.193.249.225"),
+		PayloadOrder: []string{"user-agent"},
 	},
-}
+})
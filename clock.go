@@ -0,0 +1,15 @@
+package main
+
+import "time"
+
+// Clock tells the current time. Time-based features take the time from a Clock
+// so tests can drive them deterministically without sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock backed by time.Now.
+type systemClock struct{}
+
+// Now implements the Clock interface.
+func (systemClock) Now() time.Time { return time.Now() }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// fakeClock is a Clock whose time only changes when advanced.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func Test_collectEventsClock(t *testing.T) {
+	Convey("Given findings with a window and a fake clock", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		clock := &fakeClock{now: time.Date(2020, 10, 15, 0, 0, 0, 0, time.UTC)}
+		f := &findings{Clock: clock, Window: time.Minute}
+		conn := &mockConn{maxEvents: int64(len(validEvents)), events: validEvents}
+
+		Convey("When collecting events", func() {
+			err := collectEvents(ctx, conn, config{Datagrams: len(validEvents), Quiet: true, Size: 512}, f)
+			So(err, ShouldBeNil)
+
+			Convey("It should stamp each event with the clock's time", func() {
+				So(f.Events, ShouldHaveLength, len(validEvents))
+				for _, e := range f.Events {
+					So(e.ReceivedAt, ShouldEqual, clock.now)
+				}
+			})
+
+			Convey("It should evict them once the clock passes the window", func() {
+				clock.advance(2 * time.Minute)
+				f.Add(&p.Event{Protocol: p.SSH, IP: validEvents[0].IP})
+
+				So(f.Events, ShouldHaveLength, 1)
+				So(f.ByProtocol[p.SSH].Occurrence, ShouldEqual, 1)
+			})
+		})
+	})
+}
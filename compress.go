@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// maxDecompressedBytes limits how large a compressed datagram may grow, so a
+// malicious datagram can't exhaust memory.
+const maxDecompressedBytes = 1 << 20
+
+// gzipMagic prefixes gzip-compressed data.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns a reader of the datagram's decompressed bytes if the
+// datagram begins with the gzip magic bytes. Otherwise, it returns the
+// datagram as is, so compressed and uncompressed datagrams may be mixed.
+// Compression is per-datagram; each compressed datagram is a complete gzip
+// member.
+func decompress(datagram io.Reader) (io.Reader, error) {
+	b, ok := datagram.(interface{ Bytes() []byte })
+	if !ok || !bytes.HasPrefix(b.Bytes(), gzipMagic) {
+		return datagram, nil
+	}
+
+	zr, err := gzip.NewReader(datagram)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing datagram: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	buf := new(bytes.Buffer)
+	n, err := io.Copy(buf, io.LimitReader(zr, maxDecompressedBytes+1))
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("decompressing datagram: %w", err)
+	case n > maxDecompressedBytes:
+		return nil, fmt.Errorf("decompressed datagram exceeds %d bytes", maxDecompressedBytes)
+	}
+
+	return buf, nil
+}
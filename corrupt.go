@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+)
+
+// corrupter flips a random bit in a fraction of datagrams, exercising the
+// handling of corrupt events.
+type corrupter struct {
+	rate float64
+	rng  *rand.Rand
+}
+
+// newCorrupter returns a corrupter of the given fraction of datagrams, drawing
+// its choices from rng.
+func newCorrupter(rate float64, rng *rand.Rand) *corrupter {
+	return &corrupter{rate: rate, rng: rng}
+}
+
+// corrupt returns the datagram, having flipped one of its bits should it fall
+// within the corrupter's rate. The boolean is true if it flipped a bit.
+func (c *corrupter) corrupt(datagram io.Reader) (io.Reader, bool) {
+	b, ok := datagram.(interface{ Bytes() []byte })
+	if !ok || len(b.Bytes()) == 0 || c.rng.Float64() >= c.rate {
+		return datagram, false
+	}
+
+	corrupted := bytes.Clone(b.Bytes())
+	bit := c.rng.Intn(len(corrupted) * 8)
+	corrupted[bit/8] ^= 1 << (bit % 8)
+
+	return bytes.NewBuffer(corrupted), true
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_corrupter(t *testing.T) {
+	Convey("Given a corrupter of every datagram", t, func() {
+		c := newCorrupter(1, rand.New(rand.NewSource(1)))
+		original := []byte{0x00, 0xff, 0x0f}
+
+		Convey("When corrupting a datagram", func() {
+			r, flipped := c.corrupt(bytes.NewBuffer(bytes.Clone(original)))
+
+			Convey("It should flip exactly one bit", func() {
+				So(flipped, ShouldBeTrue)
+
+				diff := 0
+				for i, b := range r.(*bytes.Buffer).Bytes() {
+					for x := b ^ original[i]; x != 0; x &= x - 1 {
+						diff++
+					}
+				}
+				So(diff, ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func Test_collectEventsCorrupt(t *testing.T) {
+	Convey("Given replayed events and a corruption seed", t, func() {
+		const (
+			rate = 0.3
+			seed = 1913
+		)
+		eventCount := len(validEvents) * 10
+
+		// Corrupting copies of the datagrams with an identically seeded
+		// corrupter predicts which datagrams collectEvents will corrupt.
+		c := newCorrupter(rate, rand.New(rand.NewSource(seed)))
+		corrupted := 0
+		for i := 0; i < eventCount; i++ {
+			b, err := validEvents[(i+1)%len(validEvents)].MarshalBinary()
+			So(err, ShouldBeNil)
+			if _, flipped := c.corrupt(bytes.NewBuffer(b)); flipped {
+				corrupted++
+			}
+		}
+		So(corrupted, ShouldBeGreaterThan, 0)
+
+		Convey("When collecting the events", func() {
+			conn := &mockConn{maxEvents: int64(eventCount), events: validEvents}
+			f := new(findings)
+			err := collectEvents(context.Background(), conn, config{
+				Corrupt:   rate,
+				Datagrams: eventCount,
+				Quiet:     true,
+				Rand:      rand.New(rand.NewSource(seed)),
+				Size:      512,
+			}, f)
+
+			Convey("It should reject the corrupted events and keep the rest", func() {
+				So(err, ShouldBeNil)
+				So(f.Events, ShouldHaveLength, eventCount-corrupted)
+			})
+		})
+
+		Convey("When collecting the events with an invalid rate", func() {
+			conn := &mockConn{maxEvents: int64(eventCount), events: validEvents}
+			err := collectEvents(context.Background(), conn, config{
+				Corrupt:   1.5,
+				Datagrams: eventCount,
+				Size:      512,
+			}, new(findings))
+
+			Convey("It should return an error", func() {
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// Deduplication modes.
+const (
+	dedupUUID        = "uuid"        // events sharing a UUID
+	dedupFingerprint = "fingerprint" // events differing only by UUID and checksum
+)
+
+// copies counts the copies received of a distinct event, itself included.
+type copies struct {
+	Protocol  p.Protocol
+	Submitter netip.Addr
+	Count     int
+}
+
+// deduper collapses duplicate events, counting the duplicates each mode
+// collapses.
+type deduper struct {
+	fingerprints map[uint64]*copies
+	uuids        map[p.UUID]*copies
+
+	// Collapsed counts the duplicates collapsed by each mode. An event
+	// duplicating another by more than one mode counts toward the first.
+	Collapsed map[string]int
+
+	// Copies counts the copies of each distinct event, in order of arrival,
+	// if the deduper retains them for the report.
+	Copies []*copies
+	report bool
+}
+
+// newDeduper returns a deduper for the comma-separated modes, retaining the
+// copies of each distinct event if report is true. Empty modes return a nil
+// deduper, which collapses nothing.
+func newDeduper(modes string, report bool) (*deduper, error) {
+	if modes == "" {
+		return nil, nil
+	}
+
+	d := &deduper{Collapsed: make(map[string]int), report: report}
+	for _, mode := range strings.Split(modes, ",") {
+		switch strings.ToLower(strings.TrimSpace(mode)) {
+		case dedupUUID:
+			d.uuids = make(map[p.UUID]*copies)
+		case dedupFingerprint:
+			d.fingerprints = make(map[uint64]*copies)
+		default:
+			return nil, fmt.Errorf("unknown dedup mode %q; use %s or %s", mode, dedupUUID, dedupFingerprint)
+		}
+	}
+
+	return d, nil
+}
+
+// duplicate returns true if the event duplicates an event the deduper has
+// seen, counting it as collapsed and as a copy of that event. Otherwise, it
+// remembers the event.
+func (d *deduper) duplicate(e *p.Event) bool {
+	if d == nil {
+		return false
+	}
+
+	if d.uuids != nil {
+		if c, ok := d.uuids[e.EventUUID]; ok {
+			d.Collapsed[dedupUUID]++
+			c.Count++
+			return true
+		}
+	}
+
+	c := &copies{Protocol: e.Protocol, Submitter: e.IP, Count: 1}
+	if d.fingerprints != nil {
+		fp := fingerprint(e)
+		if seen, ok := d.fingerprints[fp]; ok {
+			d.Collapsed[dedupFingerprint]++
+			seen.Count++
+			return true
+		}
+		d.fingerprints[fp] = c
+	}
+
+	if d.uuids != nil {
+		d.uuids[e.EventUUID] = c
+	}
+	if d.report {
+		d.Copies = append(d.Copies, c)
+	}
+
+	return false
+}
+
+// String summarizes the duplicates collapsed by each active mode, e.g.,
+// "collapsed 3 duplicate events (UUID), 1 (fingerprint)".
+func (d *deduper) String() string {
+	var counts []string
+	for _, m := range []struct {
+		active bool
+		mode   string
+		label  string
+	}{
+		{d.uuids != nil, dedupUUID, "UUID"},
+		{d.fingerprints != nil, dedupFingerprint, "fingerprint"},
+	} {
+		switch {
+		case !m.active:
+		case len(counts) == 0:
+			counts = append(counts, fmt.Sprintf("%d duplicate events (%s)", d.Collapsed[m.mode], m.label))
+		default:
+			counts = append(counts, fmt.Sprintf("%d (%s)", d.Collapsed[m.mode], m.label))
+		}
+	}
+
+	return "collapsed " + strings.Join(counts, ", ")
+}
+
+// fingerprint hashes the event's fields but its UUID and checksum, which a
+// retransmission may not preserve. It hashes the submitter's IP address, which,
+// unlike Submitter, distinguishes IPv6 submitters.
+func fingerprint(e *p.Event) uint64 {
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%d|%d|%d|%s|", e.NodeID, e.TimeStamp, e.Protocol, e.IP)
+	_, _ = h.Write(e.PayloadBytes)
+
+	return h.Sum64()
+}
+
+// copiesBuckets label the retransmission histogram's buckets by the number of
+// copies received of each distinct event.
+var copiesBuckets = []string{"1x", "2x", "3x+"}
+
+// copiesBucket returns the index of the copiesBuckets bucket counting n
+// copies.
+func copiesBucket(n int) int {
+	if n > len(copiesBuckets) {
+		n = len(copiesBuckets)
+	}
+
+	return n - 1
+}
+
+// retransmissionHistogram renders the number of distinct events by the number
+// of copies received of each, as a table with bars.
+func (f *findings) retransmissionHistogram(rf reportFormat) (string, error) {
+	counts := make([]int, len(copiesBuckets))
+	for _, c := range f.Copies {
+		counts[copiesBucket(c.Count)]++
+	}
+
+	most := 0
+	for _, n := range counts {
+		if n > most {
+			most = n
+		}
+	}
+
+	d := pterm.TableData{{"Copies", "Events", "%", ""}}
+	for i, n := range counts {
+		bar := ""
+		if most > 0 {
+			bar = strings.Repeat("█", n*histogramBarWidth/most)
+		}
+		d = append(d, []string{copiesBuckets[i], thousands(n), percent(n, len(f.Copies)), bar})
+	}
+	d = append(d,
+		[]string{
+			pterm.DefaultTable.HeaderStyle.Sprint("TOTAL"),
+			pterm.DefaultTable.HeaderStyle.Sprint(thousands(len(f.Copies))),
+			"",
+			"",
+		},
+	)
+
+	return rf.table(d)
+}
+
+// duplication tallies the distinct events of a protocol or submitter and the
+// duplicates received of them.
+type duplication struct {
+	item       string
+	events     int
+	duplicated int // distinct events received more than once
+	duplicates int // copies beyond the first
+}
+
+// row renders the duplication as a table row.
+func (v *duplication) row() []string {
+	return []string{
+		v.item,
+		thousands(v.events),
+		thousands(v.duplicated),
+		thousands(v.duplicates),
+		percent(v.duplicated, v.events),
+	}
+}
+
+// duplications tallies the Copies by the key, sorted by most duplicates, then
+// by the order func.
+func (f *findings) duplications(key func(*copies) string, less func(a, b string) bool) []*duplication {
+	m := make(map[string]*duplication)
+	for _, c := range f.Copies {
+		k := key(c)
+		v := m[k]
+		if v == nil {
+			v = &duplication{item: k}
+			m[k] = v
+		}
+		v.events++
+		if c.Count > 1 {
+			v.duplicated++
+			v.duplicates += c.Count - 1
+		}
+	}
+
+	s := make([]*duplication, 0, len(m))
+	for _, v := range m {
+		s = append(s, v)
+	}
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].duplicates != s[j].duplicates {
+			return s[i].duplicates > s[j].duplicates
+		}
+
+		return less(s[i].item, s[j].item)
+	})
+
+	return s
+}
+
+// retransmissionsByProtocol renders the duplicates received of each protocol's
+// events, most duplicated first.
+func (f *findings) retransmissionsByProtocol(rf reportFormat) (string, error) {
+	d := pterm.TableData{{"Protocol", "Events", "Duplicated", "Duplicates", "Duplicated %"}}
+	for _, v := range f.duplications(
+		func(c *copies) string { return dotProtocol(c.Protocol) },
+		func(a, b string) bool { return a < b },
+	) {
+		d = append(d, v.row())
+	}
+
+	return rf.table(d)
+}
+
+// retransmissionsBySubmitter renders the duplicates received of the count
+// submitters whose events were most duplicated.
+func (f *findings) retransmissionsBySubmitter(rf reportFormat, count int) (string, error) {
+	d := pterm.TableData{{"#", "Submitter", "Events", "Duplicated", "Duplicates", "Duplicated %"}}
+	for i, v := range f.duplications(
+		func(c *copies) string { return c.Submitter.String() },
+		func(a, b string) bool {
+			addrA, errA := netip.ParseAddr(a)
+			addrB, errB := netip.ParseAddr(b)
+			if errA != nil || errB != nil {
+				return a < b
+			}
+
+			return addrA.Less(addrB)
+		},
+	) {
+		if i == count {
+			break
+		}
+		d = append(d, append([]string{strconv.Itoa(i + 1)}, v.row()...))
+	}
+
+	return rf.table(d)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/pterm/pterm"
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_deduper(t *testing.T) {
+	Convey("Given events with known duplicates", t, func() {
+		retransmitted := *validEvents[1]
+		retransmitted.EventUUID.TimeLow++
+
+		events := []*p.Event{
+			validEvents[0],
+			validEvents[1],
+			validEvents[0], // UUID duplicate
+			&retransmitted, // fingerprint duplicate of validEvents[1]
+			validEvents[1], // UUID duplicate
+		}
+
+		Convey("When deduplicating them by UUID and fingerprint", func() {
+			d, err := newDeduper("uuid, fingerprint", false)
+			So(err, ShouldBeNil)
+
+			var kept []*p.Event
+			for _, e := range events {
+				if !d.duplicate(e) {
+					kept = append(kept, e)
+				}
+			}
+
+			Convey("It should count the duplicates collapsed by each mode", func() {
+				So(kept, ShouldResemble, []*p.Event{validEvents[0], validEvents[1]})
+				So(d.Collapsed, ShouldResemble, map[string]int{dedupUUID: 2, dedupFingerprint: 1})
+				So(d.String(), ShouldEqual, "collapsed 2 duplicate events (UUID), 1 (fingerprint)")
+			})
+
+			Convey("It should retain no copies without the report", func() {
+				So(d.Copies, ShouldBeNil)
+			})
+		})
+
+		Convey("When deduplicating them by UUID alone", func() {
+			d, err := newDeduper("uuid", false)
+			So(err, ShouldBeNil)
+			for _, e := range events {
+				d.duplicate(e)
+			}
+
+			Convey("It should keep the retransmission", func() {
+				So(d.Collapsed, ShouldResemble, map[string]int{dedupUUID: 2})
+				So(d.String(), ShouldEqual, "collapsed 2 duplicate events (UUID)")
+			})
+		})
+	})
+
+	Convey("Given identical events from two IPv6 submitters", t, func() {
+		a, b := *validEvents[1], *validEvents[1]
+		a.IP, a.Submitter = netip.MustParseAddr("2001:db8::1"), 0
+		b.IP, b.Submitter = netip.MustParseAddr("2001:db8::2"), 0
+		b.EventUUID.TimeLow++
+
+		Convey("When deduplicating them by fingerprint", func() {
+			d, err := newDeduper("fingerprint", false)
+			So(err, ShouldBeNil)
+
+			Convey("It should keep both", func() {
+				So(d.duplicate(&a), ShouldBeFalse)
+				So(d.duplicate(&b), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given an unknown dedup mode", t, func() {
+		Convey("When creating a deduper", func() {
+			_, err := newDeduper("uuid,window", false)
+
+			Convey("It should return an error", func() {
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func Test_collectEventsDedup(t *testing.T) {
+	Convey("Given a server that repeats its events", t, func() {
+		eventCount := len(validEvents) * 3
+		conn := &mockConn{maxEvents: int64(eventCount), events: validEvents}
+
+		Convey("When collecting events deduplicated by UUID", func() {
+			f := new(findings)
+			err := collectEvents(context.Background(), conn, config{
+				Datagrams: eventCount,
+				Dedup:     "uuid",
+				Quiet:     true,
+				Size:      512,
+			}, f)
+
+			Convey("It should collapse the repeats and expose the count", func() {
+				So(err, ShouldBeNil)
+				So(f.Events, ShouldHaveLength, len(validEvents))
+				So(f.Duplicates[dedupUUID], ShouldEqual, eventCount-len(validEvents))
+			})
+		})
+	})
+}
+
+func Test_findings_retransmissions(t *testing.T) {
+	Convey("Given events received once, twice, three times, and four times", t, func() {
+		var events []*p.Event
+		for i, n := range []int{1, 2, 3, 4, 1} {
+			for j := 0; j < n; j++ {
+				events = append(events, validEvents[i])
+			}
+		}
+
+		d, err := newDeduper("uuid", true)
+		So(err, ShouldBeNil)
+		for _, e := range events {
+			d.duplicate(e)
+		}
+		f := &findings{Copies: d.Copies}
+
+		Convey("When rendering the retransmission histogram", func() {
+			s, err := f.retransmissionHistogram(reportFormat{})
+			So(err, ShouldBeNil)
+
+			Convey("It should bucket the distinct events as 1x, 2x, and 3x+, the fullest with the longest bar", func() {
+				So(f.Copies, ShouldHaveLength, len(validEvents))
+
+				rows := strings.Split(pterm.RemoveColorFromString(s), "\n")
+				So(rows[1], ShouldStartWith, "1x     | 2      | 40.0% | "+strings.Repeat("█", histogramBarWidth))
+				So(rows[2], ShouldStartWith, "2x     | 1      | 20.0% | "+strings.Repeat("█", histogramBarWidth/2)+" ")
+				So(rows[3], ShouldStartWith, "3x+    | 2      | 40.0% | "+strings.Repeat("█", histogramBarWidth))
+				So(rows[4], ShouldStartWith, "TOTAL  | 5 ")
+			})
+		})
+
+		Convey("When tallying the duplicates by protocol", func() {
+			s := f.duplications(
+				func(c *copies) string { return dotProtocol(c.Protocol) },
+				func(a, b string) bool { return a < b },
+			)
+
+			Convey("It should rank the protocols by their duplicates", func() {
+				So(s, ShouldHaveLength, 4)
+				So(*s[0], ShouldResemble, duplication{item: "SSH", events: 2, duplicated: 2, duplicates: 3})
+				So(*s[1], ShouldResemble, duplication{item: "TELNET", events: 1, duplicated: 1, duplicates: 3})
+				So(s[2].duplicates, ShouldEqual, 0)
+				So(s[3].duplicates, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When rendering the report's retransmissions section alone", func() {
+			f.Events = validEvents
+			f.Sections = []string{"retransmissions"}
+			s, err := f.report()
+
+			Convey("It should render the histogram, protocol, and submitter tables", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "How many copies of each event arrived?")
+				So(s, ShouldContainSubstring, "Which protocols' events were retransmitted most?")
+				So(s, ShouldContainSubstring, "Whose events were retransmitted most?")
+				So(s, ShouldContainSubstring, "130.21.96.80")
+			})
+		})
+	})
+}
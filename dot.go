@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// WriteDOT writes a Graphviz DOT graph to w connecting each submitter to the
+// protocols of its events, weighting each edge by the number of events. Nodes
+// and edges are sorted so the same findings always produce the same graph.
+func (f *findings) WriteDOT(w io.Writer) error {
+	if f.ByProtocol == nil {
+		f.populate()
+	}
+
+	protocols := make([]p.Protocol, 0, len(f.SubmittersByProtocol))
+	for proto := range f.SubmittersByProtocol {
+		protocols = append(protocols, proto)
+	}
+	sort.Slice(protocols, func(i, j int) bool { return dotProtocol(protocols[i]) < dotProtocol(protocols[j]) })
+
+	submitters := make([]netip.Addr, 0, len(f.Submitters))
+	for ip := range f.Submitters {
+		submitters = append(submitters, ip)
+	}
+	sort.Slice(submitters, func(i, j int) bool { return submitters[i].Less(submitters[j]) })
+
+	ew := &errWriter{w: w}
+	ew.printf("digraph submitters {\n\trankdir=LR;\n")
+	for _, proto := range protocols {
+		ew.printf("\t%q [shape=ellipse];\n", dotProtocol(proto))
+	}
+	for _, ip := range submitters {
+		ew.printf("\t%q [shape=box];\n", ip.String())
+	}
+	for _, ip := range submitters {
+		for _, proto := range protocols {
+			if n := f.SubmittersByProtocol[proto][ip]; n > 0 {
+				ew.printf("\t%q -> %q [weight=%d, label=\"%d\"];\n", ip.String(), dotProtocol(proto), n, n)
+			}
+		}
+	}
+	ew.printf("}\n")
+
+	return ew.err
+}
+
+// dotProtocol returns the protocol's node name, distinguishing unknown
+// protocols by their values.
+func dotProtocol(proto p.Protocol) string {
+	if !proto.Known() {
+		return fmt.Sprintf("UNKNOWN 0x%04X", uint16(proto))
+	}
+
+	return proto.String()
+}
+
+// errWriter writes formatted output to w until the first error, which it
+// retains.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, a ...any) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, a...)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_findings_WriteDOT(t *testing.T) {
+	Convey("Given findings for a small dataset", t, func() {
+		a := netip.MustParseAddr("10.0.0.2")
+		b := netip.MustParseAddr("10.0.0.1")
+		f := new(findings)
+		for _, e := range []*p.Event{
+			{Protocol: p.SSH, IP: a},
+			{Protocol: p.SSH, IP: a},
+			{Protocol: p.HTTP, IP: a},
+			{Protocol: p.SSH, IP: b},
+		} {
+			f.Add(e)
+		}
+
+		Convey("When writing the DOT graph", func() {
+			buf := new(bytes.Buffer)
+			So(f.WriteDOT(buf), ShouldBeNil)
+
+			Convey("It should declare sorted nodes and weighted edges", func() {
+				So(buf.String(), ShouldEqual, `digraph submitters {
+	rankdir=LR;
+	"HTTP" [shape=ellipse];
+	"SSH" [shape=ellipse];
+	"10.0.0.1" [shape=box];
+	"10.0.0.2" [shape=box];
+	"10.0.0.1" -> "SSH" [weight=1, label="1"];
+	"10.0.0.2" -> "HTTP" [weight=1, label="1"];
+	"10.0.0.2" -> "SSH" [weight=2, label="2"];
+}
+`)
+			})
+		})
+	})
+}
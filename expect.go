@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// expectation is the checksum expected of an event, and the checksum received,
+// if any.
+type expectation struct {
+	UUID     string
+	Want     uint32
+	Got      uint32
+	Received bool
+}
+
+// readExpectations reads event UUID and checksum pairs, one pair per line,
+// separated by white space. Checksums may be decimal or, given a 0x prefix,
+// hexadecimal. Blank lines and lines beginning with # are ignored.
+func readExpectations(r io.Reader) (map[string]uint32, error) {
+	expected := make(map[string]uint32)
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want UUID and checksum; got %q", line, text)
+		}
+
+		sum, err := strconv.ParseUint(fields[1], 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: parsing checksum: %w", line, err)
+		}
+		expected[strings.ToLower(fields[0])] = uint32(sum)
+	}
+
+	return expected, s.Err()
+}
+
+// readExpectationsFile reads the expectations in the file at path.
+func readExpectationsFile(path string) (map[string]uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return readExpectations(f)
+}
+
+// unmetExpectations compares the events to the Expected checksums, returning
+// the events whose checksums differ from those expected of their UUIDs, and
+// the expected UUIDs never received, ordered by UUID.
+func (f *findings) unmetExpectations() []expectation {
+	received := make(map[string]bool, len(f.Expected))
+	var unmet []expectation
+
+	for _, e := range f.Events {
+		id := e.EventUUID.String()
+		want, ok := f.Expected[id]
+		if !ok {
+			continue
+		}
+		received[id] = true
+
+		if e.CheckSum != want {
+			unmet = append(unmet, expectation{UUID: id, Want: want, Got: e.CheckSum, Received: true})
+		}
+	}
+
+	for id, want := range f.Expected {
+		if !received[id] {
+			unmet = append(unmet, expectation{UUID: id, Want: want})
+		}
+	}
+
+	sort.Slice(unmet, func(i, j int) bool {
+		if unmet[i].UUID != unmet[j].UUID {
+			return unmet[i].UUID < unmet[j].UUID
+		}
+
+		return unmet[i].Got < unmet[j].Got
+	})
+
+	return unmet
+}
+
+// expectations renders the unmet expectations.
+func (f *findings) expectations(rf reportFormat) (string, error) {
+	d := pterm.TableData{{"#", "Event UUID", "Expected", "Received"}}
+
+	for i, x := range f.unmetExpectations() {
+		got := "MISSING"
+		if x.Received {
+			got = fmt.Sprintf("0x%08x", x.Got)
+		}
+		d = append(d, []string{strconv.Itoa(i + 1), x.UUID, fmt.Sprintf("0x%08x", x.Want), got})
+	}
+	if len(d) == 1 {
+		d = append(d, []string{"", "ALL", "EXPECTATIONS", "MET"})
+	}
+
+	return rf.table(d)
+}
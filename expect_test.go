@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pterm/pterm"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_readExpectations(t *testing.T) {
+	Convey("Given expectations with comments, blank lines, and both checksum bases", t, func() {
+		r := strings.NewReader("# uuid checksum\n\nAB-CD 0x0000000a\nef-01 11\n")
+
+		Convey("When reading them", func() {
+			expected, err := readExpectations(r)
+
+			Convey("It should map lowercase UUIDs to checksums", func() {
+				So(err, ShouldBeNil)
+				So(expected, ShouldResemble, map[string]uint32{"ab-cd": 10, "ef-01": 11})
+			})
+		})
+	})
+
+	Convey("Given an expectation lacking a checksum", t, func() {
+		r := strings.NewReader("ab-cd\n")
+
+		Convey("When reading it", func() {
+			_, err := readExpectations(r)
+
+			Convey("It should return an error", func() {
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func Test_findings_expectations(t *testing.T) {
+	Convey("Given an expectation file with one mismatch and one missing UUID", t, func() {
+		const missing = "00000000-0000-1000-8000-000000000000"
+		met, mismatched := validEvents[0], validEvents[1]
+
+		path := filepath.Join(t.TempDir(), "expected.txt")
+		So(os.WriteFile(path, []byte(fmt.Sprintf("%s 0x%08x\n%s 0x%08x\n%s 0x%08x\n",
+			met.EventUUID.String(), met.CheckSum,
+			mismatched.EventUUID.String(), mismatched.CheckSum+1,
+			missing, 1,
+		)), 0o600), ShouldBeNil)
+
+		expected, err := readExpectationsFile(path)
+		So(err, ShouldBeNil)
+
+		f := &findings{Events: validEvents, Expected: expected}
+
+		Convey("When comparing the events to the expectations", func() {
+			unmet := f.unmetExpectations()
+
+			Convey("It should return the mismatch and the missing UUID", func() {
+				So(unmet, ShouldHaveLength, 2)
+				So(unmet, ShouldContain, expectation{
+					UUID:     mismatched.EventUUID.String(),
+					Want:     mismatched.CheckSum + 1,
+					Got:      mismatched.CheckSum,
+					Received: true,
+				})
+				So(unmet, ShouldContain, expectation{UUID: missing, Want: 1})
+			})
+		})
+
+		Convey("When rendering the report", func() {
+			f.populate()
+			report, err := f.report()
+
+			Convey("It should list the unmet expectations", func() {
+				So(err, ShouldBeNil)
+				report = pterm.RemoveColorFromString(report)
+				So(report, ShouldContainSubstring, "Which events differ from expectations?")
+				So(report, ShouldContainSubstring, mismatched.EventUUID.String())
+				So(report, ShouldContainSubstring, "MISSING")
+				So(report, ShouldNotContainSubstring, met.EventUUID.String()+" ")
+			})
+		})
+	})
+}
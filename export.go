@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+const (
+	// archiveBackoff is the initial wait before retrying a write to a full
+	// disk.
+	archiveBackoff = 500 * time.Millisecond
+
+	// defaultArchiveRetries is the default number of times to retry a write
+	// to a full disk.
+	defaultArchiveRetries = 3
+)
+
+// archiveSleep waits out the backoff before retrying a write to a full disk.
+var archiveSleep = time.Sleep
+
+// canonicalEvents returns a copy of events sorted in canonical order, so the
+// same set of events always produces byte-identical output regardless of the
+// order in which they arrived.
+func canonicalEvents(events []*p.Event) []*p.Event {
+	sorted := make([]*p.Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return p.CompareEvents(sorted[i], sorted[j]) < 0
+	})
+
+	return sorted
+}
+
+// diskFullWriter writes to w, retrying the remainder of a write up to retries
+// times should w report the disk is full, doubling the backoff between
+// attempts, in case space frees up. Each write starts from the initial backoff.
+type diskFullWriter struct {
+	w       io.Writer
+	retries int
+	backoff time.Duration
+}
+
+// Write implements the io.Writer interface.
+func (d *diskFullWriter) Write(b []byte) (int, error) {
+	written, backoff := 0, d.backoff
+	for attempt := 0; ; attempt++ {
+		n, err := d.w.Write(b[written:])
+		written += n
+		if err == nil {
+			return written, nil
+		}
+		if !errors.Is(err, syscall.ENOSPC) || attempt >= d.retries {
+			return written, err
+		}
+
+		log.Warnf("writing: %v; retrying in %s", err, backoff)
+		archiveSleep(backoff)
+		backoff *= 2
+	}
+}
+
+// archiveEvents writes the binary equivalent of each event to w in batches of up
+// to outputBufferSize bytes, returning the number of events written in full.
+// Should w report the disk is full, it retries the remainder of the batch up to
+// the given number of times (see diskFullWriter).
+func archiveEvents(w io.Writer, events []*p.Event, retries int, backoff time.Duration) (int, error) {
+	dw := &diskFullWriter{w: w, retries: retries, backoff: backoff}
+	batch := make([]byte, 0, outputBufferSize)
+	written := 0 // events in batches written in full
+	for i, e := range events {
+		b, err := e.MarshalBinary()
+		if err != nil {
+			return written, fmt.Errorf("marshaling event %s: %w", e.EventUUID.String(), err)
+		}
+
+		if len(batch) > 0 && len(batch)+len(b) > outputBufferSize {
+			if _, err = dw.Write(batch); err != nil {
+				return written, fmt.Errorf("writing event %s: %w", events[written].EventUUID.String(), err)
+			}
+			batch, written = batch[:0], i
+		}
+		batch = append(batch, b...)
+	}
+
+	if len(batch) > 0 {
+		if _, err := dw.Write(batch); err != nil {
+			return written, fmt.Errorf("writing event %s: %w", events[written].EventUUID.String(), err)
+		}
+	}
+
+	return len(events), nil
+}
+
+// archiveFile is a file to which writeEventsFile archives events.
+type archiveFile interface {
+	io.Writer
+	Sync() error
+	Close() error
+}
+
+// createArchive creates (or truncates) the archive file at path.
+var createArchive = func(path string) (archiveFile, error) { return os.Create(path) }
+
+// writeEventsFile creates (or truncates) the file at path and archives the
+// binary equivalent of each event to it, retrying a full disk up to retries
+// times (see archiveEvents). Should archiving fail, it logs how many events it
+// archived and syncs the file, so those events remain recoverable.
+func writeEventsFile(path string, events []*p.Event, retries int) error {
+	f, err := createArchive(path)
+	if err != nil {
+		return err
+	}
+
+	n, err := archiveEvents(f, events, retries, archiveBackoff)
+	if err != nil {
+		log.Warnf("archived %d of %d events to %q", n, len(events), path)
+		_ = f.Sync()
+		_ = f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// writeFile creates (or truncates) the file at path and calls write with it,
+// buffered (see newFlushWriter).
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	fw := newFlushWriter(f, outputBufferSize, flushInterval)
+	err = write(fw)
+	if flushErr := fw.Close(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		// Flush whatever was written so it's recoverable.
+		_ = f.Sync()
+		_ = f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_canonicalEvents(t *testing.T) {
+	Convey("Given the same events in two arrival orders", t, func() {
+		reversed := make([]*p.Event, 0, len(validEvents))
+		for i := len(validEvents) - 1; i >= 0; i-- {
+			reversed = append(reversed, validEvents[i])
+		}
+
+		Convey("When writing each in canonical order", func() {
+			var a, b bytes.Buffer
+			_, err := archiveEvents(&a, canonicalEvents(validEvents), 0, 0)
+			So(err, ShouldBeNil)
+			_, err = archiveEvents(&b, canonicalEvents(reversed), 0, 0)
+			So(err, ShouldBeNil)
+
+			Convey("It should write identical bytes", func() {
+				So(a.Len(), ShouldBeGreaterThan, 0)
+				So(a.Bytes(), ShouldResemble, b.Bytes())
+			})
+
+			Convey("It should leave the arrival order untouched", func() {
+				So(reversed[0], ShouldEqual, validEvents[len(validEvents)-1])
+			})
+		})
+	})
+}
+
+func Test_writeEventsFile(t *testing.T) {
+	Convey("Given a run configured to write events to a file", t, func() {
+		addr, err := udpServer(validEvents)
+		So(err, ShouldBeNil)
+
+		path := filepath.Join(t.TempDir(), "events.bin")
+		cfg := config{
+			Address:   addr.String(),
+			Datagrams: len(validEvents),
+			DetailIP:  netip.MustParseAddr("106.54.93.84"),
+			EventsOut: path,
+			Size:      minDatagramBytes,
+		}
+
+		Convey("When calling the run function", func() {
+			So(run(cfg), ShouldBeNil)
+
+			Convey("It should write every valid event to the file", func() {
+				f, err := os.Open(path)
+				So(err, ShouldBeNil)
+				defer func() { _ = f.Close() }()
+
+				var events []*p.Event
+				er := p.NewEventReader(f)
+				for {
+					e, err := er.Read()
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					So(err, ShouldBeNil)
+					So(e.Valid(), ShouldBeTrue)
+
+					events = append(events, e)
+				}
+
+				So(events, ShouldHaveLength, len(validEvents))
+			})
+		})
+	})
+}
+
+// memArchive is an archiveFile writing to w, recording whether it was synced
+// and closed.
+type memArchive struct {
+	io.Writer
+	synced, closed bool
+}
+
+func (m *memArchive) Sync() error {
+	m.synced = true
+
+	return nil
+}
+
+func (m *memArchive) Close() error {
+	m.closed = true
+
+	return nil
+}
+
+func Test_writeEventsFile_fullDisk(t *testing.T) {
+	// Write each event through, so the disk fills between events.
+	size := outputBufferSize
+	outputBufferSize = 0
+	defer func() { outputBufferSize = size }()
+
+	Convey("Given an archive file whose disk fills after two events", t, func() {
+		var want bytes.Buffer
+		_, err := archiveEvents(&want, validEvents, 0, 0)
+		So(err, ShouldBeNil)
+
+		w := &fullDiskWriter{after: 2, failures: 2, err: syscall.ENOSPC}
+		archive := &memArchive{Writer: w}
+		create := createArchive
+		createArchive = func(string) (archiveFile, error) { return archive, nil }
+		defer func() { createArchive = create }()
+
+		sleep := archiveSleep
+		archiveSleep = func(time.Duration) {}
+		defer func() { archiveSleep = sleep }()
+
+		Convey("When writing the events file with enough retries", func() {
+			err := writeEventsFile("events.bin", validEvents, 3)
+
+			Convey("It should resume and archive every event intact", func() {
+				So(err, ShouldBeNil)
+				So(w.Bytes(), ShouldResemble, want.Bytes())
+				So(archive.closed, ShouldBeTrue)
+			})
+		})
+
+		Convey("When writing the events file with too few retries", func() {
+			err := writeEventsFile("events.bin", validEvents, 1)
+
+			Convey("It should sync and close the events archived before the disk filled", func() {
+				So(errors.Is(err, syscall.ENOSPC), ShouldBeTrue)
+				So(archive.synced, ShouldBeTrue)
+				So(archive.closed, ShouldBeTrue)
+
+				er := p.NewEventReader(bytes.NewReader(w.Bytes()))
+				for i := 0; i < 2; i++ {
+					e, err := er.Read()
+					So(err, ShouldBeNil)
+					So(e.EventUUID, ShouldResemble, validEvents[i].EventUUID)
+				}
+			})
+		})
+	})
+}
+
+// fullDiskWriter accepts the first after writes in full, then fails the next
+// failures writes with err, each having written half of its bytes.
+type fullDiskWriter struct {
+	bytes.Buffer
+	after    int
+	failures int
+	err      error
+	writes   int
+}
+
+func (w *fullDiskWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if w.writes <= w.after || w.failures == 0 {
+		return w.Buffer.Write(b)
+	}
+	w.failures--
+
+	n, _ := w.Buffer.Write(b[:len(b)/2])
+
+	return n, w.err
+}
+
+// flakyDiskWriter fails every other write as a full disk, writing nothing.
+type flakyDiskWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *flakyDiskWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if w.writes%2 == 1 {
+		return 0, syscall.ENOSPC
+	}
+
+	return w.Buffer.Write(b)
+}
+
+func Test_archiveEvents(t *testing.T) {
+	// Write each event through, so the disk fills between events.
+	size := outputBufferSize
+	outputBufferSize = 0
+	defer func() { outputBufferSize = size }()
+
+	Convey("Given a writer whose disk fills after two events", t, func() {
+		var want bytes.Buffer
+		_, err := archiveEvents(&want, validEvents, 0, 0)
+		So(err, ShouldBeNil)
+
+		w := &fullDiskWriter{after: 2, failures: 2, err: syscall.ENOSPC}
+
+		Convey("When archiving events with enough retries", func() {
+			n, err := archiveEvents(w, validEvents, 3, time.Millisecond)
+
+			Convey("It should resume and archive every event intact", func() {
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, len(validEvents))
+				So(w.Bytes(), ShouldResemble, want.Bytes())
+			})
+		})
+
+		Convey("When archiving events with too few retries", func() {
+			n, err := archiveEvents(w, validEvents, 1, time.Millisecond)
+
+			Convey("It should report the events archived before the disk filled", func() {
+				So(errors.Is(err, syscall.ENOSPC), ShouldBeTrue)
+				So(n, ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given a writer whose disk fills once during each event", t, func() {
+		w := new(flakyDiskWriter)
+
+		var waits []time.Duration
+		sleep := archiveSleep
+		archiveSleep = func(d time.Duration) { waits = append(waits, d) }
+		defer func() { archiveSleep = sleep }()
+
+		Convey("When archiving events", func() {
+			n, err := archiveEvents(w, validEvents, 1, time.Millisecond)
+
+			Convey("It should wait the initial backoff after each successful event", func() {
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, len(validEvents))
+				So(waits, ShouldHaveLength, len(validEvents))
+				for _, wait := range waits {
+					So(wait, ShouldEqual, time.Millisecond)
+				}
+			})
+		})
+	})
+
+	Convey("Given a writer that fails permanently after two events", t, func() {
+		w := &fullDiskWriter{after: 2, failures: 1, err: errors.New("broken pipe")}
+
+		Convey("When archiving events", func() {
+			n, err := archiveEvents(w, validEvents, 3, time.Millisecond)
+
+			Convey("It should fail without retrying", func() {
+				So(err, ShouldBeError)
+				So(n, ShouldEqual, 2)
+				So(w.writes, ShouldEqual, 3)
+			})
+		})
+	})
+}
+
+func Test_archiveEvents_buffered(t *testing.T) {
+	Convey("Given a writer whose disk fills partway through a batch of events", t, func() {
+		var want bytes.Buffer
+		_, err := archiveEvents(&want, validEvents, 0, 0)
+		So(err, ShouldBeNil)
+
+		w := &fullDiskWriter{failures: 1, err: syscall.ENOSPC}
+
+		sleep := archiveSleep
+		archiveSleep = func(time.Duration) {}
+		defer func() { archiveSleep = sleep }()
+
+		Convey("When archiving the events with retries", func() {
+			n, err := archiveEvents(w, validEvents, defaultArchiveRetries, time.Millisecond)
+
+			Convey("It should replay the rest of the batch in one write", func() {
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, len(validEvents))
+				So(w.writes, ShouldEqual, 2)
+				So(w.Bytes(), ShouldResemble, want.Bytes())
+			})
+		})
+
+		Convey("When archiving the events without retries", func() {
+			n, err := archiveEvents(w, validEvents, 0, time.Millisecond)
+
+			Convey("It should count none of the batch's events as archived", func() {
+				So(errors.Is(err, syscall.ENOSPC), ShouldBeTrue)
+				So(n, ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// extractValues returns the unique values of the payload key in the events,
+// ordered by descending frequency, then ascending value. If protocols isn't
+// empty, only events of those protocols contribute values.
+func extractValues(events []*p.Event, key string, protocols ...p.Protocol) []string {
+	m := make(itemOccurrenceMap)
+	for _, e := range events {
+		if len(protocols) > 0 && !containsProtocol(protocols, e.Protocol) {
+			continue
+		}
+
+		value, ok := e.Payload[key]
+		if !ok {
+			continue
+		}
+
+		item, ok := m[value]
+		if !ok {
+			item = &itemOccurrence{Item: value}
+			m[value] = item
+		}
+		item.Occurrence++
+	}
+
+	items := make(itemOccurrences, 0, len(m))
+	for _, item := range m {
+		items = append(items, item)
+	}
+	sort.Sort(items)
+
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		values = append(values, item.Item)
+	}
+
+	return values
+}
+
+// writeValues writes each value to w on its own line.
+func writeValues(w io.Writer, values []string) error {
+	for _, v := range values {
+		if _, err := fmt.Fprintln(w, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// containsProtocol returns true if proto is among the protocols.
+func containsProtocol(protocols []p.Protocol, proto p.Protocol) bool {
+	for _, x := range protocols {
+		if x == proto {
+			return true
+		}
+	}
+
+	return false
+}
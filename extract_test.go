@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_extractValues(t *testing.T) {
+	Convey("Given events with duplicate passwords across protocols", t, func() {
+		event := func(proto p.Protocol, password string) *p.Event {
+			return &p.Event{Protocol: proto, Payload: map[string]string{"password": password, "username": "root"}}
+		}
+		events := []*p.Event{
+			event(p.SSH, "hunter2"),
+			event(p.SSH, "letmein"),
+			event(p.TELNET, "admin"),
+			event(p.SSH, "hunter2"),
+			event(p.TELNET, "letmein"),
+			event(p.SSH, "hunter2"),
+			{Protocol: p.HTTP, Payload: map[string]string{"user-agent": "curl/7.68.0"}},
+		}
+
+		Convey("When extracting the passwords", func() {
+			values := extractValues(events, "password")
+
+			Convey("It should deduplicate them, most frequent first, then by value", func() {
+				So(values, ShouldResemble, []string{"hunter2", "letmein", "admin"})
+			})
+		})
+
+		Convey("When extracting the TELNET passwords", func() {
+			values := extractValues(events, "password", p.TELNET)
+
+			Convey("It should only extract values from TELNET events", func() {
+				So(values, ShouldResemble, []string{"admin", "letmein"})
+			})
+		})
+
+		Convey("When writing the extracted values", func() {
+			var b strings.Builder
+			So(writeValues(&b, extractValues(events, "password")), ShouldBeNil)
+
+			Convey("It should write one value per line", func() {
+				So(b.String(), ShouldEqual, "hunter2\nletmein\nadmin\n")
+			})
+		})
+	})
+}
+
+func Test_runExtract(t *testing.T) {
+	Convey("Given a run configured to extract SSH passwords", t, func() {
+		addr, err := udpServer(validEvents)
+		So(err, ShouldBeNil)
+
+		Convey("When calling the run function", func() {
+			stdout, err := captureStdout(func() error {
+				return run(config{
+					Address:         addr.String(),
+					Datagrams:       len(validEvents),
+					Extract:         "password",
+					ExtractProtocol: "ssh",
+					Size:            minDatagramBytes,
+				})
+			})
+
+			Convey("It should print only the passwords", func() {
+				So(err, ShouldBeNil)
+				So(stdout, ShouldEqual, "Jackallava\nShriekerlavender\n")
+			})
+		})
+	})
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"bytes"
+	"container/heap"
+	"errors"
 	"fmt"
 	"net/netip"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/pterm/pterm"
 	log "github.com/sirupsen/logrus"
@@ -14,320 +18,1418 @@ import (
 	p "github.com/awoodbeck/event-emitter-client/protocol"
 )
 
+var (
+	// ErrNoEvents indicates there are no events to report.
+	ErrNoEvents = errors.New("no events")
+
+	// ErrMissingProtocol indicates the report requires events, or findings, of
+	// a protocol the events lack. Use errors.As with a *missingProtocolError
+	// to learn which protocol.
+	ErrMissingProtocol = errors.New("missing protocol")
+)
+
+// missingProtocolError reports the protocol, and the finding thereof, missing
+// from the events.
+type missingProtocolError struct {
+	Protocol p.Protocol
+	Finding  string // e.g., events, passwords
+}
+
+func (e *missingProtocolError) Error() string {
+	return fmt.Sprintf("no %s %s", e.Protocol.String(), e.Finding)
+}
+
+// Unwrap allows errors.Is to match ErrMissingProtocol.
+func (e *missingProtocolError) Unwrap() error { return ErrMissingProtocol }
+
 // findings is an accounting of the collected events.
 type findings struct {
 	Events []*p.Event
 
+	// Classify breaks down each payload key's values by classification, such
+	// as numeric-only passwords or bot user-agents, in the report.
+	Classify bool
+
+	// Clock tells the time events arrive, for their ReceivedAt times and the
+	// Window. Nil uses the system clock.
+	Clock Clock
+
+	// AcrossProtocols are the payload keys whose top values are ranked across
+	// all protocols in the report, such as "password".
+	AcrossProtocols []string
+
+	// Cardinality counts the distinct values and occurrences of each
+	// aggregated payload key by protocol in the report.
+	Cardinality bool
+
+	// CheckCharset reports the events whose payload keys or values contain
+	// non-printable runes or invalid UTF-8.
+	CheckCharset bool
+
+	// ClusterPrefix is the prefix length used to aggregate IPv4 submitters
+	// into subnets, and ClusterPrefix6 IPv6 submitters. Zero leaves out the
+	// family's submitters; zero for both omits the subnet section from the
+	// report.
+	ClusterPrefix  int
+	ClusterPrefix6 int
+
+	// CRCSample is the number of Invalid events with checksum mismatches
+	// whose stored, IEEE, and Castagnoli checksums the report compares. Zero
+	// omits the comparison.
+	CRCSample int
+
+	// Detail is the submitter whose events are detailed in the report, if
+	// valid. Only its entry in Submitters retains its events, unless the
+	// findings have a Window, whose eviction needs every submitter's events.
+	Detail netip.Addr
+
+	// Expected maps event UUIDs to the checksums expected of them. The report
+	// lists events that differ and expected events never received. Nil
+	// omits the comparison.
+	Expected map[string]uint32
+
+	// ExplainInvalid summarizes the Invalid events in the report by failure
+	// mode, submitter, and protocol.
+	ExplainInvalid bool
+
+	// Geo resolves submitters' countries for the report's country section.
+	// Nil omits the section.
+	Geo geoResolver
+
+	// LabelColor is the ANSI SGR foreground color code used for section
+	// labels. Zero renders labels in the terminal's default color.
+	LabelColor int
+
+	// Location is the time zone used to render times. Nil means local time.
+	Location *time.Location
+
+	// LowMemory discards events after Add accounts for them, leaving Events
+	// empty. Events are still retained for eviction if the findings have a
+	// Window.
+	LowMemory bool
+
+	// MaskCredentials renders passwords and usernames as asterisks of the same
+	// length, for sharing the report on screen.
+	MaskCredentials bool
+
+	// OnEvent, if not nil, is called with each event Add receives before the
+	// event is aggregated, allowing callers to tag, count, or log events.
+	OnEvent func(*p.Event)
+
+	// PayloadSizes summarizes the payload sizes of each protocol in the
+	// report.
+	PayloadSizes bool
+
+	// Reputation lists known-malicious submitters, which the submitter
+	// sections mark in a Reputation column. Nil omits the column.
+	Reputation *reputation
+
+	// Sections names the report sections to render, in order. Nil renders
+	// every section in its default order.
+	Sections []string
+
+	// SequentialUUIDs reports runs of near-sequential event UUIDs among the
+	// Events.
+	SequentialUUIDs bool
+
+	// SkewThreshold is the maximum difference allowed between a version 1
+	// event UUID's time and the event's TimeStamp. Zero disables the check.
+	SkewThreshold time.Duration
+
+	// TimeFormat renders times as "epoch" seconds, a "date", a "datetime", or
+	// in a Go time layout. Empty means "datetime".
+	TimeFormat string
+
+	// Topology cross-tabulates the emitters' NodeIDs against the protocols of
+	// their events in the report.
+	Topology bool
+
+	// Width is the number of terminal columns available to the report. The
+	// user-agent column truncates to fit. Zero doesn't limit the width.
+	Width int
+
+	// Window limits the findings to events added by Add within the duration.
+	// Zero includes all events.
+	Window time.Duration
+
 	ByProtocol map[p.Protocol]*itemOccurrence
+	Copies     []*copies      // with -dedup-report, the copies received of each distinct event
+	Discarded  map[string]int // events discarded during collection, by reason
+	Duplicates map[string]int // duplicate events collapsed by each dedup mode
 	Emails     map[p.Protocol]itemOccurrenceMap
+	Empty      int         // events with empty payloads
+	Invalid    []rejection // with -invalid-json, -explain-invalid, or -crc-sample, the malformed and invalid events
+	Latency    histogram   // in nanoseconds, from each event's TimeStamp to its ReceivedAt
 	Passwords  map[p.Protocol]itemOccurrenceMap
+
+	// ProtocolsByNode counts each emitter node's events by protocol.
+	ProtocolsByNode map[uint16]map[p.Protocol]int
+
+	Sizes      map[p.Protocol]*histogram // payload sizes
+	Skewed     []*p.Event
 	Submitters map[netip.Addr]*itemOccurrence
-	UserAgents map[p.Protocol]itemOccurrenceMap
-	Usernames  map[p.Protocol]itemOccurrenceMap
+
+	// SubmittersByProtocol counts each submitter's events by protocol.
+	SubmittersByProtocol map[p.Protocol]map[netip.Addr]int
+
+	Unknown     map[p.Protocol]string // sample payload of each unknown protocol
+	Unprintable []*p.Event            // with CheckCharset, events failing PayloadCharsetValid
+	UserAgents  map[p.Protocol]itemOccurrenceMap
+	Usernames   map[p.Protocol]itemOccurrenceMap
+	Version     uint8 // the protocol version the server negotiated
+
+	arrivals []time.Time // when each event in a Window arrived
+	missing  []error     // the missing protocols soft rendered placeholders for
+}
+
+// Add accounts for the event in the findings. If the findings have a Window,
+// Add first evicts events that arrived longer ago than the Window. Events the
+// findings were populated with before the first Add count as arriving then.
+func (f *findings) Add(event *p.Event) {
+	if f.OnEvent != nil {
+		f.OnEvent(event)
+	}
+
+	now := f.now()
+	if f.ByProtocol == nil {
+		f.populate()
+		f.arrivals = f.arrivals[:0]
+		if f.Window > 0 {
+			for range f.Events {
+				f.arrivals = append(f.arrivals, now)
+			}
+		}
+	}
+
+	if f.Window > 0 {
+		f.evict(now)
+		f.arrivals = append(f.arrivals, now)
+	}
+
+	if !f.LowMemory || f.Window > 0 {
+		f.Events = append(f.Events, event)
+	}
+	f.account(event)
+}
+
+// account adds the event to the aggregates.
+func (f *findings) account(event *p.Event) {
+	// ByProtocol
+	item := f.ByProtocol[event.Protocol]
+	if item == nil {
+		item = &itemOccurrence{Events: make([]*p.Event, 0), Item: event.Protocol.String()}
+		f.ByProtocol[event.Protocol] = item
+	}
+	item.Occurrence++
+
+	// Payload sizes
+	sizes := f.Sizes[event.Protocol]
+	if sizes == nil {
+		sizes = new(histogram)
+		f.Sizes[event.Protocol] = sizes
+	}
+	sizes.add(int64(event.Size))
+
+	// Receive latency
+	if !event.ReceivedAt.IsZero() {
+		f.Latency.add(int64(latency(event)))
+	}
+
+	// Unknown protocols
+	if _, ok := f.Unknown[event.Protocol]; !ok && !event.Protocol.Known() {
+		f.Unknown[event.Protocol] = string(event.PayloadBytes)
+	}
+
+	// Submitter
+	item = f.Submitters[event.IP]
+	if item == nil {
+		item = &itemOccurrence{Events: make([]*p.Event, 0), Item: event.IP.String()}
+		f.Submitters[event.IP] = item
+	}
+	if f.Window > 0 || event.IP == f.Detail {
+		item.Events = append(item.Events, event)
+	}
+	item.Occurrence++
+	item.see(event.TimeStamp)
+
+	byIP := f.SubmittersByProtocol[event.Protocol]
+	if byIP == nil {
+		byIP = make(map[netip.Addr]int)
+		f.SubmittersByProtocol[event.Protocol] = byIP
+	}
+	byIP[event.IP]++
+
+	// Nodes
+	byProto := f.ProtocolsByNode[event.NodeID]
+	if byProto == nil {
+		byProto = make(map[p.Protocol]int)
+		f.ProtocolsByNode[event.NodeID] = byProto
+	}
+	byProto[event.Protocol]++
+
+	// Empty payloads
+	if len(event.Payload) == 0 {
+		f.Empty++
+	}
+
+	// Clock skew
+	if ut, ok := event.EventUUID.Time(); ok && f.SkewThreshold > 0 {
+		ts := time.Unix(int64(event.TimeStamp), 0)
+		if ut.Sub(ts).Abs() > f.SkewThreshold {
+			f.Skewed = append(f.Skewed, event)
+		}
+	}
+
+	// Payload charset
+	if f.CheckCharset && !event.PayloadCharsetValid() {
+		f.Unprintable = append(f.Unprintable, event)
+	}
+
+	for k, v := range event.Payload {
+		m := f.payloadMap(k, event.Protocol, true)
+		if m == nil {
+			log.Warnf("unknown event (%s) payload key %q", event.EventUUID.String(), k)
+			continue
+		}
+
+		item = m[v]
+		if item == nil {
+			item = &itemOccurrence{Item: v}
+			m[v] = item
+		}
+		item.Occurrence++
+	}
+}
+
+// discount removes the event from the aggregates, reversing account.
+func (f *findings) discount(event *p.Event) {
+	// ByProtocol
+	if item := f.ByProtocol[event.Protocol]; item != nil {
+		if item.Occurrence--; item.Occurrence <= 0 {
+			delete(f.ByProtocol, event.Protocol)
+			delete(f.Unknown, event.Protocol)
+		}
+	}
+
+	if byIP := f.SubmittersByProtocol[event.Protocol]; byIP != nil {
+		if byIP[event.IP]--; byIP[event.IP] <= 0 {
+			delete(byIP, event.IP)
+		}
+		if len(byIP) == 0 {
+			delete(f.SubmittersByProtocol, event.Protocol)
+		}
+	}
+
+	if byProto := f.ProtocolsByNode[event.NodeID]; byProto != nil {
+		if byProto[event.Protocol]--; byProto[event.Protocol] <= 0 {
+			delete(byProto, event.Protocol)
+		}
+		if len(byProto) == 0 {
+			delete(f.ProtocolsByNode, event.NodeID)
+		}
+	}
+
+	// Receive latency
+	if !event.ReceivedAt.IsZero() {
+		f.Latency.remove(int64(latency(event)))
+	}
+
+	// Payload sizes
+	if sizes := f.Sizes[event.Protocol]; sizes != nil {
+		if sizes.remove(int64(event.Size)); sizes.Count == 0 {
+			delete(f.Sizes, event.Protocol)
+		}
+	}
+
+	// Submitter
+	if item := f.Submitters[event.IP]; item != nil {
+		item.Events = removeEvent(item.Events, event)
+		if item.Occurrence--; item.Occurrence <= 0 {
+			delete(f.Submitters, event.IP)
+		} else {
+			item.First, item.Last, item.seen = 0, 0, false
+			for _, e := range item.Events {
+				item.see(e.TimeStamp)
+			}
+		}
+	}
+
+	// Empty payloads
+	if len(event.Payload) == 0 {
+		f.Empty--
+	}
+
+	// Clock skew
+	f.Skewed = removeEvent(f.Skewed, event)
+
+	// Payload charset
+	f.Unprintable = removeEvent(f.Unprintable, event)
+
+	for k, v := range event.Payload {
+		m := f.payloadMap(k, event.Protocol, false)
+		if m == nil {
+			continue
+		}
+
+		if item := m[v]; item != nil {
+			if item.Occurrence--; item.Occurrence <= 0 {
+				delete(m, v)
+			}
+		}
+	}
+}
+
+// evict discounts the events that arrived longer than the Window before now.
+// Events arrive in chronological order, so the oldest are first.
+func (f *findings) evict(now time.Time) {
+	i := 0
+	for ; i < len(f.arrivals) && now.Sub(f.arrivals[i]) > f.Window; i++ {
+		f.discount(f.Events[i])
+	}
+
+	f.arrivals = f.arrivals[i:]
+	f.Events = f.Events[i:]
+}
+
+// now returns the current time according to the findings' Clock.
+func (f *findings) now() time.Time {
+	if f.Clock != nil {
+		return f.Clock.Now()
+	}
+
+	return systemClock{}.Now()
+}
+
+// payloadMap returns the item occurrences for the given payload key and
+// protocol. It returns nil for unknown keys, or for known keys that have no
+// occurrences for the protocol unless create is true.
+func (f *findings) payloadMap(key string, proto p.Protocol, create bool) itemOccurrenceMap {
+	maps := f.payloadMaps(key)
+	if maps == nil {
+		return nil
+	}
+
+	m := maps[proto]
+	if m == nil && create {
+		m = make(itemOccurrenceMap)
+		maps[proto] = m
+	}
+
+	return m
+}
+
+// aggregatedKeys are the payload keys whose values the findings aggregate.
+var aggregatedKeys = []string{"email", "password", "user-agent", "username"}
+
+// aggregated reports whether the findings aggregate the payload key's values.
+func aggregated(key string) bool {
+	for _, k := range aggregatedKeys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// payloadMaps returns the item occurrences of each protocol for the given
+// payload key, or nil if the findings don't aggregate the key.
+func (f *findings) payloadMaps(key string) map[p.Protocol]itemOccurrenceMap {
+	switch key {
+	case "email":
+		return f.Emails
+	case "password":
+		return f.Passwords
+	case "user-agent":
+		return f.UserAgents
+	case "username":
+		return f.Usernames
+	}
+
+	return nil
 }
 
 func (f *findings) populate() {
+	f.reset()
+
+	for _, event := range f.Events {
+		f.account(event)
+	}
+}
+
+// total returns the number of events accounted for in the findings.
+func (f *findings) total() int {
+	n := 0
+	for _, item := range f.ByProtocol {
+		n += item.Occurrence
+	}
+
+	return n
+}
+
+// reset clears the aggregates.
+func (f *findings) reset() {
 	f.ByProtocol = make(map[p.Protocol]*itemOccurrence)
 	f.Emails = make(map[p.Protocol]itemOccurrenceMap)
+	f.Empty = 0
+	f.Latency = histogram{}
 	f.Passwords = make(map[p.Protocol]itemOccurrenceMap)
+	f.ProtocolsByNode = make(map[uint16]map[p.Protocol]int)
+	f.Sizes = make(map[p.Protocol]*histogram)
+	f.Skewed = nil
 	f.Submitters = make(map[netip.Addr]*itemOccurrence)
+	f.SubmittersByProtocol = make(map[p.Protocol]map[netip.Addr]int)
+	f.Unknown = make(map[p.Protocol]string)
+	f.Unprintable = nil
 	f.UserAgents = make(map[p.Protocol]itemOccurrenceMap)
 	f.Usernames = make(map[p.Protocol]itemOccurrenceMap)
+}
 
-	for _, event := range f.Events {
-		// ByProtocol
-		item := f.ByProtocol[event.Protocol]
-		if item == nil {
-			item = &itemOccurrence{Events: make([]*p.Event, 0)}
-		}
-		item.Item = event.Protocol.String()
-		item.Occurrence++
-		f.ByProtocol[event.Protocol] = item
+// report renders the report for the terminal, fitting its columns to the
+// findings' Width (see render).
+func (f *findings) report() (string, error) {
+	return f.render(reportFormat{width: f.Width})
+}
 
-		// Submitter
-		item = f.Submitters[event.IP]
-		if item == nil {
-			item = &itemOccurrence{Events: make([]*p.Event, 0)}
-		}
-		item.Events = append(item.Events, event)
-		item.Item = event.IP.String()
-		item.Occurrence++
-		f.Submitters[event.IP] = item
+// render renders the report's sections in the given format, the Sections if
+// any are selected, or all of them in their default order. If a section's
+// protocol is missing from the events, render renders a placeholder in its
+// stead and returns the rendered report along with an error matching
+// ErrMissingProtocol.
+func (f *findings) render(rf reportFormat) (string, error) {
+	f.missing = nil
+	if f.ByProtocol == nil {
+		// The findings weren't populated incrementally by Add.
+		f.populate()
+	}
+	if f.total() == 0 {
+		return "", ErrNoEvents
+	}
 
-		for k, v := range event.Payload {
-			var m itemOccurrenceMap
+	sections, err := selectSections(f.Sections)
+	if err != nil {
+		return "", err
+	}
 
-			switch k {
-			case "email":
-				m = f.Emails[event.Protocol]
-				if m == nil {
-					m = make(itemOccurrenceMap)
-					f.Emails[event.Protocol] = m
-				}
-			case "password":
-				m = f.Passwords[event.Protocol]
-				if m == nil {
-					m = make(itemOccurrenceMap)
-					f.Passwords[event.Protocol] = m
-				}
-			case "user-agent":
-				m = f.UserAgents[event.Protocol]
-				if m == nil {
-					m = make(itemOccurrenceMap)
-					f.UserAgents[event.Protocol] = m
-				}
-			case "username":
-				m = f.Usernames[event.Protocol]
-				if m == nil {
-					m = make(itemOccurrenceMap)
-					f.Usernames[event.Protocol] = m
+	var buf bytes.Buffer
+	for _, section := range sections {
+		if section.enabled != nil && !section.enabled(f) {
+			continue
+		}
+
+		parts, err := section.render(f, rf)
+		if err != nil {
+			return "", err
+		}
+		for _, part := range parts {
+			if rf.markdown {
+				if buf.Len() > 0 {
+					buf.WriteString("\n\n")
 				}
-			default:
-				log.Warnf("unknown event (%s) payload key %q", event.EventUUID.String(), k)
+				buf.WriteString(fmt.Sprintf("### %s\n\n", markdownEscaper.Replace(part.label)))
+				buf.WriteString(part.body)
+
 				continue
 			}
 
-			item = m[v]
-			if item == nil {
-				item = new(itemOccurrence)
+			if buf.Len() > 0 {
+				buf.WriteString("\n\n\n")
 			}
-			item.Item = v
-			item.Occurrence++
-			m[v] = item
+			buf.WriteString(fmt.Sprintf("\u001B[%dm%s\u001B[0m\n\n", f.LabelColor, part.label))
+			buf.WriteString(part.body)
+		}
+	}
+
+	return buf.String(), errors.Join(f.missing...)
+}
+
+// soft returns a placeholder table in the given format in lieu of a section
+// whose protocol, or finding thereof, is missing from the events, so the rest
+// of the report renders, and notes the missing protocol for render to return.
+// Other errors pass through.
+func (f *findings) soft(rf reportFormat, s string, err error) (string, error) {
+	var mpe *missingProtocolError
+	if !errors.As(err, &mpe) {
+		return s, err
+	}
+	log.Debugf("rendering placeholder section: %v", err)
+	f.missing = append(f.missing, err)
+
+	d := pterm.TableData{{"NO", mpe.Protocol.String(), strings.ToUpper(mpe.Finding), "FOUND"}}
+	if rf.markdown {
+		return markdownTable(d), nil
+	}
+
+	return pterm.DefaultTable.WithData(d).Srender()
+}
+
+// latencyStats renders statistics of the time between each event's TimeStamp
+// and its receipt. The P95 is as precise as the histogram's buckets.
+func (f *findings) latencyStats(rf reportFormat) (string, error) {
+	h := &f.Latency
+
+	d := pterm.TableData{
+		{"Events", "Min", "Mean", "Max", "P95"},
+		{
+			strconv.Itoa(h.Count),
+			time.Duration(h.Min).String(),
+			time.Duration(h.Mean()).Round(time.Millisecond).String(),
+			time.Duration(h.Max).String(),
+			time.Duration(h.Quantile(0.95)).String(),
+		},
+	}
+
+	return rf.table(d)
+}
+
+// sizeStatsByProtocol renders the payload size statistics of each protocol.
+// The P95 is as precise as the histogram's buckets.
+func (f *findings) sizeStatsByProtocol(rf reportFormat) (string, error) {
+	protocols := make([]p.Protocol, 0, len(f.Sizes))
+	for proto := range f.Sizes {
+		protocols = append(protocols, proto)
+	}
+	sort.Slice(protocols, func(i, j int) bool {
+		if ni, nj := protocols[i].String(), protocols[j].String(); ni != nj {
+			return ni < nj
+		}
+
+		return protocols[i] < protocols[j]
+	})
+
+	d := pterm.TableData{{"Protocol", "Events", "Min", "Mean", "Max", "P95"}}
+	for _, proto := range protocols {
+		h := f.Sizes[proto]
+		d = append(d,
+			[]string{
+				proto.String(),
+				strconv.Itoa(h.Count),
+				strconv.FormatInt(h.Min, 10),
+				strconv.FormatFloat(h.Mean(), 'f', 1, 64),
+				strconv.FormatInt(h.Max, 10),
+				strconv.FormatInt(h.Quantile(0.95), 10),
+			},
+		)
+	}
+	if len(protocols) == 0 {
+		d = append(d, []string{"", "NO", "EVENTS", "FOUND", "", ""})
+	}
+
+	return rf.table(d)
+}
+
+func (f *findings) skewedEvents(rf reportFormat) (string, error) {
+	d := pterm.TableData{{"#", "Event UUID", "Time Stamp", "UUID Time", "Skew"}}
+
+	for i, e := range f.Skewed {
+		ts := f.time(e.TimeStamp)
+		ut, _ := e.EventUUID.Time()
+		ut = ut.In(ts.Location())
+		d = append(d,
+			[]string{
+				strconv.Itoa(i + 1),
+				e.EventUUID.String(),
+				f.formatInstant(ts),
+				f.formatInstant(ut),
+				ut.Sub(ts).String(),
+			},
+		)
+	}
+	if len(f.Skewed) == 0 {
+		d = append(d, []string{"", "NO", "SKEWED", "EVENTS", "FOUND"})
+	}
+
+	return rf.table(d)
+}
+
+func (f *findings) unprintablePayloads(rf reportFormat) (string, error) {
+	d := pterm.TableData{{"#", "Payload", "Event UUID", "Protocol", "Submitter"}}
+
+	for i, e := range f.Unprintable {
+		d = append(d,
+			[]string{
+				strconv.Itoa(i + 1),
+				strconv.Quote(e.FormatPayload()),
+				e.EventUUID.String(),
+				e.Protocol.String(),
+				e.IP.String(),
+			},
+		)
+	}
+	if len(f.Unprintable) == 0 {
+		d = append(d, []string{"", "NO", "UNPRINTABLE", "PAYLOADS", "FOUND"})
+	} else {
+		d = append(d,
+			[]string{
+				"",
+				pterm.DefaultTable.HeaderStyle.Sprint("TOTAL UNPRINTABLE EVENTS"),
+				pterm.DefaultTable.HeaderStyle.Sprint(thousands(len(f.Unprintable))),
+				"",
+				"",
+			},
+		)
+	}
+	if rf.width > 0 {
+		fitColumn(d, 1, rf.width)
+	}
+
+	return rf.table(d)
+}
+
+func (f *findings) submitter(rf reportFormat, ipDetail netip.Addr) (string, error) {
+	d := pterm.TableData{{"#", "Event UUID", "Protocol", "Timestamp"}}
+	if f.Reputation != nil {
+		d[0] = append(d[0], "Reputation")
+	}
+
+	var timeline string
+	item, ok := f.Submitters[ipDetail]
+	if ok {
+		timestamps := make([]uint32, 0, len(item.Events))
+		for _, e := range item.Events {
+			timestamps = append(timestamps, e.TimeStamp)
+		}
+		timeline = fmt.Sprintf("Activity: %s\nHours:    %s (00-23 %s)\n\n",
+			sparkline(timestamps, sparklineBuckets), diurnal(f.hours(timestamps)), f.time(0).Location(),
+		)
+
+		for i, e := range item.Events {
+			ts := f.formatTime(e.TimeStamp)
+			row := []string{strconv.Itoa(i + 1), e.EventUUID.String(), e.Protocol.String(), ts}
+			if f.Reputation != nil {
+				row = append(row, f.reputationOf(ipDetail))
+			}
+			d = append(d, row)
+		}
+	} else {
+		row := []string{"", "NO", "EVENTS", "FOUND"}
+		if f.Reputation != nil {
+			row = append(row, f.reputationOf(ipDetail))
+		}
+		d = append(d, row)
+	}
+
+	s, err := rf.table(d)
+	if err != nil {
+		return "", err
+	}
+
+	return timeline + s, nil
+}
+
+// hours counts the time stamps by their hour of the day in the findings' time
+// zone.
+func (f *findings) hours(timestamps []uint32) [24]int {
+	var counts [24]int
+	for _, ts := range timestamps {
+		counts[f.time(ts).Hour()]++
+	}
+
+	return counts
+}
+
+// diurnal renders the counts of each hour of the day as a block character
+// scaled to the busiest hour, or a space if the hour is empty, revealing the
+// hours a submitter is active, and so its time zone or automation.
+func diurnal(counts [24]int) string {
+	most := 0
+	for _, c := range counts {
+		if c > most {
+			most = c
+		}
+	}
+
+	line := make([]rune, len(counts))
+	for i, c := range counts {
+		if c == 0 {
+			line[i] = ' '
+			continue
+		}
+		line[i] = sparkBlocks[(c*len(sparkBlocks)-1)/most]
+	}
+
+	return string(line)
+}
+
+// sparklineBuckets is the number of time buckets in a submitter's activity
+// sparkline.
+const sparklineBuckets = 24
+
+// sparkBlocks are the block characters of a sparkline, from fewest events to
+// most.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline divides the span of the time stamps into the given number of equal
+// buckets and renders the number of time stamps in each as a block character,
+// or a space if the bucket is empty. Time stamps sharing a single instant
+// render as a single block.
+func sparkline(timestamps []uint32, buckets int) string {
+	if len(timestamps) == 0 || buckets < 1 {
+		return ""
+	}
+
+	first, last := timestamps[0], timestamps[0]
+	for _, ts := range timestamps[1:] {
+		if ts < first {
+			first = ts
+		}
+		if ts > last {
+			last = ts
 		}
 	}
-}
-
-func (f *findings) report(ipDetail netip.Addr) (string, error) {
-	f.populate()
+	if first == last {
+		return string(sparkBlocks[len(sparkBlocks)-1])
+	}
+
+	counts := make([]int, buckets)
+	span := uint64(last-first) + 1
+	for _, ts := range timestamps {
+		counts[uint64(ts-first)*uint64(buckets)/span]++
+	}
+
+	most := 0
+	for _, c := range counts {
+		if c > most {
+			most = c
+		}
+	}
+
+	line := make([]rune, buckets)
+	for i, c := range counts {
+		if c == 0 {
+			line[i] = ' '
+			continue
+		}
+		line[i] = sparkBlocks[(c*len(sparkBlocks)-1)/most]
+	}
+
+	return string(line)
+}
+
+// ItemCount is an item found in events' payloads, such as a password, and the
+// number of events in which it occurs.
+type ItemCount struct {
+	Item  string
+	Count int
+}
+
+// itemCounts returns the count items of proto in m with the most occurrences,
+// padded with empty items to count as top does, or a missingProtocolError for
+// finding if proto has none.
+func itemCounts(m map[p.Protocol]itemOccurrenceMap, proto p.Protocol, finding string, count int) ([]ItemCount, error) {
+	items, ok := m[proto]
+	if !ok {
+		return nil, &missingProtocolError{Protocol: proto, Finding: finding}
+	}
+
+	top := items.top(count)
+	counts := make([]ItemCount, len(top))
+	for i, item := range top {
+		counts[i] = ItemCount{Item: item.Item, Count: item.Occurrence}
+	}
+
+	return counts, nil
+}
+
+// TopEmails returns the count most frequent emails of proto's events.
+func (f *findings) TopEmails(proto p.Protocol, count int) ([]ItemCount, error) {
+	return itemCounts(f.Emails, proto, "emails", count)
+}
+
+// TopPasswords returns the count most frequent passwords of proto's events.
+func (f *findings) TopPasswords(proto p.Protocol, count int) ([]ItemCount, error) {
+	return itemCounts(f.Passwords, proto, "passwords", count)
+}
+
+// TopUserAgents returns the count most frequent user-agents of proto's events.
+func (f *findings) TopUserAgents(proto p.Protocol, count int) ([]ItemCount, error) {
+	return itemCounts(f.UserAgents, proto, "user-agents", count)
+}
+
+// TopUsernames returns the count most frequent usernames of proto's events.
+func (f *findings) TopUsernames(proto p.Protocol, count int) ([]ItemCount, error) {
+	return itemCounts(f.Usernames, proto, "users", count)
+}
+
+func (f *findings) topEmails(rf reportFormat, proto p.Protocol, count int) (string, error) {
+	item, ok := f.ByProtocol[proto]
+	if !ok {
+		return "", &missingProtocolError{Protocol: proto, Finding: "events"}
+	}
+
+	emails, err := f.TopEmails(proto, count)
+	if err != nil {
+		return "", err
+	}
+
+	d := pterm.TableData{{"#", "Email", "Count", "%"}}
+	for i := range emails {
+		d = append(d,
+			[]string{
+				strconv.Itoa(i + 1),
+				emails[i].Item,
+				strconv.Itoa(emails[i].Count),
+				percent(emails[i].Count, item.Occurrence),
+			},
+		)
+	}
+	d = append(d,
+		[]string{
+			"",
+			pterm.DefaultTable.HeaderStyle.Sprintf("TOTAL %s EVENTS", proto.String()),
+			pterm.DefaultTable.HeaderStyle.Sprint(thousands(item.Occurrence)),
+			"",
+		},
+	)
 
-	var buf bytes.Buffer
+	return rf.table(d)
+}
 
-	// SSH Top 5 Passwords and Users
-	s, err := f.topPasswordsUsers(p.SSH, 5)
-	if err != nil {
-		return "", err
+func (f *findings) topPasswordsUsers(rf reportFormat, proto p.Protocol, count int) (string, error) {
+	item, ok := f.ByProtocol[proto]
+	if !ok {
+		return "", &missingProtocolError{Protocol: proto, Finding: "events"}
 	}
-	buf.WriteString(
-		fmt.Sprintf("\u001B[%dmWhat are the top 5 %s passwords and users?\u001B[0m\n\n",
-			labelColor, p.SSH.String(),
-		),
-	)
-	buf.WriteString(s)
 
-	// TELNET Top 5 Passwords and Users
-	s, err = f.topPasswordsUsers(p.TELNET, 5)
+	passwords, err := f.TopPasswords(proto, count)
 	if err != nil {
 		return "", err
 	}
-	buf.WriteString(
-		fmt.Sprintf("\n\n\n\u001B[%dmWhat are the top 5 %s passwords and users?\u001B[0m\n\n",
-			labelColor, p.TELNET.String(),
-		),
-	)
-	buf.WriteString(s)
 
-	// HTTP Top 30 User-Agents
-	s, err = f.topUserAgents(p.HTTP, 30)
+	usernames, err := f.TopUsernames(proto, count)
 	if err != nil {
 		return "", err
 	}
-	buf.WriteString(
-		fmt.Sprintf("\n\n\n\u001B[%dmWhat are the top 30 %s user-agents?\u001B[0m\n\n",
-			labelColor, p.HTTP.String(),
-		),
-	)
-	buf.WriteString(s)
 
-	// SMTP Top 20 Emails
-	s, err = f.topEmails(p.SMTP, 20)
-	if err != nil {
-		return "", err
+	d := pterm.TableData{{"#", "Passwords", "Count", "%", "", "Users", "Count", "%"}}
+	for i := range passwords {
+		d = append(d,
+			[]string{
+				strconv.Itoa(i + 1),
+				f.credential(passwords[i].Item),
+				strconv.Itoa(passwords[i].Count),
+				percent(passwords[i].Count, item.Occurrence),
+				"",
+				f.credential(usernames[i].Item),
+				strconv.Itoa(usernames[i].Count),
+				percent(usernames[i].Count, item.Occurrence),
+			},
+		)
 	}
-	buf.WriteString(
-		fmt.Sprintf("\n\n\n\u001B[%dmWhat are the top 20 %s emails?\u001B[0m\n\n",
-			labelColor, p.SMTP.String(),
-		),
+	d = append(d,
+		[]string{
+			"", "", "", "", "",
+			pterm.DefaultTable.HeaderStyle.Sprintf("TOTAL %s EVENTS", proto.String()),
+			pterm.DefaultTable.HeaderStyle.Sprint(thousands(item.Occurrence)),
+			"",
+		},
 	)
-	buf.WriteString(s)
 
-	// Top 15 Submitters
-	s, err = f.topSubmitters(15)
-	if err != nil {
-		return "", err
+	return rf.table(d)
+}
+
+// histogramBarWidth is the width of the longest bar in the report's
+// histograms.
+const histogramBarWidth = 40
+
+// passwordLengths renders the number of SSH and TELNET passwords of each
+// length, in characters, as a table with bars, revealing whether attackers
+// favor short dictionary words or long generated strings.
+func (f *findings) passwordLengths(rf reportFormat) (string, error) {
+	counts := make(map[int]int)
+	total := 0
+	for _, proto := range []p.Protocol{p.SSH, p.TELNET} {
+		for password, item := range f.Passwords[proto] {
+			counts[utf8.RuneCountInString(password)] += item.Occurrence
+			total += item.Occurrence
+		}
 	}
-	buf.WriteString(
-		fmt.Sprintf("\n\n\n\u001B[%dmWho are the top 15 subitters?\u001B[0m\n\n", labelColor),
-	)
-	buf.WriteString(s)
 
-	// Submitter
-	if ipDetail.IsValid() {
-		s, err = f.submitter(ipDetail)
-		if err != nil {
-			return "", err
+	lengths := make([]int, 0, len(counts))
+	most := 0
+	for length, n := range counts {
+		lengths = append(lengths, length)
+		if n > most {
+			most = n
 		}
-		buf.WriteString(
-			fmt.Sprintf("\n\n\n\u001B[%dmWhat events did %s submit?\u001B[0m\n\n",
-				labelColor, ipDetail.String(),
-			),
+	}
+	sort.Ints(lengths)
+
+	d := pterm.TableData{{"Length", "Passwords", "%", ""}}
+	for _, length := range lengths {
+		n := counts[length]
+		d = append(d,
+			[]string{
+				strconv.Itoa(length),
+				thousands(n),
+				percent(n, total),
+				strings.Repeat("█", n*histogramBarWidth/most),
+			},
+		)
+	}
+	if total == 0 {
+		d = append(d, []string{"NO", "PASSWORDS", "FOUND", ""})
+	} else {
+		d = append(d,
+			[]string{
+				pterm.DefaultTable.HeaderStyle.Sprint("TOTAL"),
+				pterm.DefaultTable.HeaderStyle.Sprint(thousands(total)),
+				"",
+				"",
+			},
 		)
-		buf.WriteString(s)
 	}
 
-	return buf.String(), nil
+	return rf.table(d)
 }
 
-func (f *findings) submitter(ipDetail netip.Addr) (string, error) {
-	d := pterm.TableData{{"#", "Event UUID", "Protocol", "Timestamp"}}
+// credential returns the password or username for display, masked if the
+// findings MaskCredentials.
+func (f *findings) credential(s string) string {
+	if !f.MaskCredentials {
+		return s
+	}
 
-	item, ok := f.Submitters[ipDetail]
-	if ok {
-		for i, e := range item.Events {
-			ts := time.Unix(int64(e.TimeStamp), 0).Format("2006-01-02")
+	return strings.Repeat("*", utf8.RuneCountInString(s))
+}
+
+// mergedPayloadMap returns the item occurrences for the given payload key
+// merged across protocols, summing the occurrences of each item.
+func (f *findings) mergedPayloadMap(key string) itemOccurrenceMap {
+	merged := make(itemOccurrenceMap)
+	for _, m := range f.payloadMaps(key) {
+		for v, item := range m {
+			if merged[v] == nil {
+				merged[v] = &itemOccurrence{Item: v}
+			}
+			merged[v].Occurrence += item.Occurrence
+		}
+	}
+
+	return merged
+}
+
+// classifications renders the share of each payload key's values, across all
+// protocols, in each of its classifications, most common first.
+func (f *findings) classifications(rf reportFormat) (string, error) {
+	d := pterm.TableData{{"Key", "Classification", "Count", "%"}}
+	for _, key := range aggregatedKeys {
+		classes := make(itemOccurrenceMap)
+		total := 0
+		for v, item := range f.mergedPayloadMap(key) {
+			class := p.ClassifyValue(key, v)
+			if classes[class] == nil {
+				classes[class] = &itemOccurrence{Item: class}
+			}
+			classes[class].Occurrence += item.Occurrence
+			total += item.Occurrence
+		}
+
+		for _, item := range classes.top(len(classes)) {
+			d = append(d, []string{key, item.Item, strconv.Itoa(item.Occurrence), percent(item.Occurrence, total)})
+		}
+	}
+
+	return rf.table(d)
+}
+
+// cardinality renders the number of unique values and total occurrences of
+// each aggregated payload key, by protocol. Many unique values relative to
+// their occurrences hint at the breadth of a dictionary attack.
+func (f *findings) cardinality(rf reportFormat) (string, error) {
+	seen := make(map[p.Protocol]bool)
+	for _, key := range aggregatedKeys {
+		for proto, m := range f.payloadMaps(key) {
+			if len(m) > 0 {
+				seen[proto] = true
+			}
+		}
+	}
+	protocols := make([]p.Protocol, 0, len(seen))
+	for proto := range seen {
+		protocols = append(protocols, proto)
+	}
+	sort.Slice(protocols, func(i, j int) bool { return dotProtocol(protocols[i]) < dotProtocol(protocols[j]) })
+
+	d := pterm.TableData{{"Protocol", "Key", "Unique", "Total", "Unique %"}}
+	for _, proto := range protocols {
+		for _, key := range aggregatedKeys {
+			m := f.payloadMaps(key)[proto]
+			if len(m) == 0 {
+				continue
+			}
+
+			total := 0
+			for _, item := range m {
+				total += item.Occurrence
+			}
 			d = append(d,
-				[]string{strconv.Itoa(i + 1), e.EventUUID.String(), e.Protocol.String(), ts},
+				[]string{
+					dotProtocol(proto),
+					key,
+					thousands(len(m)),
+					thousands(total),
+					percent(len(m), total),
+				},
 			)
 		}
-	} else {
-		d = append(d, []string{"", "NO", "EVENTS", "FOUND"})
 	}
 
-	return pterm.DefaultTable.WithHasHeader().WithData(d).Srender()
+	return rf.table(d)
 }
 
-func (f *findings) topEmails(proto p.Protocol, count int) (string, error) {
-	item, ok := f.ByProtocol[proto]
-	if !ok {
-		return "", fmt.Errorf("no %s events", proto.String())
+// topAcrossProtocols renders the count most frequent values of the payload key
+// regardless of protocol.
+func (f *findings) topAcrossProtocols(rf reportFormat, key string, count int) (string, error) {
+	if !aggregated(key) {
+		return "", fmt.Errorf("payload key %q isn't aggregated", key)
 	}
 
-	m, ok := f.Emails[proto]
-	if !ok {
-		return "", fmt.Errorf("no %s emails", proto.String())
+	m := f.mergedPayloadMap(key)
+	total := 0
+	for _, item := range m {
+		total += item.Occurrence
 	}
-	emails := m.top(count)
 
-	d := pterm.TableData{{"#", "Email", "Count"}}
-	for i := range emails {
+	value := f.credential
+	if key != "password" && key != "username" {
+		value = func(s string) string { return s }
+	}
+
+	d := pterm.TableData{{"#", strings.ToUpper(key[:1]) + key[1:], "Count", "%"}}
+	for i, item := range m.top(count) {
 		d = append(d,
 			[]string{
 				strconv.Itoa(i + 1),
-				emails[i].Item,
-				strconv.Itoa(emails[i].Occurrence),
+				value(item.Item),
+				strconv.Itoa(item.Occurrence),
+				percent(item.Occurrence, total),
 			},
 		)
 	}
 	d = append(d,
 		[]string{
 			"",
-			pterm.DefaultTable.HeaderStyle.Sprintf("TOTAL %s EVENTS", proto.String()),
-			pterm.DefaultTable.HeaderStyle.Sprintf("%d", item.Occurrence),
+			pterm.DefaultTable.HeaderStyle.Sprintf("TOTAL %sS", strings.ToUpper(key)),
+			pterm.DefaultTable.HeaderStyle.Sprint(thousands(total)),
+			"",
 		},
 	)
 
-	return pterm.DefaultTable.WithHasHeader().WithData(d).Srender()
+	return rf.table(d)
 }
 
-func (f *findings) topPasswordsUsers(proto p.Protocol, count int) (string, error) {
-	item, ok := f.ByProtocol[proto]
-	if !ok {
-		return "", fmt.Errorf("no %s events", proto.String())
+func (f *findings) topSubmitters(rf reportFormat, count int) (string, error) {
+	totalEvents := 0
+	for _, v := range f.Submitters {
+		totalEvents += v.Occurrence
 	}
+	submitters := f.submitterOccurrences().top(count)
 
-	m, ok := f.Passwords[proto]
-	if !ok {
-		return "", fmt.Errorf("no %s passwords", proto.String())
+	d := pterm.TableData{{"#", "IP Address", "Count", "%", "First Seen", "Last Seen"}}
+	if f.Reputation != nil {
+		d[0] = append(d[0], "Reputation")
 	}
-	passwords := m.top(count)
+	for i, item := range submitters {
+		var firstSeen, lastSeen string
+		if first, last, ok := item.span(); ok {
+			firstSeen = f.formatTime(first)
+			lastSeen = f.formatTime(last)
+		}
 
-	m, ok = f.Usernames[proto]
-	if !ok {
-		return "", fmt.Errorf("no %s users", proto.String())
+		row := []string{
+			strconv.Itoa(i + 1),
+			item.Item,
+			strconv.Itoa(item.Occurrence),
+			percent(item.Occurrence, totalEvents),
+			firstSeen,
+			lastSeen,
+		}
+		if f.Reputation != nil {
+			addr, _ := netip.ParseAddr(item.Item)
+			row = append(row, f.reputationOf(addr))
+		}
+		d = append(d, row)
 	}
-	usernames := m.top(count)
+	total := []string{
+		"",
+		pterm.DefaultTable.HeaderStyle.Sprint("TOTAL EVENTS"),
+		pterm.DefaultTable.HeaderStyle.Sprint(thousands(totalEvents)),
+		"",
+		"",
+		"",
+	}
+	if f.Reputation != nil {
+		total = append(total, "")
+	}
+	d = append(d, total)
+
+	return rf.table(d)
+}
+
+func (f *findings) topSubnets(rf reportFormat, count int) (string, error) {
+	m, err := f.subnetOccurrences()
+	if err != nil {
+		return "", err
+	}
+
+	totalEvents := 0
+	for _, v := range m {
+		totalEvents += v.Occurrence
+	}
+
+	d := pterm.TableData{{"#", "Subnet", "Count", "%", "First Seen", "Last Seen"}}
+	for i, item := range m.top(count) {
+		var firstSeen, lastSeen string
+		if first, last, ok := item.span(); ok {
+			firstSeen = f.formatTime(first)
+			lastSeen = f.formatTime(last)
+		}
 
-	d := pterm.TableData{{"#", "Passwords", "Count", "", "Users", "Count"}}
-	for i := range passwords {
 		d = append(d,
 			[]string{
 				strconv.Itoa(i + 1),
-				passwords[i].Item,
-				strconv.Itoa(passwords[i].Occurrence),
-				"",
-				usernames[i].Item,
-				strconv.Itoa(usernames[i].Occurrence),
+				item.Item,
+				strconv.Itoa(item.Occurrence),
+				percent(item.Occurrence, totalEvents),
+				firstSeen,
+				lastSeen,
 			},
 		)
 	}
 	d = append(d,
 		[]string{
-			"", "", "", "",
-			pterm.DefaultTable.HeaderStyle.Sprintf("TOTAL %s EVENTS", proto.String()),
-			pterm.DefaultTable.HeaderStyle.Sprintf("%d", item.Occurrence),
+			"",
+			pterm.DefaultTable.HeaderStyle.Sprint("TOTAL EVENTS"),
+			pterm.DefaultTable.HeaderStyle.Sprint(thousands(totalEvents)),
+			"",
+			"",
+			"",
 		},
 	)
 
-	return pterm.DefaultTable.WithHasHeader().WithData(d).Srender()
+	return rf.table(d)
 }
 
-func (f *findings) topSubmitters(count int) (string, error) {
-	totalEvents := 0
-	submitters := make(itemOccurrences, 0, len(f.Submitters))
-	for k, v := range f.Submitters {
-		submitters = append(submitters, &itemOccurrence{Item: k.String(), Occurrence: v.Occurrence})
-		totalEvents += v.Occurrence
+// subnetOccurrences returns the submitters aggregated by their addresses
+// masked to the ClusterPrefix, or ClusterPrefix6 for IPv6 addresses, keyed by
+// the subnets' string representations.
+func (f *findings) subnetOccurrences() (itemOccurrenceMap, error) {
+	m := make(itemOccurrenceMap)
+	for addr, v := range f.Submitters {
+		bits := f.ClusterPrefix
+		if addr.Is6() {
+			bits = f.ClusterPrefix6
+		}
+		if bits == 0 {
+			continue
+		}
+
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			return nil, fmt.Errorf("clustering submitter %s: %w", addr, err)
+		}
+
+		key := prefix.String()
+		item := m[key]
+		if item == nil {
+			item = &itemOccurrence{Item: key}
+			m[key] = item
+		}
+		item.Occurrence += v.Occurrence
+		if first, last, ok := v.span(); ok {
+			item.see(first)
+			item.see(last)
+		}
+	}
+
+	return m, nil
+}
+
+// subnetsLabel returns the subnet section's label, naming the prefix length of
+// each address family among the submitters it clusters.
+func (f *findings) subnetsLabel() string {
+	var v4, v6 bool
+	for addr := range f.Submitters {
+		v4 = v4 || addr.Is4() && f.ClusterPrefix > 0
+		v6 = v6 || addr.Is6() && f.ClusterPrefix6 > 0
+	}
+
+	switch {
+	case v4 && v6:
+		return fmt.Sprintf("Which /%d IPv4 and /%d IPv6 subnets are most active?", f.ClusterPrefix, f.ClusterPrefix6)
+	case v6:
+		return fmt.Sprintf("Which /%d IPv6 subnets are most active?", f.ClusterPrefix6)
+	default:
+		return fmt.Sprintf("Which /%d subnets are most active?", f.ClusterPrefix)
+	}
+}
+
+// topology renders a matrix of each emitter node's event counts by protocol,
+// with a total for each node.
+func (f *findings) topology(rf reportFormat) (string, error) {
+	nodes := make([]uint16, 0, len(f.ProtocolsByNode))
+	seen := make(map[p.Protocol]bool)
+	for node, byProto := range f.ProtocolsByNode {
+		nodes = append(nodes, node)
+		for proto := range byProto {
+			seen[proto] = true
+		}
 	}
-	sort.Sort(submitters)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
 
-	if len(submitters) < count {
-		// Ensure there's at least `count` submitters, even if the last few are
-		// empty.
-		for j := count - len(submitters); j > 0; j-- {
-			submitters = append(submitters, new(itemOccurrence))
+	protocols := make([]p.Protocol, 0, len(seen))
+	for proto := range seen {
+		protocols = append(protocols, proto)
+	}
+	sort.Slice(protocols, func(i, j int) bool { return dotProtocol(protocols[i]) < dotProtocol(protocols[j]) })
+
+	header := []string{"Node ID"}
+	for _, proto := range protocols {
+		header = append(header, dotProtocol(proto))
+	}
+	d := pterm.TableData{append(header, "Total")}
+
+	for _, node := range nodes {
+		row := []string{strconv.Itoa(int(node))}
+		total := 0
+		for _, proto := range protocols {
+			n := f.ProtocolsByNode[node][proto]
+			total += n
+			row = append(row, strconv.Itoa(n))
 		}
+		d = append(d, append(row, pterm.DefaultTable.HeaderStyle.Sprint(thousands(total))))
+	}
+
+	return rf.table(d)
+}
+
+// unknownProtocols renders the events of unknown protocols tallied by their
+// raw protocol values, with a sample payload of each.
+func (f *findings) unknownProtocols(rf reportFormat) (string, error) {
+	const maxSample = 60
+
+	protocols := make([]p.Protocol, 0, len(f.Unknown))
+	for proto := range f.Unknown {
+		protocols = append(protocols, proto)
 	}
+	sort.Slice(protocols, func(i, j int) bool {
+		ci, cj := f.ByProtocol[protocols[i]].Occurrence, f.ByProtocol[protocols[j]].Occurrence
+		if ci == cj {
+			return protocols[i] < protocols[j]
+		}
+
+		return ci > cj
+	})
+
+	d := pterm.TableData{{"#", "Protocol", "Count", "Sample Payload"}}
+	for i, proto := range protocols {
+		sample := f.Unknown[proto]
+		if len(sample) > maxSample {
+			sample = sample[:maxSample] + "..."
+		}
 
-	d := pterm.TableData{{"#", "IP Address", "Count"}}
-	for i := 0; i < count; i++ {
 		d = append(d,
 			[]string{
 				strconv.Itoa(i + 1),
-				submitters[i].Item,
-				strconv.Itoa(submitters[i].Occurrence),
+				fmt.Sprintf("0x%04X (%d)", uint16(proto), uint16(proto)),
+				strconv.Itoa(f.ByProtocol[proto].Occurrence),
+				strconv.Quote(sample),
 			},
 		)
 	}
-	d = append(d,
-		[]string{
-			"",
-			pterm.DefaultTable.HeaderStyle.Sprint("TOTAL EVENTS"),
-			pterm.DefaultTable.HeaderStyle.Sprintf("%d", totalEvents),
-		},
-	)
 
-	return pterm.DefaultTable.WithHasHeader().WithData(d).Srender()
+	return rf.table(d)
+}
+
+// submitterOccurrences returns the submitters keyed by their IP addresses'
+// string representations.
+func (f *findings) submitterOccurrences() itemOccurrenceMap {
+	m := make(itemOccurrenceMap, len(f.Submitters))
+	for k, v := range f.Submitters {
+		m[k.String()] = v
+	}
+
+	return m
+}
+
+// time returns the time of the given event time stamp in the findings'
+// location, defaulting to the local time zone.
+func (f *findings) time(ts uint32) time.Time {
+	loc := f.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	return time.Unix(int64(ts), 0).In(loc)
+}
+
+// Named time formats. Any other time format is a Go time layout.
+const (
+	timeFormatEpoch    = "epoch"    // seconds since the Unix epoch
+	timeFormatDate     = "date"     // time.DateOnly
+	timeFormatDateTime = "datetime" // time.DateTime
+)
+
+// checkTimeFormat returns an error if the time format is neither named nor a
+// Go time layout.
+func checkTimeFormat(format string) error {
+	switch format {
+	case "", timeFormatEpoch, timeFormatDate, timeFormatDateTime:
+		return nil
+	}
+
+	// A layout without any layout elements renders every time the same.
+	a := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	b := time.Date(2012, 11, 14, 16, 17, 18, 0, time.UTC)
+	if a.Format(format) == b.Format(format) {
+		return fmt.Errorf("time format %q is neither %s, %s, %s, nor a Go time layout",
+			format, timeFormatEpoch, timeFormatDate, timeFormatDateTime)
+	}
+
+	return nil
+}
+
+// formatTime renders the event time stamp in the findings' TimeFormat and
+// location.
+func (f *findings) formatTime(ts uint32) string {
+	return f.formatInstant(f.time(ts))
+}
+
+// formatInstant renders the time in the findings' TimeFormat, defaulting to
+// time.DateTime.
+func (f *findings) formatInstant(t time.Time) string {
+	switch f.TimeFormat {
+	case timeFormatEpoch:
+		return strconv.FormatInt(t.Unix(), 10)
+	case timeFormatDate:
+		return t.Format(time.DateOnly)
+	case "", timeFormatDateTime:
+		return t.Format(time.DateTime)
+	default:
+		return t.Format(f.TimeFormat)
+	}
 }
 
-func (f *findings) topUserAgents(proto p.Protocol, count int) (string, error) {
+func (f *findings) topUserAgents(rf reportFormat, proto p.Protocol, count int) (string, error) {
 	item, ok := f.ByProtocol[proto]
 	if !ok {
-		return "", fmt.Errorf("no %s events", proto.String())
+		return "", &missingProtocolError{Protocol: proto, Finding: "events"}
 	}
 
-	m, ok := f.UserAgents[proto]
-	if !ok {
-		return "", fmt.Errorf("no %s user-agents", proto.String())
+	userAgents, err := f.TopUserAgents(proto, count)
+	if err != nil {
+		return "", err
 	}
-	userAgents := m.top(count)
 
-	d := pterm.TableData{{"#", "User-Agents", "Count"}}
+	d := pterm.TableData{{"#", "User-Agents", "Count", "%"}}
 	for i := range userAgents {
 		d = append(d,
 			[]string{
 				strconv.Itoa(i + 1),
 				userAgents[i].Item,
-				strconv.Itoa(userAgents[i].Occurrence),
+				strconv.Itoa(userAgents[i].Count),
+				percent(userAgents[i].Count, item.Occurrence),
 			},
 		)
 	}
@@ -335,52 +1437,207 @@ func (f *findings) topUserAgents(proto p.Protocol, count int) (string, error) {
 		[]string{
 			"",
 			pterm.DefaultTable.HeaderStyle.Sprintf("TOTAL %s EVENTS", proto.String()),
-			pterm.DefaultTable.HeaderStyle.Sprintf("%d", item.Occurrence),
+			pterm.DefaultTable.HeaderStyle.Sprint(thousands(item.Occurrence)),
+			"",
 		},
 	)
+	if rf.width > 0 {
+		fitColumn(d, 1, rf.width)
+	}
+
+	return rf.table(d)
+}
+
+// fitColumn truncates the cells of the given column so the rendered table is
+// no wider than width, if possible. The column remains at least as wide as its
+// header and its last row, which are never truncated.
+func fitColumn(d pterm.TableData, column, width int) {
+	widths := make([]int, len(d[0]))
+	for _, row := range d {
+		for j, cell := range row {
+			if w := utf8.RuneCountInString(pterm.RemoveColorFromString(cell)); w > widths[j] {
+				widths[j] = w
+			}
+		}
+	}
+
+	// Account for the " | " separating each column.
+	avail := width - 3*(len(widths)-1)
+	for j, w := range widths {
+		if j != column {
+			avail -= w
+		}
+	}
+	for _, i := range []int{0, len(d) - 1} {
+		if w := utf8.RuneCountInString(pterm.RemoveColorFromString(d[i][column])); w > avail {
+			avail = w
+		}
+	}
+
+	for _, row := range d[1 : len(d)-1] {
+		row[column] = truncate(row[column], avail)
+	}
+}
+
+// truncate returns s shortened to width runes, ending in an ellipsis if
+// shortened.
+func truncate(s string, width int) string {
+	const ellipsis = "..."
+
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	if width <= len(ellipsis) {
+		return string([]rune(s)[:width])
+	}
+
+	return string([]rune(s)[:width-len(ellipsis)]) + ellipsis
+}
+
+// thousands returns n in decimal with commas separating groups of three
+// digits, such as 1,234,567.
+func thousands(n int) string {
+	s := strconv.Itoa(n)
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
 
-	return pterm.DefaultTable.WithHasHeader().WithData(d).Srender()
+	return b.String()
+}
+
+// percent returns n as a percentage of total to one decimal place. A zero total
+// yields zero percent.
+func percent(n, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+
+	return strconv.FormatFloat(100*float64(n)/float64(total), 'f', 1, 64) + "%"
+}
+
+// latency returns the time between the event's TimeStamp and its receipt.
+func latency(event *p.Event) time.Duration {
+	return event.ReceivedAt.Sub(time.Unix(int64(event.TimeStamp), 0))
+}
+
+// removeEvent returns events without the first occurrence of event.
+func removeEvent(events []*p.Event, event *p.Event) []*p.Event {
+	for i, e := range events {
+		if e == event {
+			return append(events[:i], events[i+1:]...)
+		}
+	}
+
+	return events
 }
 
 type itemOccurrence struct {
 	Events     []*p.Event
+	First      uint32 // earliest event time stamp
 	Item       string
+	Last       uint32 // latest event time stamp
 	Occurrence int
+
+	seen bool // whether First and Last hold a time stamp
+}
+
+// see updates the item's first and last time stamps given a time stamp of one
+// of its events.
+func (i *itemOccurrence) see(ts uint32) {
+	if !i.seen || ts < i.First {
+		i.First = ts
+	}
+	if !i.seen || ts > i.Last {
+		i.Last = ts
+	}
+	i.seen = true
+}
+
+// span returns the earliest and latest time stamps of the item's events. The
+// boolean is false if the item has seen no time stamps.
+func (i *itemOccurrence) span() (first, last uint32, ok bool) {
+	return i.First, i.Last, i.seen
 }
 
 type itemOccurrences []*itemOccurrence
 
-func (i itemOccurrences) Len() int { return len(i) }
-func (i itemOccurrences) Less(j, k int) bool {
-	if i[j].Occurrence == i[k].Occurrence {
+func (i itemOccurrences) Len() int           { return len(i) }
+func (i itemOccurrences) Less(j, k int) bool { return ranksAbove(i[j], i[k]) }
+func (i itemOccurrences) Swap(j, k int)      { i[j], i[k] = i[k], i[j] }
+
+var _ sort.Interface = (*itemOccurrences)(nil)
+
+// ranksAbove returns true if a has more occurrences than b or, if they have the
+// same number, a's item sorts before b's.
+func ranksAbove(a, b *itemOccurrence) bool {
+	if a.Occurrence == b.Occurrence {
 		// If the occurrences are the same, sort ascending by the item.
-		return i[j].Item < i[k].Item
+		return a.Item < b.Item
 	}
 
 	// Less is really More in our use case because we want a reverse sort.
-	return i[j].Occurrence > i[k].Occurrence
+	return a.Occurrence > b.Occurrence
 }
-func (i itemOccurrences) Swap(j, k int) { i[j], i[k] = i[k], i[j] }
-
-var _ sort.Interface = (*itemOccurrences)(nil)
 
 type itemOccurrenceMap map[string]*itemOccurrence
 
+// top returns the count items with the most occurrences, breaking ties by
+// ascending item. Rather than sorting every item, it keeps the best count seen
+// in a bounded heap, which matters for maps of many distinct items.
 func (i itemOccurrenceMap) top(count int) itemOccurrences {
-	items := itemOccurrences{}
+	if count <= 0 {
+		return itemOccurrences{}
+	}
 
+	h := &itemHeap{make(itemOccurrences, 0, count)}
 	for _, item := range i {
-		items = append(items, item)
+		switch {
+		case h.Len() < count:
+			heap.Push(h, item)
+		case ranksAbove(item, h.itemOccurrences[0]):
+			h.itemOccurrences[0] = item
+			heap.Fix(h, 0)
+		}
 	}
 
-	sort.Sort(items)
+	// Popping yields the items worst first.
+	items := make(itemOccurrences, h.Len(), count)
+	for j := len(items) - 1; j >= 0; j-- {
+		items[j] = heap.Pop(h).(*itemOccurrence)
+	}
 
-	if len(items) < count {
-		// Ensure there's at least `count` items, even if the last few are empty.
-		for j := count - len(items); j > 0; j-- {
-			items = append(items, new(itemOccurrence))
-		}
+	// Ensure there's at least `count` items, even if the last few are empty.
+	for j := count - len(items); j > 0; j-- {
+		items = append(items, new(itemOccurrence))
 	}
 
-	return items[:count]
+	return items
+}
+
+// itemHeap is a heap of items whose root is the item that sorts last in
+// itemOccurrences order.
+type itemHeap struct{ itemOccurrences }
+
+func (h itemHeap) Less(j, k int) bool { return h.itemOccurrences.Less(k, j) }
+func (h *itemHeap) Push(x any)        { h.itemOccurrences = append(h.itemOccurrences, x.(*itemOccurrence)) }
+func (h *itemHeap) Pop() any {
+	n := len(h.itemOccurrences) - 1
+	item := h.itemOccurrences[n]
+	h.itemOccurrences = h.itemOccurrences[:n]
+
+	return item
 }
+
+var _ heap.Interface = (*itemHeap)(nil)
@@ -0,0 +1,1211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pterm/pterm"
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_findings_Add(t *testing.T) {
+	Convey("Given findings with a window and a fake clock", t, func() {
+		now := &fakeClock{now: time.Date(2020, 10, 15, 0, 0, 0, 0, time.UTC)}
+		f := &findings{Clock: now, Window: time.Minute}
+
+		Convey("When adding events as the clock advances", func() {
+			// validEvents[1] and [2] are SSH events with distinct passwords.
+			f.Add(validEvents[1])
+			now.advance(45 * time.Second)
+			f.Add(validEvents[2])
+
+			Convey("It should include both events within the window", func() {
+				top := f.Passwords[p.SSH].top(2)
+				So(top[0].Item, ShouldEqual, "Jackallava")
+				So(top[1].Item, ShouldEqual, "Shriekerlavender")
+				So(f.ByProtocol[p.SSH].Occurrence, ShouldEqual, 2)
+			})
+
+			Convey("It should drop events older than the window from the top-N", func() {
+				now.advance(30 * time.Second)
+				f.Add(validEvents[0])
+
+				top := f.Passwords[p.SSH].top(2)
+				So(top[0].Item, ShouldEqual, "Shriekerlavender")
+				So(top[1].Occurrence, ShouldEqual, 0)
+				So(f.ByProtocol[p.SSH].Occurrence, ShouldEqual, 1)
+				So(f.Submitters, ShouldNotContainKey, validEvents[1].IP)
+				So(f.Events, ShouldResemble, []*p.Event{validEvents[2], validEvents[0]})
+			})
+		})
+	})
+
+	Convey("Given findings with a window populated with events before the first Add", t, func() {
+		now := &fakeClock{now: time.Date(2020, 10, 15, 0, 0, 0, 0, time.UTC)}
+		f := &findings{Clock: now, Events: []*p.Event{validEvents[1]}, Window: time.Minute}
+
+		Convey("When adding events as the clock advances", func() {
+			f.Add(validEvents[2])
+
+			Convey("It should account for the populated event as arriving with the first", func() {
+				So(f.ByProtocol[p.SSH].Occurrence, ShouldEqual, 2)
+				So(f.arrivals, ShouldHaveLength, len(f.Events))
+			})
+
+			Convey("It should evict the populated event along with the first", func() {
+				now.advance(90 * time.Second)
+				f.Add(validEvents[0])
+
+				So(f.Events, ShouldResemble, []*p.Event{validEvents[0]})
+				So(f.arrivals, ShouldHaveLength, 1)
+				So(f.total(), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given findings without a window", t, func() {
+		f := new(findings)
+
+		Convey("When adding events", func() {
+			for _, e := range validEvents {
+				f.Add(e)
+			}
+
+			Convey("It should account for every event", func() {
+				So(f.Events, ShouldHaveLength, len(validEvents))
+				So(f.ByProtocol[p.SSH].Occurrence, ShouldEqual, 2)
+
+				s, err := f.report()
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "Jackallava")
+			})
+		})
+	})
+}
+
+func Test_findings_OnEvent(t *testing.T) {
+	Convey("Given findings with an event hook", t, func() {
+		var seen []*p.Event
+		f := &findings{
+			OnEvent: func(e *p.Event) {
+				So(e, ShouldNotBeNil)
+				seen = append(seen, e)
+			},
+		}
+
+		Convey("When adding events", func() {
+			for _, e := range validEvents {
+				f.Add(e)
+			}
+
+			Convey("It should call the hook once per event, in order", func() {
+				So(seen, ShouldResemble, validEvents)
+				So(f.total(), ShouldEqual, len(validEvents))
+			})
+		})
+	})
+}
+
+func Test_findings_AddLowMemory(t *testing.T) {
+	Convey("Given findings in low-memory mode", t, func() {
+		detail := validEvents[1].IP
+		f := &findings{Detail: detail, LowMemory: true}
+
+		Convey("When adding many events", func() {
+			for i := 0; i < 1000; i++ {
+				for _, e := range validEvents {
+					f.Add(e)
+				}
+			}
+
+			Convey("It should retain only the detail submitter's events", func() {
+				So(f.Events, ShouldBeEmpty)
+				So(f.Submitters[detail].Events, ShouldHaveLength, 1000)
+				for ip, item := range f.Submitters {
+					if ip != detail {
+						So(item.Events, ShouldBeEmpty)
+					}
+				}
+			})
+
+			Convey("It should still account for every event", func() {
+				So(f.total(), ShouldEqual, 1000*len(validEvents))
+				So(f.Passwords[p.SSH]["Jackallava"].Occurrence, ShouldEqual, 1000)
+			})
+
+			Convey("It should not allocate when adding further events", func() {
+				allocs := testing.AllocsPerRun(1000, func() { f.Add(validEvents[2]) })
+				So(allocs, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func Test_findings_AddDetailEvents(t *testing.T) {
+	Convey("Given findings detailing one submitter", t, func() {
+		detail := validEvents[1].IP
+		f := &findings{Detail: detail}
+
+		Convey("When adding events", func() {
+			for i := 0; i < 10; i++ {
+				for _, e := range validEvents {
+					f.Add(e)
+				}
+			}
+
+			Convey("It should retain only the detail submitter's events by submitter", func() {
+				So(f.Events, ShouldHaveLength, 10*len(validEvents))
+				So(f.Submitters[detail].Events, ShouldHaveLength, f.Submitters[detail].Occurrence)
+				So(f.Submitters[detail].Events, ShouldNotBeEmpty)
+				for ip, item := range f.Submitters {
+					if ip != detail {
+						So(item.Events, ShouldBeEmpty)
+					}
+				}
+			})
+
+			Convey("It should count every submitter's events as before", func() {
+				counts := make(map[netip.Addr]int)
+				for _, e := range f.Events {
+					counts[e.IP]++
+				}
+				So(f.Submitters, ShouldHaveLength, len(counts))
+				for ip, n := range counts {
+					So(f.Submitters[ip].Occurrence, ShouldEqual, n)
+				}
+
+				populated := &findings{Detail: detail, Events: f.Events}
+				populated.populate()
+				for ip, n := range counts {
+					So(populated.Submitters[ip].Occurrence, ShouldEqual, n)
+				}
+				So(populated.Submitters[detail].Events, ShouldHaveLength, counts[detail])
+			})
+		})
+	})
+}
+
+func Test_findings_skewedEvents(t *testing.T) {
+	Convey("Given findings with version 1 UUID events", t, func() {
+		ts := time.Date(2020, 10, 15, 0, 0, 0, 0, time.UTC)
+		consistent := &p.Event{
+			TimeStamp: uint32(ts.Unix()),
+			EventUUID: v1UUID(ts.Add(time.Second)),
+			Protocol:  p.SSH,
+			IP:        netip.MustParseAddr("1.2.3.4"),
+		}
+		skewed := &p.Event{
+			TimeStamp: uint32(ts.Unix()),
+			EventUUID: v1UUID(ts.Add(2 * time.Hour)),
+			Protocol:  p.SSH,
+			IP:        netip.MustParseAddr("1.2.3.4"),
+		}
+		f := &findings{
+			Events:        []*p.Event{consistent, skewed, validEvents[0]},
+			SkewThreshold: time.Minute,
+		}
+
+		Convey("When populating the findings", func() {
+			f.populate()
+
+			Convey("It should flag only the skewed event", func() {
+				So(f.Skewed, ShouldResemble, []*p.Event{skewed})
+			})
+
+			Convey("It should render the skewed event", func() {
+				s, err := f.skewedEvents(reportFormat{})
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, skewed.EventUUID.String())
+				So(s, ShouldNotContainSubstring, consistent.EventUUID.String())
+			})
+		})
+	})
+}
+
+func Test_findings_unprintablePayloads(t *testing.T) {
+	Convey("Given findings with an event whose password contains a control byte", t, func() {
+		unprintable := &p.Event{
+			Protocol:     p.SSH,
+			IP:           netip.MustParseAddr("1.2.3.4"),
+			Payload:      map[string]string{"username": "root", "password": "hunt\x07er2"},
+			PayloadOrder: []string{"username", "password"},
+		}
+		f := &findings{
+			Events:       []*p.Event{validEvents[0], unprintable, validEvents[1]},
+			CheckCharset: true,
+		}
+
+		Convey("When populating the findings", func() {
+			f.populate()
+
+			Convey("It should flag only the unprintable event", func() {
+				So(f.Unprintable, ShouldResemble, []*p.Event{unprintable})
+			})
+
+			Convey("It should render the payload quoted", func() {
+				s, err := f.unprintablePayloads(reportFormat{})
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, `"username:root,password:hunt\aer2"`)
+				So(s, ShouldContainSubstring, "1.2.3.4")
+				So(s, ShouldNotContainSubstring, validEvents[0].IP.String())
+			})
+		})
+	})
+}
+
+func Test_findings_passwordLengths(t *testing.T) {
+	Convey("Given findings with SSH and TELNET passwords of varying lengths", t, func() {
+		f := new(findings)
+		for _, x := range []struct {
+			proto    p.Protocol
+			password string
+		}{
+			{p.SSH, "root"},
+			{p.SSH, "root"},
+			{p.SSH, "toor"},
+			{p.TELNET, "admin"},
+			{p.TELNET, "x7Gq2LpZ9wRt"},
+			{p.HTTP, "ignored"},
+		} {
+			f.Add(&p.Event{
+				Protocol: x.proto,
+				IP:       netip.MustParseAddr("1.2.3.4"),
+				Payload:  map[string]string{"username": "u", "password": x.password},
+			})
+		}
+
+		Convey("When rendering the password length histogram", func() {
+			s, err := f.passwordLengths(reportFormat{})
+			So(err, ShouldBeNil)
+
+			Convey("It should count the passwords in each length bucket, shortest first", func() {
+				rows := strings.Split(pterm.RemoveColorFromString(s), "\n")
+				So(rows[1], ShouldStartWith, "4      | 3         | 60.0% | "+strings.Repeat("█", histogramBarWidth))
+				So(rows[2], ShouldStartWith, "5      | 1         | 20.0% | "+strings.Repeat("█", histogramBarWidth/3))
+				So(rows[3], ShouldStartWith, "12     | 1         | 20.0% | ")
+				So(rows[4], ShouldStartWith, "TOTAL  | 5 ")
+				So(s, ShouldNotContainSubstring, "7 ")
+			})
+		})
+	})
+
+	Convey("Given findings without passwords", t, func() {
+		f := &findings{Events: validEvents[:1]}
+		f.populate()
+
+		Convey("When rendering the password length histogram", func() {
+			s, err := f.passwordLengths(reportFormat{})
+
+			Convey("It should say no passwords were found", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "PASSWORDS")
+				So(s, ShouldContainSubstring, "FOUND")
+			})
+		})
+	})
+}
+
+func Test_findings_topSubmitters(t *testing.T) {
+	Convey("Given findings with a submitter's events across a span of time", t, func() {
+		ip := netip.MustParseAddr("1.2.3.4")
+		first := time.Date(2020, 10, 1, 8, 0, 0, 0, time.UTC)
+		last := time.Date(2020, 10, 15, 17, 30, 0, 0, time.UTC)
+		f := &findings{
+			Events: []*p.Event{
+				{TimeStamp: uint32(first.Add(time.Hour).Unix()), Protocol: p.SSH, IP: ip},
+				{TimeStamp: uint32(last.Unix()), Protocol: p.SSH, IP: ip},
+				{TimeStamp: uint32(first.Unix()), Protocol: p.SSH, IP: ip},
+			},
+			Location: time.UTC,
+		}
+		f.populate()
+
+		Convey("When calling its span method", func() {
+			actualFirst, actualLast, ok := f.Submitters[ip].span()
+
+			Convey("It should return the first and last time stamps", func() {
+				So(ok, ShouldBeTrue)
+				So(actualFirst, ShouldEqual, uint32(first.Unix()))
+				So(actualLast, ShouldEqual, uint32(last.Unix()))
+			})
+		})
+
+		Convey("When adding an event with a zero time stamp", func() {
+			f.Add(&p.Event{Protocol: p.SSH, IP: ip})
+			f.Add(&p.Event{TimeStamp: uint32(first.Unix()), Protocol: p.SSH, IP: ip})
+			actualFirst, actualLast, ok := f.Submitters[ip].span()
+
+			Convey("It should keep zero as the first time stamp", func() {
+				So(ok, ShouldBeTrue)
+				So(actualFirst, ShouldEqual, 0)
+				So(actualLast, ShouldEqual, uint32(last.Unix()))
+			})
+		})
+
+		Convey("When calling the span method of an item that has seen no time stamps", func() {
+			_, _, ok := (&itemOccurrence{Occurrence: 1}).span()
+
+			Convey("It should report none", func() {
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("When rendering the top submitters", func() {
+			s, err := f.topSubmitters(reportFormat{}, 15)
+
+			Convey("It should include the first and last seen times", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "2020-10-01 08:00:00")
+				So(s, ShouldContainSubstring, "2020-10-15 17:30:00")
+			})
+		})
+	})
+}
+
+func Test_findings_report(t *testing.T) {
+	Convey("Given findings without events", t, func() {
+		f := new(findings)
+
+		Convey("When rendering the report", func() {
+			_, err := f.report()
+
+			Convey("It should return ErrNoEvents", func() {
+				So(errors.Is(err, ErrNoEvents), ShouldBeTrue)
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given findings without SMTP events", t, func() {
+		f := new(findings)
+		for _, e := range validEvents {
+			if e.Protocol != p.SMTP {
+				f.Add(e)
+			}
+		}
+
+		Convey("When rendering the SMTP section", func() {
+			_, err := f.topEmails(reportFormat{}, p.SMTP, 20)
+
+			Convey("It should return ErrMissingProtocol for SMTP", func() {
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+				So(errors.Is(err, ErrNoEvents), ShouldBeFalse)
+
+				var mpe *missingProtocolError
+				So(errors.As(err, &mpe), ShouldBeTrue)
+				So(mpe.Protocol, ShouldEqual, p.SMTP)
+			})
+		})
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+
+			Convey("It should return ErrMissingProtocol for SMTP", func() {
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+				So(errors.Is(err, ErrNoEvents), ShouldBeFalse)
+				So(err.Error(), ShouldEqual, "no SMTP events")
+			})
+
+			Convey("It should render a placeholder for the SMTP section", func() {
+				So(s, ShouldContainSubstring, "NO")
+				So(s, ShouldContainSubstring, "SMTP")
+				So(s, ShouldContainSubstring, "Who are the top 15 subitters?")
+			})
+		})
+	})
+}
+
+func Test_findings_reportSections(t *testing.T) {
+	Convey("Given findings selecting only the passwords section", t, func() {
+		f := &findings{Events: validEvents, Sections: []string{"passwords"}}
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+
+			Convey("It should render only the SSH and TELNET password tables", func() {
+				So(err, ShouldBeNil)
+				So(strings.HasPrefix(s, "\u001B[0mWhat are the top 5 SSH passwords and users?"), ShouldBeTrue)
+				So(s, ShouldContainSubstring, "What are the top 5 TELNET passwords and users?")
+				So(s, ShouldNotContainSubstring, "user-agents")
+				So(s, ShouldNotContainSubstring, "Who are the top 15 subitters?")
+			})
+		})
+	})
+
+	Convey("Given findings selecting submitters before passwords", t, func() {
+		f := &findings{Events: validEvents, Sections: []string{"submitters", "passwords"}}
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+
+			Convey("It should render the sections in the given order", func() {
+				So(err, ShouldBeNil)
+				So(strings.Index(s, "subitters"), ShouldBeLessThan, strings.Index(s, "SSH passwords"))
+			})
+		})
+	})
+
+	Convey("Given findings selecting an unknown section", t, func() {
+		f := &findings{Events: validEvents, Sections: []string{"bogus"}}
+
+		Convey("When rendering the report", func() {
+			_, err := f.report()
+
+			Convey("It should return an error naming the section", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, `"bogus"`)
+			})
+		})
+	})
+}
+
+func Test_findings_formatTime(t *testing.T) {
+	Convey("Given a known event time stamp in UTC", t, func() {
+		const ts = 1602720000 // 2020-10-15 00:00:00 UTC
+
+		for _, tc := range []struct {
+			format   string
+			expected string
+		}{
+			{"", "2020-10-15 00:00:00"},
+			{timeFormatEpoch, "1602720000"},
+			{timeFormatDate, "2020-10-15"},
+			{timeFormatDateTime, "2020-10-15 00:00:00"},
+			{time.RFC3339, "2020-10-15T00:00:00Z"},
+		} {
+			tc := tc
+			Convey(fmt.Sprintf("When formatting it with the %q time format", tc.format), func() {
+				So(checkTimeFormat(tc.format), ShouldBeNil)
+				f := &findings{Location: time.UTC, TimeFormat: tc.format}
+
+				Convey("It should render "+tc.expected, func() {
+					So(f.formatTime(ts), ShouldEqual, tc.expected)
+				})
+			})
+		}
+
+		Convey("When checking a time format without layout elements", func() {
+			err := checkTimeFormat("iso")
+
+			Convey("It should return an error", func() {
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func Test_findings_MaskCredentials(t *testing.T) {
+	Convey("Given findings that mask credentials", t, func() {
+		f := &findings{MaskCredentials: true}
+		for _, e := range validEvents {
+			f.Add(e)
+		}
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+			So(err, ShouldBeNil)
+			s = pterm.RemoveColorFromString(s)
+
+			Convey("It should mask passwords and usernames, keeping their lengths", func() {
+				So(s, ShouldNotContainSubstring, "Jackallava")
+				So(s, ShouldNotContainSubstring, "aiden")
+				So(s, ShouldContainSubstring, strings.Repeat("*", len("Jackallava"))+" ")
+			})
+
+			Convey("It should leave emails and counts intact", func() {
+				So(s, ShouldContainSubstring, "chloesmith263@test.net")
+				So(s, ShouldContainSubstring, "TOTAL SSH EVENTS")
+				So(s, ShouldContainSubstring, "50.0%")
+			})
+		})
+	})
+}
+
+func Test_findings_unknownProtocols(t *testing.T) {
+	Convey("Given findings with an event of a fabricated protocol", t, func() {
+		f := new(findings)
+		for _, e := range validEvents {
+			f.Add(e)
+		}
+		f.Add(&p.Event{
+			Protocol:     0x99,
+			PayloadBytes: []byte("gopher:burrow"),
+			IP:           netip.MustParseAddr("1.2.3.4"),
+		})
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+
+			Convey("It should tally the unknown protocol with a sample payload", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "Which UNKNOWN protocol events arrived?")
+				So(s, ShouldContainSubstring, "0x0099 (153)")
+				So(s, ShouldContainSubstring, `"gopher:burrow"`)
+			})
+		})
+	})
+
+	Convey("Given findings with only known protocols", t, func() {
+		f := &findings{Events: validEvents}
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+
+			Convey("It should omit the unknown protocol section", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldNotContainSubstring, "UNKNOWN")
+			})
+		})
+	})
+}
+
+func Test_findings_reportTheme(t *testing.T) {
+	Convey("Given findings with the cyan theme's label color", t, func() {
+		f := &findings{Events: validEvents, LabelColor: themes["cyan"]}
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+
+			Convey("It should render labels in cyan", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "\u001B[36mWho are the top 15 subitters?")
+				So(s, ShouldNotContainSubstring, "\u001B[32m")
+			})
+		})
+	})
+
+	Convey("Given findings with the mono theme's label color", t, func() {
+		f := &findings{Events: validEvents, LabelColor: themes["mono"]}
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+
+			Convey("It should render labels without color", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "\u001B[0mWho are the top 15 subitters?")
+			})
+		})
+	})
+}
+
+func Test_findings_topAcrossProtocols(t *testing.T) {
+	Convey("Given a password seen in both SSH and TELNET events", t, func() {
+		f := &findings{AcrossProtocols: []string{"password"}}
+		for _, e := range []*p.Event{
+			{Protocol: p.SSH, Payload: map[string]string{"password": "hunter2"}},
+			{Protocol: p.SSH, Payload: map[string]string{"password": "hunter2"}},
+			{Protocol: p.TELNET, Payload: map[string]string{"password": "hunter2"}},
+			{Protocol: p.TELNET, Payload: map[string]string{"password": "letmein"}},
+		} {
+			f.Add(e)
+		}
+
+		Convey("When merging the passwords across protocols", func() {
+			m := f.mergedPayloadMap("password")
+
+			Convey("It should sum the password's occurrences", func() {
+				So(m, ShouldHaveLength, 2)
+				So(m["hunter2"].Occurrence, ShouldEqual, 3)
+				So(m["letmein"].Occurrence, ShouldEqual, 1)
+				So(f.Passwords[p.SSH]["hunter2"].Occurrence, ShouldEqual, 2) // unmodified
+			})
+		})
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+			s = pterm.RemoveColorFromString(s)
+
+			Convey("It should rank the passwords across all protocols", func() {
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+				i := strings.Index(s, "What are the top 10 passwords across all protocols?")
+				So(i, ShouldBeGreaterThan, -1)
+				section := s[i:]
+				So(strings.Index(section, "hunter2"), ShouldBeLessThan, strings.Index(section, "letmein"))
+				So(section, ShouldContainSubstring, "75.0%")
+			})
+		})
+
+		Convey("When ranking a key the findings don't aggregate", func() {
+			_, err := f.topAcrossProtocols(reportFormat{}, "color", 10)
+
+			Convey("It should return an error", func() {
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func Test_findings_classifications(t *testing.T) {
+	Convey("Given findings with numeric and alphabetic passwords and a bot user-agent", t, func() {
+		f := &findings{Classify: true}
+		for _, e := range []*p.Event{
+			{Protocol: p.SSH, Payload: map[string]string{"password": "123456"}},
+			{Protocol: p.TELNET, Payload: map[string]string{"password": "123456"}},
+			{Protocol: p.SSH, Payload: map[string]string{"password": "000000"}},
+			{Protocol: p.SSH, Payload: map[string]string{"password": "dragon"}},
+			{Protocol: p.HTTP, Payload: map[string]string{"user-agent": "curl/7.68.0"}},
+		} {
+			f.Add(e)
+		}
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+			s = pterm.RemoveColorFromString(s)
+
+			Convey("It should break down the values by classification", func() {
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+				i := strings.Index(s, "How are payload values classified?")
+				So(i, ShouldBeGreaterThan, -1)
+
+				var rows [][]string
+				for _, line := range strings.Split(s[i:], "\n")[2:] {
+					if fields := strings.Fields(strings.ReplaceAll(line, "|", " ")); len(fields) == 4 {
+						rows = append(rows, fields)
+					}
+				}
+				So(rows, ShouldContain, []string{"password", "numeric", "3", "75.0%"})
+				So(rows, ShouldContain, []string{"password", "alpha", "1", "25.0%"})
+				So(rows, ShouldContain, []string{"user-agent", "bot-ua", "1", "100.0%"})
+			})
+		})
+	})
+}
+
+func Test_findings_cardinality(t *testing.T) {
+	Convey("Given findings with repeated passwords, usernames, and emails", t, func() {
+		f := new(findings)
+		for _, e := range []*p.Event{
+			{Protocol: p.SSH, Payload: map[string]string{"username": "root", "password": "123456"}},
+			{Protocol: p.SSH, Payload: map[string]string{"username": "root", "password": "admin"}},
+			{Protocol: p.SSH, Payload: map[string]string{"username": "pi", "password": "123456"}},
+			{Protocol: p.SSH, Payload: map[string]string{"username": "root", "password": "hunter2"}},
+			{Protocol: p.TELNET, Payload: map[string]string{"username": "admin", "password": "admin"}},
+			{Protocol: p.SMTP, Payload: map[string]string{"email": "a@example.com"}},
+			{Protocol: p.SMTP, Payload: map[string]string{"email": "a@example.com"}},
+		} {
+			f.Add(e)
+		}
+
+		Convey("When rendering the cardinality", func() {
+			s, err := f.cardinality(reportFormat{})
+			s = pterm.RemoveColorFromString(s)
+
+			Convey("It should count the unique values and occurrences of each key by protocol", func() {
+				So(err, ShouldBeNil)
+
+				var rows [][]string
+				for _, line := range strings.Split(s, "\n")[1:] {
+					if fields := strings.Fields(strings.ReplaceAll(line, "|", " ")); len(fields) == 5 {
+						rows = append(rows, fields)
+					}
+				}
+				So(rows, ShouldResemble, [][]string{
+					{"SMTP", "email", "1", "2", "50.0%"},
+					{"SSH", "password", "3", "4", "75.0%"},
+					{"SSH", "username", "2", "4", "50.0%"},
+					{"TELNET", "password", "1", "1", "100.0%"},
+					{"TELNET", "username", "1", "1", "100.0%"},
+				})
+			})
+		})
+
+		Convey("When rendering the report", func() {
+			without, _ := f.report()
+			f.Cardinality = true
+			with, err := f.report()
+
+			Convey("It should include the cardinality section only given Cardinality", func() {
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+				So(without, ShouldNotContainSubstring, "How many distinct payload values were seen?")
+				So(with, ShouldContainSubstring, "How many distinct payload values were seen?")
+			})
+		})
+	})
+}
+
+func Test_findings_topology(t *testing.T) {
+	Convey("Given findings with events across nodes and protocols", t, func() {
+		f := &findings{Topology: true}
+		for _, e := range []*p.Event{
+			{NodeID: 2, Protocol: p.SSH},
+			{NodeID: 2, Protocol: p.SSH},
+			{NodeID: 2, Protocol: p.TELNET},
+			{NodeID: 10, Protocol: p.HTTP},
+			{NodeID: 4, Protocol: p.SSH},
+		} {
+			f.Add(e)
+		}
+
+		Convey("When tallying the protocols of each node", func() {
+			Convey("It should count each node's events by protocol", func() {
+				So(f.ProtocolsByNode, ShouldResemble, map[uint16]map[p.Protocol]int{
+					2:  {p.SSH: 2, p.TELNET: 1},
+					4:  {p.SSH: 1},
+					10: {p.HTTP: 1},
+				})
+			})
+		})
+
+		Convey("When rendering the topology matrix", func() {
+			s, err := f.topology(reportFormat{})
+			So(err, ShouldBeNil)
+
+			var rows [][]string
+			for _, line := range strings.Split(pterm.RemoveColorFromString(s), "\n") {
+				rows = append(rows, strings.Fields(strings.ReplaceAll(line, "|", " ")))
+			}
+
+			Convey("It should cross-tabulate nodes against protocols with totals", func() {
+				So(rows[0], ShouldResemble, []string{"Node", "ID", "HTTP", "SSH", "TELNET", "Total"})
+				So(rows[1], ShouldResemble, []string{"2", "0", "2", "1", "3"})
+				So(rows[2], ShouldResemble, []string{"4", "0", "1", "0", "1"})
+				So(rows[3], ShouldResemble, []string{"10", "1", "0", "0", "1"})
+			})
+		})
+	})
+}
+
+func Test_findings_subnetOccurrences(t *testing.T) {
+	Convey("Given findings with submitters sharing a /24 subnet", t, func() {
+		ts := uint32(time.Date(2020, 10, 15, 0, 0, 0, 0, time.UTC).Unix())
+		f := &findings{ClusterPrefix: 24, Location: time.UTC}
+		for _, ip := range []string{"10.1.2.3", "10.1.2.77", "10.1.2.200", "10.1.3.4"} {
+			f.Add(&p.Event{TimeStamp: ts, Protocol: p.SSH, IP: netip.MustParseAddr(ip)})
+		}
+		f.Add(&p.Event{TimeStamp: ts, Protocol: p.SSH, IP: netip.MustParseAddr("10.1.2.3")})
+
+		Convey("When aggregating submitters by subnet", func() {
+			m, err := f.subnetOccurrences()
+
+			Convey("It should sum the submitters' counts within each subnet", func() {
+				So(err, ShouldBeNil)
+				So(m, ShouldHaveLength, 2)
+				So(m["10.1.2.0/24"].Occurrence, ShouldEqual, 4)
+				So(m["10.1.3.0/24"].Occurrence, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When rendering the top subnets", func() {
+			s, err := f.topSubnets(reportFormat{}, 15)
+
+			Convey("It should list the subnets", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "10.1.2.0/24")
+				So(s, ShouldContainSubstring, "10.1.3.0/24")
+			})
+		})
+	})
+
+	Convey("Given findings with IPv4 and IPv6 submitters", t, func() {
+		ts := uint32(time.Date(2020, 10, 15, 0, 0, 0, 0, time.UTC).Unix())
+		f := &findings{ClusterPrefix: 24, ClusterPrefix6: 64, Location: time.UTC}
+		for _, ip := range []string{"10.1.2.3", "2001:db8:0:1::1", "2001:db8:0:1::2", "2001:db8:0:2::1"} {
+			f.Add(&p.Event{TimeStamp: ts, Protocol: p.SSH, IP: netip.MustParseAddr(ip)})
+		}
+
+		Convey("When aggregating submitters by subnet", func() {
+			m, err := f.subnetOccurrences()
+
+			Convey("It should cluster each family by its own prefix length", func() {
+				So(err, ShouldBeNil)
+				So(m, ShouldHaveLength, 3)
+				So(m["10.1.2.0/24"].Occurrence, ShouldEqual, 1)
+				So(m["2001:db8:0:1::/64"].Occurrence, ShouldEqual, 2)
+				So(m["2001:db8:0:2::/64"].Occurrence, ShouldEqual, 1)
+				So(f.subnetsLabel(), ShouldEqual, "Which /24 IPv4 and /64 IPv6 subnets are most active?")
+			})
+		})
+
+		Convey("When aggregating them without an IPv6 prefix length", func() {
+			f.ClusterPrefix6 = 0
+			m, err := f.subnetOccurrences()
+
+			Convey("It should leave out the IPv6 submitters", func() {
+				So(err, ShouldBeNil)
+				So(m, ShouldHaveLength, 1)
+				So(f.subnetsLabel(), ShouldEqual, "Which /24 subnets are most active?")
+			})
+		})
+	})
+}
+
+func Test_findings_sizeStatsByProtocol(t *testing.T) {
+	Convey("Given findings with mixed-protocol events", t, func() {
+		f := new(findings)
+		for _, e := range []*p.Event{
+			{Protocol: p.HTTP, Size: 100},
+			{Protocol: p.HTTP, Size: 300},
+			{Protocol: p.SSH, Size: 20},
+		} {
+			f.Add(e)
+		}
+
+		Convey("When rendering the payload sizes by protocol", func() {
+			s, err := f.sizeStatsByProtocol(reportFormat{})
+
+			Convey("It should render a row per protocol", func() {
+				So(err, ShouldBeNil)
+
+				var http, ssh string
+				for _, line := range strings.Split(pterm.RemoveColorFromString(s), "\n") {
+					switch {
+					case strings.Contains(line, "HTTP"):
+						http = line
+					case strings.Contains(line, "SSH"):
+						ssh = line
+					}
+				}
+				So(strings.Fields(strings.ReplaceAll(http, "|", " ")), ShouldResemble,
+					[]string{"HTTP", "2", "100", "200.0", "300", "300"})
+				So(strings.Fields(strings.ReplaceAll(ssh, "|", " ")), ShouldResemble,
+					[]string{"SSH", "1", "20", "20.0", "20", "20"})
+			})
+		})
+
+		Convey("When rendering the report", func() {
+			without, _ := f.report()
+			f.PayloadSizes = true
+			with, _ := f.report()
+
+			Convey("It should include the payload sizes only given PayloadSizes", func() {
+				So(without, ShouldNotContainSubstring, "What are the payload sizes by protocol?")
+				So(with, ShouldContainSubstring, "What are the payload sizes by protocol?")
+			})
+		})
+	})
+}
+
+func Test_findings_latencyStats(t *testing.T) {
+	Convey("Given findings with received events", t, func() {
+		f := new(findings)
+		sent := time.Unix(1_000_000, 0)
+		for _, d := range []time.Duration{time.Second, 3 * time.Second} {
+			f.Add(&p.Event{Protocol: p.SSH, TimeStamp: uint32(sent.Unix()), ReceivedAt: sent.Add(d)})
+		}
+		f.Add(&p.Event{Protocol: p.SSH, TimeStamp: uint32(sent.Unix())})
+
+		Convey("When rendering the receive latencies", func() {
+			s, err := f.latencyStats(reportFormat{})
+
+			Convey("It should summarize only the events with receive times", func() {
+				So(err, ShouldBeNil)
+				So(f.Latency.Count, ShouldEqual, 2)
+
+				lines := strings.Split(strings.TrimSpace(pterm.RemoveColorFromString(s)), "\n")
+				So(lines, ShouldHaveLength, 2)
+				So(strings.Fields(strings.ReplaceAll(lines[1], "|", " ")), ShouldResemble,
+					[]string{"2", "1s", "2s", "3s", "3s"})
+			})
+		})
+	})
+}
+
+func Test_itemOccurrenceMap_top(t *testing.T) {
+	Convey("Given many items with tied occurrences", t, func() {
+		m := syntheticItems(rand.New(rand.NewSource(1)), 5000)
+
+		Convey("When selecting the top items", func() {
+			Convey("It should match a full sort for any count", func() {
+				sorted := make(itemOccurrences, 0, len(m))
+				for _, item := range m {
+					sorted = append(sorted, item)
+				}
+				sort.Sort(sorted)
+
+				for _, count := range []int{1, 30, 100, len(m)} {
+					So(m.top(count), ShouldResemble, sorted[:count])
+				}
+			})
+
+			Convey("It should pad with empty items given a count exceeding the items", func() {
+				top := m.top(len(m) + 2)
+				So(top, ShouldHaveLength, len(m)+2)
+				So(top[len(m)].Occurrence, ShouldEqual, 0)
+				So(top[len(m)+1].Occurrence, ShouldEqual, 0)
+			})
+
+			Convey("It should return no items given a count of 0", func() {
+				So(m.top(0), ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func Benchmark_itemOccurrenceMap_top(b *testing.B) {
+	m := syntheticItems(rand.New(rand.NewSource(1)), 1_000_000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = m.top(30)
+	}
+}
+
+// syntheticItems returns n distinct items with occurrences drawn from a small
+// range, so many tie.
+func syntheticItems(rng *rand.Rand, n int) itemOccurrenceMap {
+	m := make(itemOccurrenceMap, n)
+	for i := 0; i < n; i++ {
+		item := fmt.Sprintf("Mozilla/5.0 (%d)", i)
+		m[item] = &itemOccurrence{Item: item, Occurrence: rng.Intn(100)}
+	}
+
+	return m
+}
+
+func Test_findings_TopPasswords(t *testing.T) {
+	Convey("Given findings with SSH passwords", t, func() {
+		f := new(findings)
+		for _, password := range []string{"123456", "admin", "123456"} {
+			f.Add(&p.Event{
+				Protocol: p.SSH,
+				Payload:  map[string]string{"password": password, "username": "root"},
+			})
+		}
+
+		Convey("When getting the top passwords", func() {
+			counts, err := f.TopPasswords(p.SSH, 3)
+
+			Convey("They should be ranked by count and padded to the count", func() {
+				So(err, ShouldBeNil)
+				So(counts, ShouldResemble, []ItemCount{
+					{Item: "123456", Count: 2},
+					{Item: "admin", Count: 1},
+					{},
+				})
+			})
+		})
+
+		Convey("When getting the top passwords of a protocol without any", func() {
+			_, err := f.TopPasswords(p.TELNET, 3)
+
+			Convey("It should report the missing protocol", func() {
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func Test_findings_topUserAgentsWidth(t *testing.T) {
+	Convey("Given findings with long user-agents and a narrow terminal", t, func() {
+		const width = 80
+
+		f := new(findings)
+		for i := 0; i < 3; i++ {
+			f.Add(&p.Event{
+				Protocol: p.HTTP,
+				Payload: map[string]string{
+					"user-agent": fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; WOW64) AppleWebKit/537.36 "+
+						"(KHTML, like Gecko) Chrome/60.0.3112.78 Safari/537.36 OPR/47.0.2631.%d", i),
+				},
+			})
+		}
+
+		Convey("When rendering the user-agents", func() {
+			s, err := f.topUserAgents(reportFormat{width: width}, p.HTTP, 30)
+
+			Convey("It should truncate them to fit the width", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "...")
+				for _, line := range strings.Split(pterm.RemoveColorFromString(s), "\n") {
+					So(utf8.RuneCountInString(line), ShouldBeLessThanOrEqualTo, width)
+				}
+			})
+		})
+
+		Convey("When rendering the user-agents without a width", func() {
+			s, err := f.topUserAgents(reportFormat{}, p.HTTP, 30)
+
+			Convey("It should render them in full", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "OPR/47.0.2631.2")
+			})
+		})
+	})
+}
+
+func Test_sparkline(t *testing.T) {
+	Convey("Given time stamps spread over time", t, func() {
+		timestamps := []uint32{100, 100, 100, 150, 199}
+
+		Convey("When rendering a sparkline", func() {
+			s := sparkline(timestamps, 10)
+
+			Convey("It should render a block per bucket", func() {
+				So(utf8.RuneCountInString(s), ShouldEqual, 10)
+				So(s, ShouldEqual, "█    ▃   ▃")
+			})
+		})
+	})
+
+	Convey("Given a single time stamp", t, func() {
+		Convey("When rendering a sparkline", func() {
+			s := sparkline([]uint32{100}, 10)
+
+			Convey("It should render a single block", func() {
+				So(s, ShouldEqual, "█")
+			})
+		})
+	})
+
+	Convey("Given a submitter's events", t, func() {
+		f := &findings{Events: validEvents}
+		f.populate()
+
+		Convey("When rendering the submitter detail", func() {
+			s, err := f.submitter(reportFormat{}, validEvents[0].IP)
+
+			Convey("It should render the activity sparkline above the table", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldStartWith, "Activity: ")
+			})
+		})
+	})
+}
+
+func Test_findings_hours(t *testing.T) {
+	Convey("Given a submitter's events spread across a few hours", t, func() {
+		ip := netip.MustParseAddr("1.2.3.4")
+		day := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+		f := &findings{Detail: ip, Location: time.UTC}
+		for _, offset := range []time.Duration{
+			8*time.Hour + 5*time.Minute,
+			8*time.Hour + 55*time.Minute,
+			9 * time.Hour,
+			24*time.Hour + 17*time.Hour,
+			17*time.Hour + 10*time.Minute,
+			17*time.Hour + 20*time.Minute,
+			17*time.Hour + 30*time.Minute,
+		} {
+			f.Add(&p.Event{Protocol: p.SSH, IP: ip, TimeStamp: uint32(day.Add(offset).Unix())})
+		}
+
+		Convey("When bucketing their time stamps by hour of the day", func() {
+			timestamps := make([]uint32, 0, len(f.Submitters[ip].Events))
+			for _, e := range f.Submitters[ip].Events {
+				timestamps = append(timestamps, e.TimeStamp)
+			}
+			counts := f.hours(timestamps)
+
+			Convey("It should count the events in each hour in the findings' time zone", func() {
+				var expected [24]int
+				expected[8], expected[9], expected[17] = 2, 1, 4
+				So(counts, ShouldResemble, expected)
+				So(diurnal(counts), ShouldEqual, "        ▄▂       █      ")
+			})
+
+			Convey("It should shift the hours with the time zone", func() {
+				f.Location = time.FixedZone("UTC+2", 2*60*60)
+				counts := f.hours(timestamps)
+				So(counts[10], ShouldEqual, 2)
+				So(counts[19], ShouldEqual, 4)
+			})
+
+			Convey("It should render the hours in the submitter detail", func() {
+				s, err := f.submitter(reportFormat{}, ip)
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "\nHours:    "+diurnal(counts)+" (00-23 UTC)\n")
+			})
+		})
+	})
+
+	Convey("Given time stamps within a single hour", t, func() {
+		f := &findings{Location: time.UTC}
+		ts := uint32(time.Date(2020, 10, 1, 23, 15, 0, 0, time.UTC).Unix())
+
+		Convey("When rendering their hours of the day", func() {
+			s := diurnal(f.hours([]uint32{ts, ts + 60, ts + 120}))
+
+			Convey("It should render a single full block in the last hour", func() {
+				So(utf8.RuneCountInString(s), ShouldEqual, 24)
+				So(s, ShouldEqual, strings.Repeat(" ", 23)+"█")
+			})
+		})
+	})
+}
+
+func Test_thousands(t *testing.T) {
+	Convey("Given counts of varying magnitude", t, func() {
+		Convey("When calling the thousands function", func() {
+			Convey("It should separate groups of three digits with commas", func() {
+				So(thousands(0), ShouldEqual, "0")
+				So(thousands(999), ShouldEqual, "999")
+				So(thousands(1000), ShouldEqual, "1,000")
+				So(thousands(1234567), ShouldEqual, "1,234,567")
+				So(thousands(-1234567), ShouldEqual, "-1,234,567")
+			})
+		})
+	})
+
+	Convey("Given findings with a submitter of many events", t, func() {
+		f := &findings{Submitters: map[netip.Addr]*itemOccurrence{
+			netip.MustParseAddr("10.0.0.1"): {Item: "10.0.0.1", Occurrence: 1234567},
+		}}
+
+		Convey("When rendering the top submitters", func() {
+			s, err := f.topSubmitters(reportFormat{}, 15)
+
+			Convey("It should render the total with thousands separators", func() {
+				So(err, ShouldBeNil)
+				So(pterm.RemoveColorFromString(s), ShouldContainSubstring, "1,234,567")
+			})
+		})
+	})
+}
+
+func Test_percent(t *testing.T) {
+	Convey("Given counts and totals", t, func() {
+		Convey("When calling the percent function", func() {
+			Convey("It should format to one decimal place", func() {
+				So(percent(1, 3), ShouldEqual, "33.3%")
+				So(percent(2, 3), ShouldEqual, "66.7%")
+				So(percent(3, 3), ShouldEqual, "100.0%")
+			})
+
+			Convey("It should not divide by a zero total", func() {
+				So(percent(0, 0), ShouldEqual, "0.0%")
+			})
+		})
+	})
+
+	Convey("Given findings with every valid event", t, func() {
+		f := new(findings)
+		for _, e := range validEvents {
+			f.Add(e)
+		}
+
+		Convey("When summing the submitters' percentages", func() {
+			var sum float64
+			for _, item := range f.submitterOccurrences().top(len(f.Submitters)) {
+				v, err := strconv.ParseFloat(strings.TrimSuffix(percent(item.Occurrence, f.total()), "%"), 64)
+				So(err, ShouldBeNil)
+				sum += v
+			}
+
+			Convey("It should total 100 percent, give or take rounding", func() {
+				So(sum, ShouldAlmostEqual, 100, 0.05*float64(len(f.Submitters)))
+			})
+		})
+	})
+}
+
+// v1UUID returns a version 1 UUID embedding the given time.
+func v1UUID(t time.Time) p.UUID {
+	ts := uint64(t.UnixNano()/100) + 0x01b21dd213814000
+
+	return p.UUID{
+		TimeLow:          uint32(ts),
+		TimeMid:          uint16(ts >> 32),
+		TimeHiAndVersion: uint16(ts>>48)&0x0fff | 0x1000,
+		ClockSeqHiAndRes: 0x80,
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultOutputBuffer is the default size of the buffer batching output writes.
+const defaultOutputBuffer = 64 << 10
+
+var (
+	// outputBufferSize is the size of the buffer batching writes to export
+	// files and streamed output, as -output-buffer sets. Zero writes through
+	// unbuffered.
+	outputBufferSize = defaultOutputBuffer
+
+	// flushInterval is how often buffered output is flushed, as
+	// -flush-interval sets. Zero flushes only once the buffer fills and when
+	// the output is closed.
+	flushInterval = time.Second
+)
+
+// flushWriter batches writes to w in a buffer, sparing a system call per event,
+// and flushes the buffer periodically, so output streamed to a consumer doesn't
+// stall, and when closed, so nothing is lost on exit. It's safe for concurrent
+// use.
+type flushWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+	bw *bufio.Writer // nil when unbuffered
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// newFlushWriter returns a writer buffering up to size bytes before writing
+// them to w, and flushing every interval. A size of zero or less writes
+// through to w unbuffered. An interval of zero or less flushes only when the
+// buffer fills and upon Close.
+func newFlushWriter(w io.Writer, size int, interval time.Duration) *flushWriter {
+	fw := &flushWriter{w: w, stop: make(chan struct{})}
+	if size <= 0 {
+		return fw
+	}
+	fw.bw = bufio.NewWriterSize(w, size)
+
+	if interval > 0 {
+		fw.wg.Add(1)
+		go func() {
+			defer fw.wg.Done()
+
+			t := time.NewTicker(interval)
+			defer t.Stop()
+
+			for {
+				select {
+				case <-fw.stop:
+					return
+				case <-t.C:
+					// The buffer retains a failed flush's error, which the
+					// next Write or Flush returns.
+					_ = fw.Flush()
+				}
+			}
+		}()
+	}
+
+	return fw
+}
+
+// Write implements the io.Writer interface.
+func (fw *flushWriter) Write(b []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if fw.bw == nil {
+		return fw.w.Write(b)
+	}
+
+	return fw.bw.Write(b)
+}
+
+// Flush writes the buffered bytes to the underlying writer.
+func (fw *flushWriter) Flush() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if fw.bw == nil {
+		return nil
+	}
+
+	return fw.bw.Flush()
+}
+
+// Close stops the periodic flushes and flushes the buffer a final time. It
+// doesn't close the underlying writer.
+func (fw *flushWriter) Close() error {
+	fw.once.Do(func() { close(fw.stop) })
+	fw.wg.Wait()
+
+	return fw.Flush()
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// syncBuffer is a bytes.Buffer safe for a flushWriter's periodic flushes to
+// write while the test reads.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.buf.Write(b)
+}
+
+func (s *syncBuffer) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.buf.Len()
+}
+
+func Test_flushWriter(t *testing.T) {
+	Convey("Given events written to a buffered writer that never flushes periodically", t, func() {
+		var want bytes.Buffer
+		_, err := archiveEvents(&want, validEvents, 0, 0)
+		So(err, ShouldBeNil)
+
+		var got bytes.Buffer
+		fw := newFlushWriter(&got, 1<<20, 0)
+		_, err = archiveEvents(fw, validEvents, 0, 0)
+		So(err, ShouldBeNil)
+
+		Convey("When shutting it down", func() {
+			So(got.Len(), ShouldEqual, 0)
+			So(fw.Close(), ShouldBeNil)
+
+			Convey("It should flush every event intact", func() {
+				So(got.Bytes(), ShouldResemble, want.Bytes())
+
+				var events []*p.Event
+				er := p.NewEventReader(&got)
+				for {
+					e, err := er.Read()
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					So(err, ShouldBeNil)
+					events = append(events, e)
+				}
+				So(events, ShouldHaveLength, len(validEvents))
+			})
+
+			Convey("It should tolerate closing again", func() {
+				So(fw.Close(), ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given a buffered writer flushing every few milliseconds", t, func() {
+		got := new(syncBuffer)
+		fw := newFlushWriter(got, 1<<20, 5*time.Millisecond)
+		defer func() { _ = fw.Close() }()
+
+		Convey("When writing to it without closing it", func() {
+			_, err := fw.Write([]byte("uuid\n"))
+			So(err, ShouldBeNil)
+
+			Convey("It should flush the write soon after", func() {
+				deadline := time.Now().Add(time.Second)
+				for got.Len() == 0 && time.Now().Before(deadline) {
+					time.Sleep(time.Millisecond)
+				}
+				So(got.Len(), ShouldEqual, len("uuid\n"))
+			})
+		})
+	})
+
+	Convey("Given an unbuffered writer", t, func() {
+		var got bytes.Buffer
+		fw := newFlushWriter(&got, 0, time.Millisecond)
+
+		Convey("When writing to it", func() {
+			_, err := fw.Write([]byte("uuid\n"))
+
+			Convey("It should write through immediately", func() {
+				So(err, ShouldBeNil)
+				So(got.String(), ShouldEqual, "uuid\n")
+				So(fw.Close(), ShouldBeNil)
+			})
+		})
+	})
+}
+
+func Benchmark_writeEventsFile(b *testing.B) {
+	events := make([]*p.Event, 0, 1000)
+	for len(events) < cap(events) {
+		events = append(events, validEvents...)
+	}
+	path := filepath.Join(b.TempDir(), "events.bin")
+
+	for _, bm := range []struct {
+		name    string
+		size    int
+		retries int
+	}{
+		{"unbuffered", 0, 0},
+		{"buffered", defaultOutputBuffer, 0},
+		{"unbuffered retrying", 0, defaultArchiveRetries},
+		{"buffered retrying", defaultOutputBuffer, defaultArchiveRetries},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			size := outputBufferSize
+			outputBufferSize = bm.size
+			defer func() { outputBufferSize = size }()
+
+			for i := 0; i < b.N; i++ {
+				if err := writeEventsFile(path, events, bm.retries); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
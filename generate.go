@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math/rand"
+	"net/netip"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// Synthetic payload values, chosen to resemble what the event server emits.
+var (
+	genEmails     = []string{"alice@example.com", "bob@example.net", "chloesmith263@test.net", "root@localhost"}
+	genPasswords  = []string{"123456", "admin", "hunter2", "letmein", "password"}
+	genProtocols  = []p.Protocol{p.HTTP, p.SMTP, p.SSH, p.TELNET}
+	genUserAgents = []string{"Go-http-client/1.1", "Mozilla/5.0 (X11; Linux x86_64)", "curl/7.68.0", "python-requests/2.25.1"}
+	genUsernames  = []string{"admin", "oracle", "pi", "root", "ubuntu"}
+)
+
+// generateEvents returns n synthetic, valid events with time stamps starting at
+// ts, drawing their fields from rng.
+func generateEvents(rng *rand.Rand, n int, ts uint32) []*p.Event {
+	events := make([]*p.Event, 0, n)
+	for i := 0; i < n; i++ {
+		events = append(events, generateEvent(rng, ts+uint32(i)))
+	}
+
+	return events
+}
+
+// generateEvent returns a synthetic, valid event with the given time stamp,
+// drawing its remaining fields from rng.
+func generateEvent(rng *rand.Rand, ts uint32) *p.Event {
+	e := &p.Event{
+		NodeID:    uint16(rng.Intn(1 << 16)),
+		TimeStamp: ts,
+		EventUUID: p.UUID{
+			TimeLow:          rng.Uint32(),
+			TimeMid:          uint16(rng.Uint32()),
+			TimeHiAndVersion: uint16(rng.Uint32())&0x0fff | 0x4000, // version 4
+			ClockSeqHiAndRes: uint8(rng.Uint32())&0x3f | 0x80,      // RFC 4122 variant
+			ClockSeqLow:      uint8(rng.Uint32()),
+		},
+		Protocol:  genProtocols[rng.Intn(len(genProtocols))],
+		Submitter: rng.Uint32(),
+	}
+	_, _ = rng.Read(e.EventUUID.Node[:])
+
+	switch e.Protocol {
+	case p.HTTP:
+		e.Payload = map[string]string{"user-agent": pick(rng, genUserAgents)}
+		e.PayloadOrder = []string{"user-agent"}
+	case p.SMTP:
+		e.Payload = map[string]string{"email": pick(rng, genEmails)}
+		e.PayloadOrder = []string{"email"}
+	default:
+		e.Payload = map[string]string{
+			"password": pick(rng, genPasswords),
+			"username": pick(rng, genUsernames),
+		}
+		e.PayloadOrder = []string{"username", "password"}
+	}
+	e.PayloadBytes = []byte(e.FormatPayload())
+	e.Size = uint16(len(e.PayloadBytes))
+
+	var addr [4]byte
+	binary.BigEndian.PutUint32(addr[:], e.Submitter)
+	e.IP = netip.AddrFrom4(addr)
+
+	b, _ := e.MarshalBinary()
+	e.CheckSum = crc32.ChecksumIEEE(b[:len(b)-4])
+
+	return e
+}
+
+// pick returns a random element of s.
+func pick(rng *rand.Rand, s []string) string { return s[rng.Intn(len(s))] }
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// unknownCountry tallies submitters whose countries don't resolve.
+const unknownCountry = "UNKNOWN"
+
+// geoResolver resolves an IP address to its country code. It returns an empty
+// string if the address's country is unknown.
+type geoResolver interface {
+	Country(netip.Addr) (string, error)
+}
+
+// geoCache caches a geoResolver's answers, including unknown countries, so
+// each address is resolved at most once.
+type geoCache struct {
+	resolver  geoResolver
+	countries map[netip.Addr]string
+}
+
+func newGeoCache(resolver geoResolver) *geoCache {
+	return &geoCache{resolver: resolver, countries: make(map[netip.Addr]string)}
+}
+
+// Country implements the geoResolver interface.
+func (g *geoCache) Country(addr netip.Addr) (string, error) {
+	if country, ok := g.countries[addr]; ok {
+		return country, nil
+	}
+
+	country, err := g.resolver.Country(addr)
+	if err != nil {
+		return "", err
+	}
+	g.countries[addr] = country
+
+	return country, nil
+}
+
+// geoTable resolves countries by the longest network prefix containing an
+// address.
+type geoTable []geoNetwork
+
+type geoNetwork struct {
+	Prefix  netip.Prefix
+	Country string
+}
+
+// Country implements the geoResolver interface.
+func (t geoTable) Country(addr netip.Addr) (string, error) {
+	addr = addr.Unmap()
+	for _, n := range t {
+		if n.Prefix.Contains(addr) {
+			return n.Country, nil
+		}
+	}
+
+	return "", nil
+}
+
+// readGeoIP reads network and country code pairs, one pair per CSV record,
+// such as "203.0.113.0/24,AU". Lines beginning with # are ignored.
+func readGeoIP(r io.Reader) (geoTable, error) {
+	c := csv.NewReader(r)
+	c.Comment = '#'
+	c.FieldsPerRecord = 2
+	c.TrimLeadingSpace = true
+
+	var t geoTable
+	for {
+		rec, err := c.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(rec[0]))
+		if err != nil {
+			line, _ := c.FieldPos(0)
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		t = append(t, geoNetwork{Prefix: prefix.Masked(), Country: strings.ToUpper(strings.TrimSpace(rec[1]))})
+	}
+
+	// Check the most specific networks first.
+	sort.SliceStable(t, func(i, j int) bool { return t[i].Prefix.Bits() > t[j].Prefix.Bits() })
+
+	return t, nil
+}
+
+// readGeoIPFile reads the networks and country codes in the file at path.
+func readGeoIPFile(path string) (geoTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return readGeoIP(f)
+}
+
+// countryOccurrences returns the submitters aggregated by their countries.
+func (f *findings) countryOccurrences() (itemOccurrenceMap, error) {
+	m := make(itemOccurrenceMap)
+	for addr, v := range f.Submitters {
+		country, err := f.Geo.Country(addr)
+		if err != nil {
+			return nil, fmt.Errorf("resolving the country of submitter %s: %w", addr, err)
+		}
+		if country == "" {
+			country = unknownCountry
+		}
+
+		item := m[country]
+		if item == nil {
+			item = &itemOccurrence{Item: country}
+			m[country] = item
+		}
+		item.Occurrence += v.Occurrence
+		if first, last, ok := v.span(); ok {
+			item.see(first)
+			item.see(last)
+		}
+	}
+
+	return m, nil
+}
+
+// topCountries renders the count countries submitting the most events.
+func (f *findings) topCountries(rf reportFormat, count int) (string, error) {
+	m, err := f.countryOccurrences()
+	if err != nil {
+		return "", err
+	}
+
+	totalEvents := 0
+	for _, v := range m {
+		totalEvents += v.Occurrence
+	}
+
+	d := pterm.TableData{{"#", "Country", "Count", "%", "First Seen", "Last Seen"}}
+	for i, item := range m.top(count) {
+		var firstSeen, lastSeen string
+		if first, last, ok := item.span(); ok {
+			firstSeen = f.formatTime(first)
+			lastSeen = f.formatTime(last)
+		}
+
+		d = append(d,
+			[]string{
+				strconv.Itoa(i + 1),
+				item.Item,
+				strconv.Itoa(item.Occurrence),
+				percent(item.Occurrence, totalEvents),
+				firstSeen,
+				lastSeen,
+			},
+		)
+	}
+	d = append(d,
+		[]string{
+			"",
+			pterm.DefaultTable.HeaderStyle.Sprint("TOTAL EVENTS"),
+			pterm.DefaultTable.HeaderStyle.Sprint(thousands(totalEvents)),
+			"",
+			"",
+			"",
+		},
+	)
+
+	return rf.table(d)
+}
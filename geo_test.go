@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pterm/pterm"
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// stubGeo resolves fixture addresses to countries and counts its lookups.
+type stubGeo struct {
+	countries map[netip.Addr]string
+	lookups   int
+}
+
+func (s *stubGeo) Country(addr netip.Addr) (string, error) {
+	s.lookups++
+	return s.countries[addr], nil
+}
+
+func Test_readGeoIP(t *testing.T) {
+	Convey("Given overlapping networks with a comment", t, func() {
+		r := strings.NewReader("# network,country\n10.0.0.0/8,us\n10.1.2.0/24, NZ\n2001:db8::/32,AU\n")
+
+		Convey("When reading and resolving addresses with them", func() {
+			geo, err := readGeoIP(r)
+			So(err, ShouldBeNil)
+
+			Convey("It should resolve by the most specific network", func() {
+				for addr, want := range map[string]string{
+					"10.1.2.3":        "NZ",
+					"10.9.9.9":        "US",
+					"::ffff:10.9.9.9": "US",
+					"2001:db8::1":     "AU",
+					"192.0.2.1":       "",
+				} {
+					country, err := geo.Country(netip.MustParseAddr(addr))
+					So(err, ShouldBeNil)
+					So(country, ShouldEqual, want)
+				}
+			})
+		})
+	})
+
+	Convey("Given a malformed network", t, func() {
+		r := strings.NewReader("10.0.0.0/33,US\n")
+
+		Convey("When reading it", func() {
+			_, err := readGeoIP(r)
+
+			Convey("It should return an error", func() {
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func Test_findings_topCountries(t *testing.T) {
+	Convey("Given findings with submitters in two countries and one unknown", t, func() {
+		ts := uint32(time.Date(2020, 10, 15, 0, 0, 0, 0, time.UTC).Unix())
+		stub := &stubGeo{countries: map[netip.Addr]string{
+			netip.MustParseAddr("10.1.2.3"):  "NZ",
+			netip.MustParseAddr("10.1.2.77"): "NZ",
+			netip.MustParseAddr("10.9.9.9"):  "US",
+		}}
+		f := &findings{Geo: newGeoCache(stub), Location: time.UTC}
+		for _, ip := range []string{"10.1.2.3", "10.1.2.77", "10.1.2.3", "10.9.9.9", "192.0.2.1"} {
+			f.Add(&p.Event{TimeStamp: ts, Protocol: p.SSH, IP: netip.MustParseAddr(ip)})
+		}
+
+		Convey("When aggregating submitters by country", func() {
+			m, err := f.countryOccurrences()
+
+			Convey("It should sum the submitters' counts within each country", func() {
+				So(err, ShouldBeNil)
+				So(m, ShouldHaveLength, 3)
+				So(m["NZ"].Occurrence, ShouldEqual, 3)
+				So(m["US"].Occurrence, ShouldEqual, 1)
+				So(m[unknownCountry].Occurrence, ShouldEqual, 1)
+			})
+
+			Convey("It should resolve each address only once", func() {
+				_, err = f.countryOccurrences()
+				So(err, ShouldBeNil)
+				So(stub.lookups, ShouldEqual, 4)
+			})
+		})
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+			s = pterm.RemoveColorFromString(s)
+
+			Convey("It should rank the countries", func() {
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+				i := strings.Index(s, "Which countries are the top sources?")
+				So(i, ShouldBeGreaterThan, -1)
+				section := s[i:]
+				So(strings.Index(section, "NZ"), ShouldBeLessThan, strings.Index(section, "US"))
+			})
+		})
+
+		Convey("When rendering the report without a resolver", func() {
+			f.Geo = nil
+			s, err := f.report()
+
+			Convey("It should omit the country section", func() {
+				So(errors.Is(err, ErrMissingProtocol), ShouldBeTrue)
+				So(s, ShouldNotContainSubstring, "Which countries are the top sources?")
+			})
+		})
+	})
+}
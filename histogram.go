@@ -0,0 +1,150 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+)
+
+// histogramSubBuckets is the number of buckets into which a histogram divides
+// each power of two. Values below it have a bucket apiece; above it, a
+// bucket's width is within 1/histogramSubBuckets of its values.
+const histogramSubBuckets = 16
+
+// histogram summarizes values in log-linear buckets, so it takes the same
+// memory whether it counts ten values or ten million, and it can forget a
+// value it counted. Values below zero share the zero bucket, though Min
+// reflects them.
+type histogram struct {
+	Count int
+	Sum   float64
+	Min   int64
+	Max   int64
+
+	buckets []int
+}
+
+// bucket returns the index of the bucket counting v.
+func (h *histogram) bucket(v int64) int {
+	if v < histogramSubBuckets {
+		return int(max64(v, 0))
+	}
+
+	e := bits.Len64(uint64(v)) - 1 // the power of two v is in, at least 4
+	s := int(v>>(e-4)) & (histogramSubBuckets - 1)
+
+	return histogramSubBuckets + (e-4)*histogramSubBuckets + s
+}
+
+// bounds returns the least and greatest values the bucket at index i counts.
+func (h *histogram) bounds(i int) (int64, int64) {
+	if i < histogramSubBuckets {
+		return int64(i), int64(i)
+	}
+
+	e := (i-histogramSubBuckets)/histogramSubBuckets + 4
+	s := int64(i % histogramSubBuckets)
+	lower := (histogramSubBuckets + s) << (e - 4)
+
+	return lower, lower + 1<<(e-4) - 1
+}
+
+// add counts v.
+func (h *histogram) add(v int64) {
+	if h.Count == 0 || v < h.Min {
+		h.Min = v
+	}
+	if h.Count == 0 || v > h.Max {
+		h.Max = v
+	}
+	h.Count++
+	h.Sum += float64(v)
+
+	i := h.bucket(v)
+	for len(h.buckets) <= i {
+		h.buckets = append(h.buckets, 0)
+	}
+	h.buckets[i]++
+}
+
+// remove forgets v, which the histogram must have counted. Should v's bucket
+// empty, Min or Max fall back to the bounds of the nearest bucket remaining.
+func (h *histogram) remove(v int64) {
+	i := h.bucket(v)
+	if i >= len(h.buckets) || h.buckets[i] == 0 {
+		return
+	}
+	h.buckets[i]--
+	h.Count--
+	h.Sum -= float64(v)
+
+	if h.Count == 0 {
+		*h = histogram{}
+
+		return
+	}
+	if h.buckets[i] > 0 {
+		return
+	}
+	if v <= h.Min {
+		for j := i + 1; j < len(h.buckets); j++ {
+			if h.buckets[j] > 0 {
+				h.Min, _ = h.bounds(j)
+				break
+			}
+		}
+	}
+	if v >= h.Max {
+		for j := i - 1; j >= 0; j-- {
+			if h.buckets[j] > 0 {
+				_, h.Max = h.bounds(j)
+				break
+			}
+		}
+	}
+}
+
+// Mean returns the mean of the values counted, or zero if none.
+func (h *histogram) Mean() float64 {
+	if h.Count == 0 {
+		return 0
+	}
+
+	return h.Sum / float64(h.Count)
+}
+
+// Quantile returns the q quantile (e.g., 0.95) of the values counted, by
+// nearest rank, as the greatest value its bucket counts, bounded by Min and
+// Max. It returns zero if the histogram counted nothing.
+func (h *histogram) Quantile(q float64) int64 {
+	rank := int(math.Ceil(q * float64(h.Count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	seen := 0
+	for i, n := range h.buckets {
+		if seen += n; seen >= rank {
+			_, upper := h.bounds(i)
+
+			return max64(min64(upper, h.Max), h.Min)
+		}
+	}
+
+	return 0
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_histogram(t *testing.T) {
+	Convey("Given a histogram", t, func() {
+		h := new(histogram)
+
+		Convey("When counting nothing", func() {
+			Convey("It should summarize no values", func() {
+				So(h.Count, ShouldEqual, 0)
+				So(h.Mean(), ShouldEqual, 0)
+				So(h.Quantile(0.95), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When counting a single value", func() {
+			h.add(42)
+
+			Convey("It should summarize it exactly", func() {
+				So(h.Count, ShouldEqual, 1)
+				So(h.Min, ShouldEqual, 42)
+				So(h.Mean(), ShouldEqual, 42)
+				So(h.Max, ShouldEqual, 42)
+				So(h.Quantile(0.95), ShouldEqual, 42)
+			})
+		})
+
+		Convey("When counting many values out of order", func() {
+			for i := int64(100); i > 0; i-- {
+				h.add(i)
+			}
+
+			Convey("It should summarize them within a bucket's precision", func() {
+				So(h.Count, ShouldEqual, 100)
+				So(h.Min, ShouldEqual, 1)
+				So(h.Mean(), ShouldEqual, 50.5)
+				So(h.Max, ShouldEqual, 100)
+				So(h.Quantile(0.95), ShouldEqual, 95)
+				So(h.Quantile(0.5), ShouldBeBetweenOrEqual, 50, 50+50/histogramSubBuckets)
+			})
+
+			Convey("It should take constant memory", func() {
+				n := len(h.buckets)
+				for i := 0; i < 10000; i++ {
+					h.add(int64(i % 100))
+				}
+				So(len(h.buckets), ShouldEqual, n)
+			})
+		})
+
+		Convey("When forgetting the extremes of the values counted", func() {
+			for _, v := range []int64{3, 10, 500, 9000} {
+				h.add(v)
+			}
+			h.remove(3)
+			h.remove(9000)
+
+			Convey("It should fall back to the bounds of the remaining buckets", func() {
+				So(h.Count, ShouldEqual, 2)
+				So(h.Sum, ShouldEqual, 510)
+				So(h.Min, ShouldEqual, 10)
+				So(h.Max, ShouldBeBetweenOrEqual, 500, 500+500/histogramSubBuckets)
+			})
+
+			Convey("It should reset once it forgets every value", func() {
+				h.remove(10)
+				h.remove(500)
+				So(*h, ShouldResemble, histogram{})
+			})
+		})
+
+		Convey("When counting values across the buckets", func() {
+			Convey("It should bound each value by its bucket", func() {
+				for _, v := range []int64{0, 1, 15, 16, 17, 31, 32, 1000, 65535, 1 << 40} {
+					lower, upper := h.bounds(h.bucket(v))
+					So(v, ShouldBeGreaterThanOrEqualTo, lower)
+					So(v, ShouldBeLessThanOrEqualTo, upper)
+					So(upper-lower, ShouldBeLessThanOrEqualTo, v/histogramSubBuckets)
+				}
+			})
+		})
+	})
+}
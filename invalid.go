@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/pterm/pterm"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// castagnoliTable is the CRC-32 table of the Castagnoli polynomial, which some
+// emitters use in place of the IEEE polynomial.
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// rejection is an event that failed to parse or to validate.
+type rejection struct {
+	Event *p.Event
+	Err   error // nil if the event parsed but failed validation
+}
+
+// rejectionJSON is the JSON representation of a rejection. Fields that weren't
+// read before the failure are omitted.
+type rejectionJSON struct {
+	Error            string   `json:"error"`
+	FailedField      string   `json:"failed_field,omitempty"`
+	FieldsRead       []string `json:"fields_read"`
+	EventUUID        string   `json:"event_uuid,omitempty"`
+	Protocol         string   `json:"protocol,omitempty"`
+	DeclaredCheckSum *uint32  `json:"declared_checksum,omitempty"`
+	ComputedCheckSum *uint32  `json:"computed_checksum,omitempty"`
+}
+
+// newRejectionJSON returns the JSON representation of the rejection.
+func newRejectionJSON(r rejection) rejectionJSON {
+	j := rejectionJSON{FieldsRead: p.Fields}
+
+	var fe *p.FieldError
+	switch {
+	case errors.As(r.Err, &fe):
+		j.Error = r.Err.Error()
+		j.FailedField = fe.Field
+		j.FieldsRead = fe.FieldsRead()
+	case r.Err != nil:
+		j.Error = r.Err.Error()
+	default:
+		j.Error = "checksum mismatch"
+	}
+
+	read := make(map[string]bool, len(j.FieldsRead))
+	for _, f := range j.FieldsRead {
+		read[f] = true
+	}
+
+	if read["EventUUID"] {
+		j.EventUUID = r.Event.EventUUID.String()
+	}
+	if read["Protocol"] {
+		j.Protocol = r.Event.Protocol.String()
+	}
+	if read["Submitter"] {
+		// Every checksummed field was read.
+		computed := r.Event.ComputeCheckSum()
+		j.ComputedCheckSum = &computed
+	}
+	if read["CheckSum"] {
+		declared := r.Event.CheckSum
+		j.DeclaredCheckSum = &declared
+	}
+
+	return j
+}
+
+// writeRejectionsJSON writes each rejection to w as a line of JSON.
+func writeRejectionsJSON(w io.Writer, rejections []rejection) error {
+	enc := json.NewEncoder(w)
+	for _, r := range rejections {
+		if err := enc.Encode(newRejectionJSON(r)); err != nil {
+			return fmt.Errorf("encoding rejected event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Failure modes of rejected events.
+const (
+	failCheckSum  = "checksum mismatch"
+	failMalformed = "malformed"
+	failSize      = "size mismatch"
+	failTruncated = "truncated header"
+)
+
+// failureMode categorizes why the event was rejected. An event that parsed
+// but failed validation has a checksum mismatch. A declared Size overrunning
+// the datagram leaves too few bytes for the payload or the fields following
+// it, so running out of bytes there is a size mismatch.
+func failureMode(r rejection) string {
+	if r.Err == nil {
+		return failCheckSum
+	}
+
+	var fe *p.FieldError
+	if !errors.As(r.Err, &fe) {
+		return failMalformed
+	}
+
+	switch fe.Field {
+	case "PayloadBytes", "Protocol", "Submitter", "CheckSum":
+		return failSize
+	}
+
+	return failTruncated
+}
+
+// failureGroup tallies rejected events sharing a failure mode, submitter, and
+// protocol.
+type failureGroup struct {
+	Mode      string
+	Submitter string
+	Protocol  string
+	Count     int
+}
+
+// failureGroups returns the Invalid events grouped by failure mode, submitter,
+// and protocol, largest group first. A submitter or protocol not read before
+// the failure is a hyphen.
+func (f *findings) failureGroups() []failureGroup {
+	groups := make(map[failureGroup]int)
+	for _, r := range f.Invalid {
+		j := newRejectionJSON(r)
+		g := failureGroup{Mode: failureMode(r), Submitter: "-", Protocol: "-"}
+		if j.Protocol != "" {
+			g.Protocol = j.Protocol
+		}
+		if j.ComputedCheckSum != nil && r.Event.IP.IsValid() {
+			// The submitter was read, since every checksummed field was.
+			g.Submitter = r.Event.IP.String()
+		}
+		groups[g]++
+	}
+
+	sorted := make([]failureGroup, 0, len(groups))
+	for g, n := range groups {
+		g.Count = n
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch {
+		case a.Count != b.Count:
+			return a.Count > b.Count
+		case a.Mode != b.Mode:
+			return a.Mode < b.Mode
+		case a.Submitter != b.Submitter:
+			return a.Submitter < b.Submitter
+		}
+
+		return a.Protocol < b.Protocol
+	})
+
+	return sorted
+}
+
+// checkSumOffset returns the difference between the declared and computed
+// checksums shared by every Invalid event with a checksum mismatch, if there
+// are at least two such events and they share one.
+func (f *findings) checkSumOffset() (uint32, bool) {
+	var (
+		offset uint32
+		n      int
+	)
+	for _, r := range f.Invalid {
+		if failureMode(r) != failCheckSum {
+			continue
+		}
+
+		d := r.Event.CheckSum - r.Event.ComputeCheckSum()
+		if n > 0 && d != offset {
+			return 0, false
+		}
+		offset = d
+		n++
+	}
+
+	return offset, n > 1
+}
+
+// checkSumPolynomials renders the stored, IEEE, and Castagnoli checksums of up
+// to count Invalid events with checksum mismatches, followed by how many each
+// polynomial matches, answering whether the emitter uses a different CRC.
+func (f *findings) checkSumPolynomials(rf reportFormat, count int) (string, error) {
+	d := pterm.TableData{{"#", "Event UUID", "Stored", "IEEE", "Castagnoli", "Match"}}
+	matches := make(map[string]int)
+	n := 0
+	for _, r := range f.Invalid {
+		if n == count {
+			break
+		}
+		if failureMode(r) != failCheckSum {
+			continue
+		}
+		n++
+
+		var (
+			e          = r.Event
+			ieee       = e.ComputeCheckSumWith(crc32.IEEETable)
+			castagnoli = e.ComputeCheckSumWith(castagnoliTable)
+			match      = "neither"
+		)
+		switch e.CheckSum {
+		case ieee:
+			match = "IEEE"
+		case castagnoli:
+			match = "Castagnoli"
+		}
+		matches[match]++
+
+		d = append(d, []string{
+			strconv.Itoa(n),
+			e.EventUUID.String(),
+			fmt.Sprintf("0x%08x", e.CheckSum),
+			fmt.Sprintf("0x%08x", ieee),
+			fmt.Sprintf("0x%08x", castagnoli),
+			match,
+		})
+	}
+	if n == 0 {
+		return rf.table(append(d, []string{"", "NO", "CHECKSUM", "MISMATCHES", "", ""}))
+	}
+
+	s, err := rf.table(d)
+	if err != nil {
+		return "", err
+	}
+
+	return s + fmt.Sprintf("\n\nOf %d sampled: IEEE matches %d, Castagnoli matches %d, neither matches %d.",
+		n, matches["IEEE"], matches["Castagnoli"], matches["neither"],
+	), nil
+}
+
+// invalidSummary renders the Invalid events grouped by failure mode,
+// submitter, and protocol, followed by the dominant failure mode.
+func (f *findings) invalidSummary(rf reportFormat, count int) (string, error) {
+	groups := f.failureGroups()
+
+	d := pterm.TableData{{"#", "Failure", "Submitter", "Protocol", "Count", "%"}}
+	modes := make(map[string]int)
+	for i, g := range groups {
+		modes[g.Mode] += g.Count
+		if i < count {
+			d = append(d, []string{
+				strconv.Itoa(i + 1),
+				g.Mode,
+				g.Submitter,
+				g.Protocol,
+				strconv.Itoa(g.Count),
+				percent(g.Count, len(f.Invalid)),
+			})
+		}
+	}
+	if len(groups) == 0 {
+		return rf.table(append(d, []string{"", "NO", "INVALID", "EVENTS", "", ""}))
+	}
+
+	s, err := rf.table(d)
+	if err != nil {
+		return "", err
+	}
+
+	var dominant string
+	for mode, n := range modes {
+		if dominant == "" || n > modes[dominant] || (n == modes[dominant] && mode < dominant) {
+			dominant = mode
+		}
+	}
+	s += fmt.Sprintf("\n\nDominant failure: %s (%d of %d, %s)",
+		dominant, modes[dominant], len(f.Invalid), percent(modes[dominant], len(f.Invalid)),
+	)
+	if offset, ok := f.checkSumOffset(); ok {
+		s += fmt.Sprintf("\nEvery checksum mismatch is off by 0x%08x.", offset)
+	}
+
+	return s, nil
+}
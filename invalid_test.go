@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pterm/pterm"
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_writeRejectionsJSON(t *testing.T) {
+	Convey("Given an event with a mismatched checksum and a truncated event", t, func() {
+		mismatched := *validEvents[0]
+		mismatched.CheckSum++
+
+		b, err := validEvents[1].MarshalBinary()
+		So(err, ShouldBeNil)
+		truncated := new(p.Event)
+		_, truncErr := truncated.ReadFrom(bytes.NewReader(b[:len(b)-6]))
+		So(truncErr, ShouldBeError)
+
+		rejections := []rejection{{Event: &mismatched}, {Event: truncated, Err: truncErr}}
+
+		Convey("When writing them as JSON", func() {
+			buf := new(bytes.Buffer)
+			So(writeRejectionsJSON(buf, rejections), ShouldBeNil)
+
+			var actual []rejectionJSON
+			s := bufio.NewScanner(buf)
+			for s.Scan() {
+				var j rejectionJSON
+				So(json.Unmarshal(s.Bytes(), &j), ShouldBeNil)
+				actual = append(actual, j)
+			}
+			So(actual, ShouldHaveLength, 2)
+
+			Convey("It should capture the checksum mismatch", func() {
+				j := actual[0]
+				So(j.Error, ShouldEqual, "checksum mismatch")
+				So(j.EventUUID, ShouldEqual, validEvents[0].EventUUID.String())
+				So(j.FieldsRead, ShouldResemble, p.Fields)
+				So(*j.DeclaredCheckSum, ShouldEqual, validEvents[0].CheckSum+1)
+				So(*j.ComputedCheckSum, ShouldEqual, validEvents[0].CheckSum)
+			})
+
+			Convey("It should capture the fields read before the parse failure", func() {
+				j := actual[1]
+				So(j.Error, ShouldEqual, truncErr.Error())
+				So(j.FailedField, ShouldEqual, "Submitter")
+				So(j.FieldsRead, ShouldResemble, []string{"NodeID", "TimeStamp", "Size", "EventUUID", "PayloadBytes", "Protocol"})
+				So(j.Protocol, ShouldEqual, validEvents[1].Protocol.String())
+				So(j.DeclaredCheckSum, ShouldBeNil)
+				So(j.ComputedCheckSum, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func Test_runInvalidJSON(t *testing.T) {
+	Convey("Given a run configured to write invalid events as JSON", t, func() {
+		addr, err := udpServer(invalidEvents)
+		So(err, ShouldBeNil)
+
+		path := filepath.Join(t.TempDir(), "invalid.json")
+		cfg := config{
+			Address:     addr.String(),
+			Datagrams:   len(invalidEvents),
+			DetailIP:    netip.MustParseAddr("106.54.93.84"),
+			InvalidJSON: path,
+			Quiet:       true,
+			Size:        minDatagramBytes,
+		}
+
+		Convey("When calling the run function", func() {
+			_, err := captureStdout(func() error { return run(cfg) })
+
+			Convey("It should write a line per invalid event, reporting no valid events", func() {
+				So(errors.Is(err, ErrNoEvents), ShouldBeTrue)
+
+				b, err := os.ReadFile(path)
+				So(err, ShouldBeNil)
+				So(bytes.Count(b, []byte("\n")), ShouldEqual, len(invalidEvents))
+				So(string(b), ShouldContainSubstring, `"declared_checksum"`)
+			})
+		})
+	})
+}
+
+func Test_findings_invalidSummary(t *testing.T) {
+	Convey("Given two checksum mismatches and two events overrunning their sizes", t, func() {
+		var rejections []rejection
+		for _, e := range validEvents[:2] {
+			mismatched := *e
+			mismatched.CheckSum++
+			rejections = append(rejections, rejection{Event: &mismatched})
+		}
+		for _, grow := range []uint16{3, 20} {
+			oversized := *validEvents[2]
+			oversized.Size += grow
+			b, err := oversized.MarshalBinary()
+			So(err, ShouldBeNil)
+
+			e := new(p.Event)
+			_, err = e.ReadFrom(bytes.NewReader(b[:len(b)-int(grow)]))
+			So(err, ShouldBeError)
+			rejections = append(rejections, rejection{Event: e, Err: err})
+		}
+		rejections = append(rejections, rejection{Event: rejections[2].Event, Err: rejections[2].Err})
+
+		f := &findings{ExplainInvalid: true, Invalid: rejections}
+
+		Convey("When categorizing them", func() {
+			Convey("It should tell size mismatches from checksum mismatches", func() {
+				So(failureMode(rejections[0]), ShouldEqual, failCheckSum)
+				So(failureMode(rejections[1]), ShouldEqual, failCheckSum)
+				So(failureMode(rejections[2]), ShouldEqual, failSize)
+				So(failureMode(rejections[3]), ShouldEqual, failSize)
+			})
+
+			Convey("It should group them by failure mode, submitter, and protocol", func() {
+				groups := f.failureGroups()
+				So(groups, ShouldHaveLength, 4)
+				So(groups[0].Mode, ShouldEqual, failSize)
+				So(groups[0].Count, ShouldEqual, 2)
+				for _, g := range groups {
+					if g.Mode == failCheckSum {
+						So(g.Submitter, ShouldNotEqual, "-")
+						So(g.Protocol, ShouldNotEqual, "-")
+					}
+				}
+			})
+
+			Convey("It should find the constant checksum offset", func() {
+				offset, ok := f.checkSumOffset()
+				So(ok, ShouldBeTrue)
+				So(offset, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When rendering the summary", func() {
+			s, err := f.invalidSummary(reportFormat{}, 15)
+
+			Convey("It should name the dominant failure mode", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "Dominant failure: size mismatch (3 of 5, 60.0%)")
+				So(s, ShouldContainSubstring, "off by 0x00000001")
+			})
+		})
+	})
+}
+
+func Test_findings_checkSumPolynomials(t *testing.T) {
+	Convey("Given an invalid event whose emitter used the Castagnoli polynomial", t, func() {
+		e := *validEvents[0]
+		e.CheckSum = e.ComputeCheckSumWith(castagnoliTable)
+		So(e.Valid(), ShouldBeFalse)
+
+		f := &findings{CRCSample: 5, Events: validEvents, Invalid: []rejection{{Event: &e}}}
+
+		Convey("When comparing its checksums", func() {
+			s, err := f.checkSumPolynomials(reportFormat{}, f.CRCSample)
+			s = pterm.RemoveColorFromString(s)
+
+			Convey("It should report the stored, IEEE, and Castagnoli checksums", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, e.EventUUID.String())
+				So(s, ShouldContainSubstring, fmt.Sprintf("0x%08x", e.CheckSum))
+				So(s, ShouldContainSubstring, fmt.Sprintf("0x%08x", validEvents[0].CheckSum))
+				So(s, ShouldContainSubstring, "Castagnoli matches 1")
+				So(s, ShouldContainSubstring, "IEEE matches 0")
+			})
+		})
+
+		Convey("When rendering the report", func() {
+			s, err := f.report()
+
+			Convey("It should include the comparison", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "Which CRC-32 polynomial do invalid events match?")
+			})
+		})
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// reportFormat is the format in which the report renders: as Markdown, or for
+// the terminal, fitting columns to a positive width.
+type reportFormat struct {
+	markdown bool
+	width    int
+}
+
+// markdownReport renders the report as Markdown. Markdown doesn't wrap to the
+// terminal, so columns render in full, regardless of the findings' Width.
+func (f *findings) markdownReport() (string, error) {
+	return f.render(reportFormat{markdown: true})
+}
+
+// table renders the table data, whose first row is its header, in the format.
+func (rf reportFormat) table(d pterm.TableData) (string, error) {
+	if rf.markdown {
+		return markdownTable(d), nil
+	}
+
+	return pterm.DefaultTable.WithHasHeader().WithData(d).Srender()
+}
+
+// markdownEscaper escapes the characters Markdown would otherwise interpret,
+// including the pipes delimiting table cells, and flattens line breaks, which
+// would end a table row.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"~", `\~`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+	"|", `\|`,
+	"\r\n", " ",
+	"\n", " ",
+	"\r", " ",
+)
+
+// markdownTable renders the table data as a GitHub-flavored Markdown table
+// whose header is the first row. Cells lose their terminal colors and have
+// their Markdown escaped.
+func markdownTable(d pterm.TableData) string {
+	var b strings.Builder
+	row := func(cells []string) {
+		b.WriteString("|")
+		for _, cell := range cells {
+			b.WriteString(" ")
+			b.WriteString(markdownEscaper.Replace(pterm.RemoveColorFromString(cell)))
+			b.WriteString(" |")
+		}
+		b.WriteString("\n")
+	}
+
+	for i, cells := range d {
+		row(cells)
+		if i == 0 {
+			b.WriteString("|")
+			b.WriteString(strings.Repeat(" --- |", len(cells)))
+			b.WriteString("\n")
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
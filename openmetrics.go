@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// openMetricsSubmitters is the number of top submitters WriteOpenMetrics
+// writes, matching the report.
+const openMetricsSubmitters = 15
+
+// labelEscaper escapes OpenMetrics label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteOpenMetrics writes the findings' event counts by protocol, the top
+// submitters' event counts, and the counts of discarded events by reason to w
+// in the OpenMetrics text format. Samples are sorted so the same findings
+// always produce the same exposition.
+func (f *findings) WriteOpenMetrics(w io.Writer) error {
+	if f.ByProtocol == nil {
+		f.populate()
+	}
+
+	protocols := make([]p.Protocol, 0, len(f.ByProtocol))
+	for proto := range f.ByProtocol {
+		protocols = append(protocols, proto)
+	}
+	sort.Slice(protocols, func(i, j int) bool { return dotProtocol(protocols[i]) < dotProtocol(protocols[j]) })
+
+	ew := &errWriter{w: w}
+
+	ew.printf("# TYPE events_by_protocol counter\n")
+	ew.printf("# HELP events_by_protocol Events received by protocol.\n")
+	for _, proto := range protocols {
+		ew.printf("events_by_protocol_total{protocol=\"%s\"} %d\n",
+			labelEscaper.Replace(dotProtocol(proto)), f.ByProtocol[proto].Occurrence,
+		)
+	}
+
+	ew.printf("# TYPE events_by_submitter counter\n")
+	ew.printf("# HELP events_by_submitter Events received from each of the top %d submitters.\n",
+		openMetricsSubmitters,
+	)
+	for _, item := range f.submitterOccurrences().top(openMetricsSubmitters) {
+		ew.printf("events_by_submitter_total{submitter=\"%s\"} %d\n",
+			labelEscaper.Replace(item.Item), item.Occurrence,
+		)
+	}
+
+	reasons := make([]string, 0, len(f.Discarded))
+	for reason := range f.Discarded {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	ew.printf("# TYPE events_invalid counter\n")
+	ew.printf("# HELP events_invalid Events discarded as malformed or invalid.\n")
+	for _, reason := range reasons {
+		ew.printf("events_invalid_total{reason=\"%s\"} %d\n", labelEscaper.Replace(reason), f.Discarded[reason])
+	}
+	ew.printf("# EOF\n")
+
+	return ew.err
+}
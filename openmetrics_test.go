@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"net/netip"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// openMetricsLine matches a metric family's TYPE or HELP descriptor, a sample
+// with escaped label values, or the terminating EOF.
+var openMetricsLine = regexp.MustCompile(
+	`^(# (TYPE|HELP) [a-z_]+ .+|[a-z_]+_total\{[a-z_]+="([^"\\\n]|\\[\\"n])*"\} [0-9]+|# EOF)$`,
+)
+
+func Test_findings_WriteOpenMetrics(t *testing.T) {
+	Convey("Given findings for a small dataset with discarded events", t, func() {
+		a := netip.MustParseAddr("10.0.0.2")
+		b := netip.MustParseAddr("10.0.0.1")
+		f := &findings{Discarded: map[string]int{"invalid": 2, "malformed": 0}}
+		for _, e := range []*p.Event{
+			{Protocol: p.SSH, IP: a},
+			{Protocol: p.SSH, IP: a},
+			{Protocol: p.HTTP, IP: a},
+			{Protocol: p.SSH, IP: b},
+		} {
+			f.Add(e)
+		}
+
+		Convey("When writing OpenMetrics", func() {
+			buf := new(bytes.Buffer)
+			So(f.WriteOpenMetrics(buf), ShouldBeNil)
+			s := buf.String()
+
+			Convey("It should write only valid metric lines, ending with EOF", func() {
+				lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+				for _, line := range lines {
+					So(openMetricsLine.MatchString(line), ShouldBeTrue)
+				}
+				So(lines[len(lines)-1], ShouldEqual, "# EOF")
+			})
+
+			Convey("It should count events by protocol, submitter, and discard reason", func() {
+				So(s, ShouldContainSubstring, `events_by_protocol_total{protocol="HTTP"} 1`+"\n")
+				So(s, ShouldContainSubstring, `events_by_protocol_total{protocol="SSH"} 3`+"\n")
+				So(s, ShouldContainSubstring, `events_by_submitter_total{submitter="10.0.0.2"} 3`+"\n")
+				So(s, ShouldContainSubstring, `events_by_submitter_total{submitter="10.0.0.1"} 1`+"\n")
+				So(s, ShouldContainSubstring, `events_invalid_total{reason="invalid"} 2`+"\n")
+				So(s, ShouldContainSubstring, `events_invalid_total{reason="malformed"} 0`+"\n")
+			})
+		})
+	})
+
+	Convey("Given a label value with a backslash, quote, and newline", t, func() {
+		Convey("When escaping it", func() {
+			Convey("It should escape each", func() {
+				So(labelEscaper.Replace("a\\b\"c\nd"), ShouldEqual, `a\\b\"c\nd`)
+			})
+		})
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// payloadSummaryRunes is the length of the payload summary in event previews.
+const payloadSummaryRunes = 48
+
+// preview renders compact tables of the first head and the last tail events,
+// omitting either table if its count isn't positive.
+func (f *findings) preview(head, tail int) (string, error) {
+	var b strings.Builder
+
+	for _, section := range []struct {
+		count  int
+		label  string
+		events func(n int) []*p.Event
+	}{
+		{head, "first", func(n int) []*p.Event { return f.Events[:n] }},
+		{tail, "last", func(n int) []*p.Event { return f.Events[len(f.Events)-n:] }},
+	} {
+		if section.count <= 0 {
+			continue
+		}
+
+		n := section.count
+		if n > len(f.Events) {
+			n = len(f.Events)
+		}
+
+		s, err := previewEvents(section.events(n))
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(
+			fmt.Sprintf("\n\n\n\u001B[%dmWhat were the %s %d events?\u001B[0m\n\n", f.LabelColor, section.label, n),
+		)
+		b.WriteString(s)
+	}
+
+	return strings.TrimPrefix(b.String(), "\n\n\n"), nil
+}
+
+// previewEvents renders a row per event.
+func previewEvents(events []*p.Event) (string, error) {
+	d := pterm.TableData{{"#", "Event UUID", "Protocol", "Submitter", "Payload"}}
+	for i, e := range events {
+		d = append(d,
+			[]string{strconv.Itoa(i + 1), e.EventUUID.String(), e.Protocol.String(), e.IP.String(), payloadSummary(e)},
+		)
+	}
+	if len(events) == 0 {
+		d = append(d, []string{"", "NO", "EVENTS", "FOUND", ""})
+	}
+
+	return pterm.DefaultTable.WithHasHeader().WithData(d).Srender()
+}
+
+// payloadSummary returns the event's payload as key:value pairs sorted by key,
+// truncated to payloadSummaryRunes.
+func payloadSummary(e *p.Event) string {
+	keys := make([]string, 0, len(e.Payload))
+	for k := range e.Payload {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+":"+e.Payload[k])
+	}
+
+	return truncate(strings.Join(pairs, " "), payloadSummaryRunes)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pterm/pterm"
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// previewRow matches a numbered row of a preview table.
+var previewRow = regexp.MustCompile(`^\s*\d+\s*\|`)
+
+func Test_findings_preview(t *testing.T) {
+	Convey("Given findings with events", t, func() {
+		f := &findings{Events: validEvents}
+
+		Convey("When previewing the first two events", func() {
+			s, err := f.preview(2, 0)
+
+			Convey("It should render exactly two event rows", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "What were the first 2 events?")
+				So(s, ShouldNotContainSubstring, "last")
+
+				var rows []string
+				for _, line := range strings.Split(pterm.RemoveColorFromString(s), "\n") {
+					if previewRow.MatchString(line) {
+						rows = append(rows, line)
+					}
+				}
+				So(rows, ShouldHaveLength, 2)
+				So(rows[0], ShouldContainSubstring, validEvents[0].EventUUID.String())
+				So(rows[1], ShouldContainSubstring, validEvents[1].EventUUID.String())
+			})
+		})
+
+		Convey("When previewing more last events than were collected", func() {
+			s, err := f.preview(0, len(validEvents)+5)
+
+			Convey("It should render every event", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, fmt.Sprintf("What were the last %d events?", len(validEvents)))
+			})
+		})
+	})
+}
+
+func Test_payloadSummary(t *testing.T) {
+	Convey("Given an event with a long payload", t, func() {
+		e := &p.Event{Payload: map[string]string{
+			"username": "aiden",
+			"password": "Jackallava",
+			"email":    "chloesmith263@test.net",
+		}}
+
+		Convey("When summarizing its payload", func() {
+			s := payloadSummary(e)
+
+			Convey("It should sort the pairs by key and truncate them", func() {
+				So(s, ShouldStartWith, "email:chloesmith263@test.net password:")
+				So(s, ShouldEndWith, "...")
+				So(len([]rune(s)), ShouldEqual, payloadSummaryRunes)
+			})
+		})
+	})
+}
+
+func Test_runHead(t *testing.T) {
+	Convey("Given a run configured with -head 2", t, func() {
+		addr, err := udpServer(validEvents)
+		So(err, ShouldBeNil)
+
+		Convey("When calling the run function", func() {
+			stdout, err := captureStdout(func() error {
+				return run(config{
+					Address:   addr.String(),
+					Datagrams: len(validEvents),
+					Head:      2,
+					Quiet:     true,
+					Size:      minDatagramBytes,
+				})
+			})
+
+			Convey("It should print the preview without the report", func() {
+				So(err, ShouldBeNil)
+				So(stdout, ShouldContainSubstring, "What were the first 2 events?")
+				So(stdout, ShouldNotContainSubstring, "Who are the top 15 subitters?")
+			})
+		})
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startCPUProfile starts writing a CPU profile to the file at path, returning
+// a function that stops profiling and closes the file. An empty path disables
+// profiling.
+func startCPUProfile(path string) (stop func() error, err error) {
+	if path == "" {
+		return func() error { return nil }, nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating CPU profile: %w", err)
+	}
+
+	if err = pprof.StartCPUProfile(f); err != nil {
+		_ = f.Close()
+
+		return nil, fmt.Errorf("starting CPU profile: %w", err)
+	}
+
+	return func() error {
+		pprof.StopCPUProfile()
+
+		return f.Close()
+	}, nil
+}
+
+// writeMemProfile writes a heap profile to the file at path. An empty path
+// disables profiling.
+func writeMemProfile(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	// Get up-to-date statistics.
+	runtime.GC()
+
+	if err := writeFile(path, func(w io.Writer) error { return pprof.WriteHeapProfile(w) }); err != nil {
+		return fmt.Errorf("writing memory profile: %w", err)
+	}
+
+	return nil
+}
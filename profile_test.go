@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_runProfiles(t *testing.T) {
+	Convey("Given a run configured to write CPU and memory profiles", t, func() {
+		addr, err := udpServer(validEvents)
+		So(err, ShouldBeNil)
+
+		dir := t.TempDir()
+		cfg := config{
+			Address:    addr.String(),
+			CPUProfile: filepath.Join(dir, "cpu.out"),
+			Datagrams:  len(validEvents),
+			MemProfile: filepath.Join(dir, "mem.out"),
+			Quiet:      true,
+			Size:       minDatagramBytes,
+		}
+
+		Convey("When calling the run function", func() {
+			_, err := captureStdout(func() error { return run(cfg) })
+			So(err, ShouldBeNil)
+
+			Convey("It should write non-empty profiles", func() {
+				for _, path := range []string{cfg.CPUProfile, cfg.MemProfile} {
+					fi, err := os.Stat(path)
+					So(err, ShouldBeNil)
+					So(fi.Size(), ShouldBeGreaterThan, 0)
+				}
+			})
+		})
+	})
+}
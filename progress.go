@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval is how often a progressTracker renders the progress bar.
+const progressInterval = 100 * time.Millisecond
+
+// progressTracker counts completed steps from any number of goroutines and
+// renders the progress bar from a single goroutine on a ticker, decoupling the
+// bar from the order in which steps complete.
+type progressTracker struct {
+	done  atomic.Int64
+	total atomic.Int64
+
+	// render draws the progress bar at the step. Only the tracker's rendering
+	// goroutine calls it, and never twice for the same step.
+	render   func(step, total int)
+	rendered int
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// newProgressTracker returns a tracker of the total steps, rendering them with
+// render every interval until stopped.
+func newProgressTracker(total int, interval time.Duration, render func(step, total int)) *progressTracker {
+	t := &progressTracker{
+		render: render,
+		stop:   make(chan struct{}),
+	}
+	t.total.Store(int64(total))
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				t.draw()
+			case <-t.stop:
+				// The final render, of 100% if every step completed.
+				t.draw()
+				return
+			}
+		}
+	}()
+
+	return t
+}
+
+// Add counts n completed steps. It's safe for concurrent use, and a nil
+// tracker ignores it.
+func (t *progressTracker) Add(n int) {
+	if t == nil {
+		return
+	}
+
+	t.done.Add(int64(n))
+}
+
+// SetTotal changes the total steps, as when the total becomes known only after
+// tracking begins. It's safe for concurrent use.
+func (t *progressTracker) SetTotal(total int) {
+	t.total.Store(int64(total))
+}
+
+// Stop renders the final progress and stops the tracker once every call to Add
+// has returned. Subsequent calls do nothing, as does calling it on a nil
+// tracker.
+func (t *progressTracker) Stop() {
+	if t == nil {
+		return
+	}
+
+	t.once.Do(func() {
+		close(t.stop)
+		t.wg.Wait()
+	})
+}
+
+// draw renders the steps completed so far, unless they're already rendered.
+func (t *progressTracker) draw() {
+	step, total := int(t.done.Load()), int(t.total.Load())
+	if step > total {
+		step = total
+	}
+	if step == t.rendered {
+		return
+	}
+
+	// The bar begins on a fresh line when rendering the first step.
+	if t.rendered == 0 && step > 1 {
+		t.render(1, total)
+	}
+	t.rendered = step
+	t.render(step, total)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_progressTracker(t *testing.T) {
+	Convey("Given a progress tracker of steps completed by concurrent workers", t, func() {
+		const (
+			workers = 8
+			steps   = 1000
+		)
+
+		var rendered []int
+		tracker := newProgressTracker(workers*steps, time.Millisecond, func(step, _ int) {
+			rendered = append(rendered, step)
+		})
+
+		Convey("When every worker completes its steps and the tracker stops", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < steps; j++ {
+						tracker.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			tracker.Stop()
+			tracker.Stop()
+
+			Convey("It should count every step and render the total exactly once, last", func() {
+				So(tracker.done.Load(), ShouldEqual, workers*steps)
+				So(rendered, ShouldNotBeEmpty)
+				So(rendered[0], ShouldEqual, 1)
+				So(rendered[len(rendered)-1], ShouldEqual, workers*steps)
+
+				totals := 0
+				for i, step := range rendered {
+					if step == workers*steps {
+						totals++
+					}
+					if i > 0 {
+						So(step, ShouldBeGreaterThan, rendered[i-1])
+					}
+				}
+				So(totals, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a nil progress tracker", t, func() {
+		var tracker *progressTracker
+
+		Convey("When counting steps and stopping it", func() {
+			Convey("It should do nothing", func() {
+				So(func() { tracker.Add(1); tracker.Stop() }, ShouldNotPanic)
+			})
+		})
+	})
+}
@@ -0,0 +1,95 @@
+package protocol
+
+import (
+	"net/mail"
+	"strings"
+	"unicode"
+)
+
+// Value classifications returned by ClassifyValue.
+const (
+	ClassAlpha        = "alpha"
+	ClassAlphanumeric = "alphanumeric"
+	ClassBotUA        = "bot-ua"
+	ClassBrowserUA    = "browser-ua"
+	ClassEmailInvalid = "email-invalid"
+	ClassEmailValid   = "email-valid"
+	ClassEmpty        = "empty"
+	ClassMixed        = "mixed"
+	ClassNumeric      = "numeric"
+	ClassOtherUA      = "other-ua"
+)
+
+// botTokens are case-insensitive substrings identifying automated user-agents.
+var botTokens = []string{
+	"bot", "crawl", "spider", "curl/", "wget/", "python-requests", "go-http-client", "libwww", "scan",
+}
+
+// ClassifyValue returns a simple classification of the payload value by its
+// key. Emails are email-valid if they hold a single address with a dotted
+// domain, or email-invalid. User-agents are bot-ua if they contain a token
+// common to crawlers and scripts, browser-ua if they begin with "Mozilla/", or
+// other-ua. Any other value is numeric, alpha, alphanumeric, or mixed by the
+// characters it contains, or empty.
+func ClassifyValue(key, value string) string {
+	switch {
+	case value == "":
+		return ClassEmpty
+	case key == "email":
+		return classifyEmail(value)
+	case key == "user-agent":
+		return classifyUserAgent(value)
+	}
+
+	var letters, digits, others int
+	for _, r := range value {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case unicode.IsDigit(r):
+			digits++
+		default:
+			others++
+		}
+	}
+
+	switch {
+	case others > 0:
+		return ClassMixed
+	case letters == 0:
+		return ClassNumeric
+	case digits == 0:
+		return ClassAlpha
+	}
+
+	return ClassAlphanumeric
+}
+
+func classifyEmail(value string) string {
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value || addr.Name != "" {
+		return ClassEmailInvalid
+	}
+
+	at := strings.LastIndexByte(value, '@')
+	if domain := value[at+1:]; !strings.Contains(strings.Trim(domain, "."), ".") {
+		return ClassEmailInvalid
+	}
+
+	return ClassEmailValid
+}
+
+func classifyUserAgent(value string) string {
+	lower := strings.ToLower(value)
+	for _, token := range botTokens {
+		if strings.Contains(lower, token) {
+			return ClassBotUA
+		}
+	}
+
+	if strings.HasPrefix(value, "Mozilla/") {
+		return ClassBrowserUA
+	}
+
+	return ClassOtherUA
+}
@@ -0,0 +1,41 @@
+package protocol
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClassifyValue(t *testing.T) {
+	Convey("Given representative payload values", t, func() {
+		Convey("When classifying them", func() {
+			Convey("It should classify passwords and usernames by their characters", func() {
+				So(ClassifyValue("password", "123456"), ShouldEqual, ClassNumeric)
+				So(ClassifyValue("password", "Jackallava"), ShouldEqual, ClassAlpha)
+				So(ClassifyValue("password", "hunter2"), ShouldEqual, ClassAlphanumeric)
+				So(ClassifyValue("password", "p@ss word!"), ShouldEqual, ClassMixed)
+				So(ClassifyValue("username", "elijah"), ShouldEqual, ClassAlpha)
+				So(ClassifyValue("username", ""), ShouldEqual, ClassEmpty)
+			})
+
+			Convey("It should validate the format of emails", func() {
+				So(ClassifyValue("email", "chloesmith263@test.net"), ShouldEqual, ClassEmailValid)
+				So(ClassifyValue("email", "chloesmith263@localhost"), ShouldEqual, ClassEmailInvalid)
+				So(ClassifyValue("email", "not an email"), ShouldEqual, ClassEmailInvalid)
+				So(ClassifyValue("email", "Chloe <chloe@test.net>"), ShouldEqual, ClassEmailInvalid)
+				So(ClassifyValue("email", "a@@test.net"), ShouldEqual, ClassEmailInvalid)
+			})
+
+			Convey("It should tell bots from browsers by their user-agents", func() {
+				So(ClassifyValue("user-agent",
+					"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"), ShouldEqual, ClassBotUA)
+				So(ClassifyValue("user-agent", "curl/7.68.0"), ShouldEqual, ClassBotUA)
+				So(ClassifyValue("user-agent", "python-requests/2.25.1"), ShouldEqual, ClassBotUA)
+				So(ClassifyValue("user-agent",
+					"Mozilla/5.0 (Windows NT 10.0; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/60.0.3112.78 Safari/537.36"),
+					ShouldEqual, ClassBrowserUA)
+				So(ClassifyValue("user-agent", "Opera/9.80 (Windows NT 6.1)"), ShouldEqual, ClassOtherUA)
+			})
+		})
+	})
+}
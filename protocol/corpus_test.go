@@ -0,0 +1,74 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// corpusDir holds captured binary events, one per file, that must round-trip
+// byte for byte. A file named *.le.bin uses little-endian byte order, and one
+// named *.v2.bin uses protocol version 2. Drop new captures in as regression
+// fixtures.
+const corpusDir = "testdata/corpus"
+
+// corpusEvent returns a zero Event configured to read the corpus file name.
+func corpusEvent(name string) *Event {
+	e := new(Event)
+	for _, ext := range strings.Split(strings.TrimSuffix(name, ".bin"), ".")[1:] {
+		switch ext {
+		case "le":
+			e.ByteOrder = binary.LittleEndian
+		case "v2":
+			e.Version = Version2
+		}
+	}
+
+	return e
+}
+
+// roundTrip reads an Event from b and returns the number of bytes read and the
+// Event marshaled back to binary.
+func roundTrip(e *Event, b []byte) (int64, []byte, error) {
+	n, err := e.ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		return n, nil, err
+	}
+	out, err := e.MarshalBinary()
+
+	return n, out, err
+}
+
+func TestEvent_RoundTripCorpus(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(corpusDir, "*.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no events in %s", corpusDir)
+	}
+
+	for _, path := range paths {
+		Convey("Given the captured event "+filepath.Base(path), t, func() {
+			b, err := os.ReadFile(path)
+			So(err, ShouldBeNil)
+			e := corpusEvent(filepath.Base(path))
+
+			Convey("When reading it and marshaling it back to binary", func() {
+				n, out, err := roundTrip(e, b)
+
+				Convey("It should reproduce the capture byte for byte", func() {
+					So(err, ShouldBeNil)
+					So(n, ShouldEqual, len(b))
+					So(e.Valid(), ShouldBeTrue)
+					So(out, ShouldResemble, b)
+				})
+			})
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package protocol
+
+// crc16Table is the lookup table of the CRC-16/CCITT-FALSE polynomial, 0x1021.
+var crc16Table = func() (t [256]uint16) {
+	for i := range t {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		t[i] = crc
+	}
+
+	return t
+}()
+
+// crc16 returns the CRC-16/CCITT-FALSE checksum of b, which version 2 events
+// use in lieu of CRC-32.
+func crc16(b []byte) uint16 {
+	return crc16Update(0xffff, b)
+}
+
+// crc16Update returns the CRC-16/CCITT-FALSE checksum crc updated with b.
+func crc16Update(crc uint16, b []byte) uint16 {
+	for _, c := range b {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^c]
+	}
+
+	return crc
+}
+
+// crc16Writer computes the CRC-16/CCITT-FALSE checksum of the bytes written
+// to it.
+type crc16Writer struct {
+	crc uint16
+}
+
+// newCRC16Writer returns a writer with the checksum of no bytes.
+func newCRC16Writer() *crc16Writer { return &crc16Writer{crc: 0xffff} }
+
+// Write implements the io.Writer interface.
+func (w *crc16Writer) Write(b []byte) (int, error) {
+	w.crc = crc16Update(w.crc, b)
+
+	return len(b), nil
+}
+
+// Sum32 returns the checksum of the bytes written so far.
+func (w *crc16Writer) Sum32() uint32 { return uint32(w.crc) }
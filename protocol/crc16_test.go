@@ -0,0 +1,21 @@
+package protocol
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_crc16(t *testing.T) {
+	Convey("Given the standard check input", t, func() {
+		b := []byte("123456789")
+
+		Convey("When computing its CRC-16/CCITT-FALSE checksum", func() {
+			sum := crc16(b)
+
+			Convey("It should match the standard check value", func() {
+				So(sum, ShouldEqual, 0x29b1)
+			})
+		})
+	})
+}
@@ -3,12 +3,15 @@
 package protocol
 
 import (
+	"bytes"
 	"encoding"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
 	"net/netip"
+	"time"
 )
 
 const (
@@ -25,11 +28,32 @@ const (
 	TELNET Protocol = 0x23
 )
 
+const (
+	// Version1 is the original event layout: an IPv4 Submitter and a CRC-32
+	// CheckSum.
+	Version1 uint8 = 1
+
+	// Version2 is the event layout with a 16-byte IPv6 Submitter and a
+	// CRC-16/CCITT-FALSE CheckSum.
+	Version2 uint8 = 2
+)
+
 // Protocol is a network protocol type
 type Protocol uint16
 
-// String implements the fmt.Stringer interface.
+// String implements the fmt.Stringer interface. Names registered with
+// RegisterProtocol take precedence over the built-in names.
 func (p Protocol) String() string {
+	if name, ok := registeredName(p); ok {
+		return name
+	}
+
+	return builtinName(p)
+}
+
+// builtinName returns the name of the built-in protocol, regardless of any
+// name registered for it, or UNKNOWN.
+func builtinName(p Protocol) string {
 	s := "UNKNOWN"
 
 	switch p {
@@ -49,8 +73,16 @@ func (p Protocol) String() string {
 var (
 	_ encoding.BinaryMarshaler = (*Event)(nil)
 	_ io.ReaderFrom            = (*Event)(nil)
+	_ io.WriterTo              = (*Event)(nil)
 )
 
+// ByteOrder is a byte order capable of both reading and appending integers.
+// Both binary.BigEndian and binary.LittleEndian satisfy it.
+type ByteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
 // Event is a server-emitted event.
 type Event struct {
 	NodeID    uint16
@@ -64,6 +96,142 @@ type Event struct {
 
 	PayloadBytes []byte
 	IP           netip.Addr
+
+	// PayloadOrder is the Payload's keys in the order they were lexed from the
+	// PayloadBytes, so FormatPayload can reproduce the original layout. A key
+	// repeated in the PayloadBytes appears once, at its first position.
+	PayloadOrder []string
+
+	// ByteOrder is the byte order of the Event's binary representation. Nil
+	// defaults to binary.BigEndian. Legacy emitters send little-endian
+	// integers.
+	ByteOrder ByteOrder
+
+	// Base64Payload indicates the emitter base64-encodes the PayloadBytes.
+	// The CheckSum still covers the encoded PayloadBytes; only parsing them
+	// into the Payload map is affected.
+	Base64Payload bool
+
+	// ReceivedAt is when the client received the Event. It isn't part of the
+	// Event's binary representation.
+	ReceivedAt time.Time
+
+	// Raw is the complete datagram the Event arrived in, as received, if the
+	// client retains it, so a proxy can forward the Event byte for byte. It
+	// may hold other Events or bytes the parser ignored. It isn't part of the
+	// Event's binary representation, nor of its comparison.
+	Raw []byte
+
+	// sum is the checksum ReadFrom computed over the bytes it received, if
+	// summed, so validation needn't rely on re-marshaling the Event's fields.
+	sum    uint32
+	summed bool
+
+	// Version is the protocol version of the Event's binary representation.
+	// Zero means Version1.
+	Version uint8
+
+	// Layout overrides the field widths of the Event's binary representation,
+	// which otherwise follow its Version. A value too wide for its Event
+	// field fails to read, and one too wide for its width is truncated when
+	// marshaled.
+	Layout *Layout
+
+	// NormalizeKeys indicates payload keys are lowercased and trimmed of
+	// surrounding white space when parsed, so keys differing only in case
+	// (e.g., "User-Agent" and "user-agent") match.
+	NormalizeKeys bool
+
+	// Flatten maps payload keys whose values hold nested key=value pairs,
+	// separated by semicolons, to the prefix under which those pairs are
+	// promoted into the Payload (e.g., "user-agent" to "ua" for ua.browser).
+	Flatten map[string]string
+
+	// MaxPayloadBytes, if positive, is the largest Size ReadFrom accepts. It
+	// rejects a larger Size with a PayloadSizeError before allocating the
+	// PayloadBytes.
+	MaxPayloadBytes int
+}
+
+// CompareEvents returns -1, 0, or 1 if a sorts before, the same as, or after b
+// in canonical order: ascending by TimeStamp, then by EventUUID bytes, then by
+// CheckSum. Sorting events canonically makes their order independent of their
+// arrival order.
+func CompareEvents(a, b *Event) int {
+	switch {
+	case a.TimeStamp < b.TimeStamp:
+		return -1
+	case a.TimeStamp > b.TimeStamp:
+		return 1
+	}
+
+	if c := bytes.Compare(a.EventUUID.marshalBinary(), b.EventUUID.marshalBinary()); c != 0 {
+		return c
+	}
+
+	switch {
+	case a.CheckSum < b.CheckSum:
+		return -1
+	case a.CheckSum > b.CheckSum:
+		return 1
+	}
+
+	return 0
+}
+
+// Explain writes a field-by-field breakdown of the Event's binary equivalent
+// to w: each field's offset, name, raw bytes, and decoded value.
+func (e *Event) Explain(w io.Writer) error {
+	b, err := e.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var (
+		order    = e.order()
+		l        = e.layout()
+		valid    = "invalid"
+		checkSum = fmt.Sprintf("0x%0*x", l.CheckSum*2, e.CheckSum)
+	)
+	if e.Valid() {
+		valid = "valid"
+	}
+
+	fields := []struct {
+		name  string
+		size  int
+		value string
+	}{
+		{"NodeID", l.NodeID, fmt.Sprintf("%d", e.NodeID)},
+		{"TimeStamp", l.TimeStamp, fmt.Sprintf("%d (%s)", e.TimeStamp,
+			time.Unix(int64(e.TimeStamp), 0).UTC().Format(time.RFC3339))},
+		{"Size", l.Size, fmt.Sprintf("%d", e.Size)},
+		{"EventUUID", 16, e.EventUUID.String()},
+		{"Payload", len(e.PayloadBytes), fmt.Sprintf("%q", e.PayloadBytes)},
+		{"Protocol", 2, e.Protocol.String()},
+		{"Submitter", l.Submitter, e.submitterAddr().String()},
+		{"CheckSum", l.CheckSum, fmt.Sprintf("%s (%s)", checkSum, valid)},
+	}
+
+	_, err = fmt.Fprintf(w, "%-6s  %-9s  %-47s  %s\n", "Offset", "Field", "Raw Bytes", "Value")
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for _, f := range fields {
+		_, err = fmt.Fprintf(w, "%06d  %-9s  %-47s  %s\n",
+			offset, f.name, fmt.Sprintf("% x", b[offset:offset+f.size]), f.value,
+		)
+		if err != nil {
+			return err
+		}
+		offset += f.size
+	}
+
+	_, err = fmt.Fprintf(w, "%d bytes; %s byte order\n", len(b), order.String())
+
+	return err
 }
 
 // MarshalBinary implements the encoding.BinaryMarshaler interface.
@@ -71,91 +239,297 @@ type Event struct {
 // This method marshals the entire Event object to its binary equivalent,
 // including its CheckSum.
 func (e *Event) MarshalBinary() ([]byte, error) {
-	return binary.BigEndian.AppendUint32(e.marshalBinary(), e.CheckSum), nil
+	return appendUint(e.marshalBinary(), e.order(), e.layout().CheckSum, uint64(e.CheckSum)), nil
+}
+
+// Fields are the names of an Event's encoded fields, in the order encoded.
+var Fields = []string{"NodeID", "TimeStamp", "Size", "EventUUID", "PayloadBytes", "Protocol", "Submitter", "CheckSum"}
+
+// fieldDescriptions describe the Fields in error messages.
+var fieldDescriptions = map[string]string{
+	"NodeID":       "node ID",
+	"TimeStamp":    "time stamp",
+	"Size":         "size",
+	"EventUUID":    "UUID",
+	"PayloadBytes": "payload",
+	"Protocol":     "protocol",
+	"Submitter":    "submitter",
+	"CheckSum":     "checksum",
+}
+
+// FieldError reports the field ReadFrom failed to read.
+type FieldError struct {
+	Field string // one of Fields
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("reading %s: %v", fieldDescriptions[e.Field], e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// FieldsRead returns the names of the fields ReadFrom read successfully before
+// failing to read the Field.
+func (e *FieldError) FieldsRead() []string {
+	for i, f := range Fields {
+		if f == e.Field {
+			return Fields[:i:i]
+		}
+	}
+
+	return nil
+}
+
+// PayloadSizeError reports an Event whose Size exceeds its MaxPayloadBytes.
+type PayloadSizeError struct {
+	Size int
+	Max  int
+}
+
+func (e *PayloadSizeError) Error() string {
+	return fmt.Sprintf("payload size %d exceeds the %d-byte limit", e.Size, e.Max)
 }
 
 // ReadFrom implements the io.ReaderFrom interface.
+//
+// ReadFrom computes the CRC-32 checksum of the bytes as it reads them, and
+// parses the payload into the Payload map only if the checksum matches the
+// Event's CheckSum. The Payload of an event that will fail Valid is nil,
+// sparing the lexer the work. The Event's layout determines the width of each
+// field.
 func (e *Event) ReadFrom(r io.Reader) (n int64, err error) {
+	var (
+		order = e.order()
+		l     = e.layout()
+		crc   summer
+		v     uint64
+	)
+	if l.CheckSum == 2 {
+		crc = newCRC16Writer()
+	} else {
+		crc = crc32.NewIEEE()
+	}
+	tr := io.TeeReader(r, crc)
+	e.summed = false
+
 	// NodeID
-	if err = binary.Read(r, binary.BigEndian, &e.NodeID); err != nil {
-		return 0, fmt.Errorf("reading node ID: %w", err)
+	if v, err = readUint(tr, order, l.NodeID, 16); err != nil {
+		return 0, &FieldError{Field: "NodeID", Err: err}
 	}
-	n += 2
+	e.NodeID = uint16(v)
+	n += int64(l.NodeID)
 
 	// TimeStamp
-	if err = binary.Read(r, binary.BigEndian, &e.TimeStamp); err != nil {
-		return n, fmt.Errorf("reading time stamp: %w", err)
+	if v, err = readUint(tr, order, l.TimeStamp, 32); err != nil {
+		return n, &FieldError{Field: "TimeStamp", Err: err}
 	}
-	n += 4
+	e.TimeStamp = uint32(v)
+	n += int64(l.TimeStamp)
 
 	// Size
-	if err = binary.Read(r, binary.BigEndian, &e.Size); err != nil {
-		return n, fmt.Errorf("reading size: %w", err)
+	if v, err = readUint(tr, order, l.Size, 16); err != nil {
+		return n, &FieldError{Field: "Size", Err: err}
 	}
-	n += 2
+	e.Size = uint16(v)
+	n += int64(l.Size)
 
 	// UUID
-	i, err := e.EventUUID.ReadFrom(r)
+	i, err := e.EventUUID.readFrom(tr, order)
 	if err != nil {
-		return n, fmt.Errorf("reading UUID: %w", err)
+		return n, &FieldError{Field: "EventUUID", Err: err}
 	}
 	n += i
 
-	// PayloadBytes
-	e.PayloadBytes = make([]byte, e.Size)
-	j, err := r.Read(e.PayloadBytes)
+	// PayloadBytes, which a streaming reader may return across several reads
+	if e.MaxPayloadBytes > 0 && int(e.Size) > e.MaxPayloadBytes {
+		return n, &FieldError{Field: "PayloadBytes", Err: &PayloadSizeError{Size: int(e.Size), Max: e.MaxPayloadBytes}}
+	}
+	// Size is untrusted, so allocate no more than the reader holds, if it
+	// tells.
+	size := int(e.Size)
+	if lr, ok := r.(interface{ Len() int }); ok && lr.Len() < size {
+		size = lr.Len()
+	}
+	e.PayloadBytes = make([]byte, size)
+	j, err := io.ReadFull(tr, e.PayloadBytes)
+	if err == nil && j < int(e.Size) {
+		err = io.ErrUnexpectedEOF
+		if j == 0 {
+			err = io.EOF
+		}
+	}
 	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return n, &FieldError{Field: "PayloadBytes", Err: fmt.Errorf("read %d of %d bytes", j, e.Size)}
 	case err != nil:
-		return n, fmt.Errorf("reading payload: %w", err)
-	case uint16(j) != e.Size:
-		return n, fmt.Errorf("reading payload: read %d of %d bytes", j, e.Size)
+		return n, &FieldError{Field: "PayloadBytes", Err: err}
 	}
 	n += int64(j)
 
-	// Parse the raw event payload into key:value pairs.
-	parsePayloadRaw(e)
-
 	// Protocol
-	if err = binary.Read(r, binary.BigEndian, &e.Protocol); err != nil {
-		return n, fmt.Errorf("reading protocol: %w", err)
+	if err = binary.Read(tr, order, &e.Protocol); err != nil {
+		return n, &FieldError{Field: "Protocol", Err: err}
 	}
 	n += 2
 
 	// Submitter
-	if err = binary.Read(r, binary.BigEndian, &e.Submitter); err != nil {
-		return n, fmt.Errorf("reading submitter: %w", err)
+	if l.Submitter == 16 {
+		var addr [16]byte
+		if _, err = io.ReadFull(tr, addr[:]); err != nil {
+			return n, &FieldError{Field: "Submitter", Err: err}
+		}
+
+		// An IPv4-mapped address is just as well an IPv4 address.
+		e.IP = netip.AddrFrom16(addr).Unmap()
+		e.Submitter = 0
+		if e.IP.Is4() {
+			a4 := e.IP.As4()
+			e.Submitter = binary.BigEndian.Uint32(a4[:])
+		}
+	} else {
+		if err = binary.Read(tr, order, &e.Submitter); err != nil {
+			return n, &FieldError{Field: "Submitter", Err: err}
+		}
+
+		// Derive the IP address from the uint32.
+		e.IP = e.submitterAddr()
 	}
-	n += 4
+	n += int64(l.Submitter)
 
-	// Derive the IP address from the uint32.
-	var addr [4]byte
-	binary.BigEndian.PutUint32(addr[:], e.Submitter)
-	e.IP = netip.AddrFrom4(addr)
+	// CheckSum, which isn't part of the checksummed bytes.
+	if v, err = readUint(r, order, l.CheckSum, 32); err != nil {
+		return n, &FieldError{Field: "CheckSum", Err: err}
+	}
+	e.CheckSum = uint32(v)
+	n += int64(l.CheckSum)
+	e.sum, e.summed = crc.Sum32(), true
 
-	// CheckSum
-	if err = binary.Read(r, binary.BigEndian, &e.CheckSum); err != nil {
-		return n, fmt.Errorf("reading checksum: %w", err)
+	// Parse the raw event payload into key:value pairs, unless the event
+	// won't validate anyway.
+	e.Payload = nil
+	if e.Valid() {
+		parsePayloadRaw(e)
 	}
-	n += 4
 
 	return n, nil
 }
 
-// Valid returns true if the Event's CheckSum value matches the calculated
-// CRC-32 checksum of all other Event field values using the IEEE polynomial.
+// Valid returns true if the Event's CheckSum value matches the checksum
+// ComputeCheckSum calculates. An Event ReadFrom read is verified against the
+// bytes exactly as received, which re-marshaling its fields may not reproduce
+// (e.g., once its PayloadBytes are re-rendered from Payload).
 func (e *Event) Valid() bool {
-	return crc32.Checksum(e.marshalBinary(), crc32.IEEETable) == e.CheckSum
+	return e.ComputeCheckSum() == e.CheckSum
+}
+
+// ValidWith returns true if the Event's CheckSum value matches the CRC-32
+// checksum ComputeCheckSumWith calculates using the table, as when diagnosing
+// an emitter that uses another polynomial (e.g., crc32.Castagnoli).
+func (e *Event) ValidWith(table *crc32.Table) bool {
+	return e.ComputeCheckSumWith(table) == e.CheckSum
+}
+
+// ComputeCheckSumWith returns the CRC-32 checksum of all Event field values but
+// the CheckSum using the table, regardless of the Event's layout. Only the IEEE
+// table's checksum of an Event ReadFrom read is that of the bytes received;
+// other tables' re-marshal its fields.
+func (e *Event) ComputeCheckSumWith(table *crc32.Table) uint32 {
+	if e.summed && table == crc32.IEEETable && e.layout().CheckSum == 4 {
+		return e.sum
+	}
+
+	return crc32.Checksum(e.marshalBinary(), table)
+}
+
+// ComputeCheckSum returns the CRC-32 checksum of all Event field values but
+// the CheckSum using the IEEE polynomial, or the CRC-16/CCITT-FALSE checksum
+// if the Event's layout has a 2-byte CheckSum, as version 2 does. For an Event
+// ReadFrom read, it's the checksum ReadFrom computed over the bytes received.
+func (e *Event) ComputeCheckSum() uint32 {
+	if e.summed {
+		return e.sum
+	}
+	if e.layout().CheckSum == 2 {
+		return uint32(crc16(e.marshalBinary()))
+	}
+
+	return crc32.Checksum(e.marshalBinary(), crc32.IEEETable)
+}
+
+// summer is a running checksum of the bytes written to it.
+type summer interface {
+	io.Writer
+	Sum32() uint32
+}
+
+// WriteTo implements the io.WriterTo interface.
+//
+// This method writes the Event's binary equivalent, including its CheckSum, to
+// w. Consecutive Events written this way can be read back by an EventReader.
+func (e *Event) WriteTo(w io.Writer) (int64, error) {
+	b, err := e.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(b)
+
+	return int64(n), err
 }
 
 // marshalBinary marshals all fields but the CheckSum to its binary equivalent.
 func (e *Event) marshalBinary() []byte {
-	b := binary.BigEndian.AppendUint16(make([]byte, 0, 32), e.NodeID)
-	b = binary.BigEndian.AppendUint32(b, e.TimeStamp)
-	b = binary.BigEndian.AppendUint16(b, e.Size)
-	b = append(b, e.EventUUID.marshalBinary()...)
+	order := e.order()
+	l := e.layout()
+
+	b := appendUint(make([]byte, 0, 32), order, l.NodeID, uint64(e.NodeID))
+	b = appendUint(b, order, l.TimeStamp, uint64(e.TimeStamp))
+	b = appendUint(b, order, l.Size, uint64(e.Size))
+	b = e.EventUUID.appendBinary(b, order)
 	b = append(b, e.PayloadBytes...)
-	b = binary.BigEndian.AppendUint16(b, uint16(e.Protocol))
-	b = binary.BigEndian.AppendUint32(b, e.Submitter)
+	b = order.AppendUint16(b, uint16(e.Protocol))
+	if l.Submitter == 16 {
+		addr := e.IP.As16()
+		b = append(b, addr[:]...)
+	} else {
+		b = order.AppendUint32(b, e.Submitter)
+	}
 
 	return b
 }
+
+// submitterAddr returns the Submitter's IP address. The IP is the Submitter of
+// an Event whose layout has a 16-byte Submitter, as version 2 does.
+func (e *Event) submitterAddr() netip.Addr {
+	if e.layout().Submitter == 16 {
+		return e.IP
+	}
+
+	var addr [4]byte
+	binary.BigEndian.PutUint32(addr[:], e.Submitter)
+
+	return netip.AddrFrom4(addr)
+}
+
+// order returns the Event's byte order, defaulting to big-endian.
+func (e *Event) order() ByteOrder {
+	if e.ByteOrder == nil {
+		return binary.BigEndian
+	}
+
+	return e.ByteOrder
+}
+
+// layout returns the Event's Layout, defaulting to that of its Version.
+func (e *Event) layout() Layout {
+	switch {
+	case e.Layout != nil:
+		return *e.Layout
+	case e.Version == Version2:
+		return Version2Layout
+	default:
+		return Version1Layout
+	}
+}
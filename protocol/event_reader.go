@@ -0,0 +1,31 @@
+package protocol
+
+import (
+	"errors"
+	"io"
+)
+
+// EventReader reads consecutive Events from a stream of marshaled Events, such
+// as a file populated by Event.WriteTo.
+type EventReader struct {
+	r io.Reader
+}
+
+// NewEventReader returns an EventReader that reads Events from r.
+func NewEventReader(r io.Reader) *EventReader { return &EventReader{r: r} }
+
+// Read returns the next Event in the stream. It returns io.EOF if the stream
+// ends cleanly between Events.
+func (er *EventReader) Read() (*Event, error) {
+	e := new(Event)
+
+	n, err := e.ReadFrom(er.r)
+	switch {
+	case n == 0 && errors.Is(err, io.EOF):
+		return nil, io.EOF
+	case err != nil:
+		return nil, err
+	}
+
+	return e, nil
+}
@@ -0,0 +1,52 @@
+package protocol
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEventReader_Read(t *testing.T) {
+	Convey("Given a stream of marshaled events", t, func() {
+		e := new(Event)
+		_, err := e.ReadFrom(bytes.NewBufferString(payload))
+		So(err, ShouldBeNil)
+
+		buf := new(bytes.Buffer)
+		for i := 0; i < 3; i++ {
+			n, err := e.WriteTo(buf)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, len(payload))
+		}
+
+		Convey("When reading it with an EventReader", func() {
+			er := NewEventReader(buf)
+
+			Convey("It should return each event followed by io.EOF", func() {
+				for i := 0; i < 3; i++ {
+					actual, err := er.Read()
+					So(err, ShouldBeNil)
+					So(actual, ShouldResemble, e)
+				}
+
+				_, err := er.Read()
+				So(err, ShouldEqual, io.EOF)
+			})
+
+			Convey("It should return an error if the stream ends mid-event", func() {
+				buf.Truncate(buf.Len() - 2)
+
+				for i := 0; i < 2; i++ {
+					_, err := er.Read()
+					So(err, ShouldBeNil)
+				}
+
+				_, err := er.Read()
+				So(err, ShouldBeError)
+				So(err, ShouldNotEqual, io.EOF)
+			})
+		})
+	})
+}
@@ -2,8 +2,16 @@ package protocol
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
 	"net/netip"
+	"reflect"
+	"sort"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -22,6 +30,70 @@ var payload = "\x00\x04\x5f\x87\x91\x00\x00\x92\x35\x61\x62\x65\x38\x35\x32\x32"
 	"\x66\x61\x72\x69\x2f\x36\x30\x31\x2e\x31\x00\x0a\xe4\xf7\xb9\xba" +
 	"\x75\x0f\x47\x97"
 
+func BenchmarkEvent_ReadFrom(b *testing.B) {
+	// The same event with its final checksum byte flipped.
+	invalid := []byte(payload)
+	invalid[len(invalid)-1] ^= 0xff
+
+	for _, bm := range []struct {
+		name string
+		b    []byte
+	}{
+		{"valid", []byte(payload)},
+		{"invalid", invalid},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := new(Event).ReadFrom(bytes.NewReader(bm.b)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func FuzzEventReadFrom(f *testing.F) {
+	f.Add([]byte(payload))
+	for _, i := range []int{2, 5, 9, 20, 156, 160, 173, 175, 179} {
+		f.Add([]byte(payload[:len(payload)-i]))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		e := new(Event)
+		n, err := e.ReadFrom(bytes.NewBuffer(b))
+		if err != nil {
+			return
+		}
+
+		if n > int64(len(b)) {
+			t.Fatalf("read %d bytes from %d-byte input", n, len(b))
+		}
+
+		// A successfully read event must marshal back to the bytes it was
+		// read from.
+		mb, err := e.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(mb, b[:n]) {
+			t.Fatalf("marshaled %x; read %x", mb, b[:n])
+		}
+
+		// A valid event must read back from its marshaled bytes unchanged.
+		if !e.Valid() {
+			return
+		}
+		e2 := new(Event)
+		if _, err = e2.ReadFrom(bytes.NewReader(mb)); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(e2, e) {
+			t.Fatalf("read back %+v; want %+v", e2, e)
+		}
+	})
+}
+
 func TestEvent_MarshalBinary(t *testing.T) {
 	Convey("Given a populated Event", t, func() {
 		e := &Event{
@@ -48,7 +120,8 @@ func TestEvent_MarshalBinary(t *testing.T) {
 				0x68, 0x2c, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x3a, 0x53, 0x74, 0x69,
 				0x6e, 0x67, 0x65, 0x72, 0x63, 0x6f, 0x63, 0x6f, 0x6e, 0x75, 0x74,
 			},
-			IP: netip.MustParseAddr("47.120.102.76"),
+			IP:           netip.MustParseAddr("47.120.102.76"),
+			PayloadOrder: []string{"username", "password"},
 		}
 		Convey("When calling its MarshalBinary method", func() {
 			Convey("It should successfully marshal itself to binary", func() {
@@ -62,7 +135,52 @@ func TestEvent_MarshalBinary(t *testing.T) {
 				n, err := e2.ReadFrom(bytes.NewBuffer(b))
 				So(err, ShouldBeNil)
 				So(n, ShouldEqual, 73)
-				So(e2, ShouldResemble, e)
+				So(e2, ShouldResemble, asRead(e))
+			})
+		})
+	})
+}
+
+func TestEvent_MarshalBinaryLittleEndian(t *testing.T) {
+	Convey("Given a populated Event using little-endian byte order", t, func() {
+		e := &Event{
+			NodeID:    0x4,
+			TimeStamp: 0x5f80f980,
+			Size:      0x0c,
+			EventUUID: *uuid,
+			Payload: map[string]string{
+				"username": "bob",
+			},
+			Protocol:     SSH,
+			Submitter:    0x2f78664c,
+			PayloadBytes: []byte("username:bob"),
+			IP:           netip.MustParseAddr("47.120.102.76"),
+			PayloadOrder: []string{"username"},
+			ByteOrder:    binary.LittleEndian,
+		}
+		e.CheckSum = crc32.ChecksumIEEE(e.marshalBinary())
+
+		Convey("When calling its MarshalBinary method", func() {
+			b, err := e.MarshalBinary()
+			So(err, ShouldBeNil)
+
+			Convey("It should write its integers in little-endian byte order", func() {
+				So(b[:2], ShouldResemble, []byte{0x04, 0x00})
+			})
+
+			Convey("It should round-trip through a little-endian Event", func() {
+				e2 := &Event{ByteOrder: binary.LittleEndian}
+				n, err := e2.ReadFrom(bytes.NewBuffer(b))
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, len(b))
+				So(e2, ShouldResemble, asRead(e))
+				So(e2.Valid(), ShouldBeTrue)
+			})
+
+			Convey("It should not validate as a big-endian Event", func() {
+				e2 := new(Event)
+				_, _ = e2.ReadFrom(bytes.NewBuffer(b))
+				So(e2.Valid(), ShouldBeFalse)
 			})
 		})
 	})
@@ -86,6 +204,35 @@ func TestEvent_ReadFrom(t *testing.T) {
 				So(err.Error(), ShouldEqual, "reading checksum: unexpected EOF")
 			})
 
+			Convey("It should report the field it failed to read and those it read", func() {
+				buf.Truncate(buf.Len() - 9)
+				_, err := (new(Event)).ReadFrom(buf)
+
+				var fe *FieldError
+				So(errors.As(err, &fe), ShouldBeTrue)
+				So(fe.Field, ShouldEqual, "Protocol")
+				So(fe.FieldsRead(), ShouldResemble, []string{"NodeID", "TimeStamp", "Size", "EventUUID", "PayloadBytes"})
+				So(errors.Is(err, io.ErrUnexpectedEOF), ShouldBeTrue)
+			})
+
+			Convey("It should reject a Size exceeding MaxPayloadBytes before reading the payload", func() {
+				e := &Event{MaxPayloadBytes: 100}
+				_, err := e.ReadFrom(buf)
+				So(err, ShouldBeError)
+				So(err.Error(), ShouldEqual, "reading payload: payload size 146 exceeds the 100-byte limit")
+
+				var pse *PayloadSizeError
+				So(errors.As(err, &pse), ShouldBeTrue)
+				So(pse.Size, ShouldEqual, 146)
+				So(pse.Max, ShouldEqual, 100)
+				So(e.PayloadBytes, ShouldBeNil)
+			})
+
+			Convey("It should accept a Size at MaxPayloadBytes", func() {
+				_, err := (&Event{MaxPayloadBytes: 146}).ReadFrom(buf)
+				So(err, ShouldBeNil)
+			})
+
 			Convey("It should return an error on short read of the Submitter", func() {
 				buf.Truncate(buf.Len() - 5)
 				_, err := (new(Event)).ReadFrom(buf)
@@ -114,6 +261,15 @@ func TestEvent_ReadFrom(t *testing.T) {
 				So(err.Error(), ShouldEqual, "reading payload: EOF")
 			})
 
+			Convey("It should allocate no more than the datagram holds given an overstated Size", func() {
+				binary.BigEndian.PutUint16(buf.Bytes()[6:8], 0xffff)
+				e := new(Event)
+				_, err := e.ReadFrom(buf)
+				So(err, ShouldBeError)
+				So(err.Error(), ShouldEqual, "reading payload: read 156 of 65535 bytes")
+				So(cap(e.PayloadBytes), ShouldEqual, 156)
+			})
+
 			Convey("It should return an error on short read of the UUID", func() {
 				buf.Truncate(buf.Len() - 160)
 				_, err := (new(Event)).ReadFrom(buf)
@@ -145,6 +301,153 @@ func TestEvent_ReadFrom(t *testing.T) {
 	})
 }
 
+func TestEvent_ReadFromOneByteReader(t *testing.T) {
+	Convey("Given an event returned one byte per read, as a stream might", t, func() {
+		r := iotest.OneByteReader(bytes.NewBufferString(payload))
+
+		Convey("When reading it", func() {
+			e := new(Event)
+			n, err := e.ReadFrom(r)
+
+			Convey("It should read the same event as from a single read", func() {
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, len(payload))
+
+				expected := new(Event)
+				_, err = expected.ReadFrom(bytes.NewBufferString(payload))
+				So(err, ShouldBeNil)
+				So(e, ShouldResemble, expected)
+				So(e.Valid(), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestCompareEvents(t *testing.T) {
+	Convey("Given events differing by time stamp, UUID, and checksum", t, func() {
+		a := &Event{TimeStamp: 1, EventUUID: UUID{TimeLow: 2}, CheckSum: 9}
+		b := &Event{TimeStamp: 2, EventUUID: UUID{TimeLow: 1}, CheckSum: 1}
+		c := &Event{TimeStamp: 2, EventUUID: UUID{TimeLow: 1, Node: [6]byte{1}}, CheckSum: 1}
+		d := &Event{TimeStamp: 2, EventUUID: UUID{TimeLow: 1, Node: [6]byte{1}}, CheckSum: 2}
+
+		Convey("When calling the CompareEvents function", func() {
+			Convey("It should order by time stamp first", func() {
+				So(CompareEvents(a, b), ShouldEqual, -1)
+				So(CompareEvents(b, a), ShouldEqual, 1)
+			})
+
+			Convey("It should order by UUID given equal time stamps", func() {
+				So(CompareEvents(b, c), ShouldEqual, -1)
+				So(CompareEvents(c, b), ShouldEqual, 1)
+			})
+
+			Convey("It should order by checksum given equal time stamps and UUIDs", func() {
+				So(CompareEvents(c, d), ShouldEqual, -1)
+				So(CompareEvents(d, c), ShouldEqual, 1)
+			})
+
+			Convey("It should return zero for equal events", func() {
+				So(CompareEvents(d, d), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestEvent_ReceivedAt(t *testing.T) {
+	Convey("Given a received event", t, func() {
+		e := &Event{ReceivedAt: time.Now()}
+		_, err := e.ReadFrom(bytes.NewBufferString(payload))
+		So(err, ShouldBeNil)
+
+		Convey("When round-tripping it through its binary representation", func() {
+			b, err := e.MarshalBinary()
+			So(err, ShouldBeNil)
+
+			actual := new(Event)
+			_, err = actual.ReadFrom(bytes.NewReader(b))
+			So(err, ShouldBeNil)
+
+			Convey("It should exclude the receive time", func() {
+				So(string(b), ShouldEqual, payload)
+				So(actual.ReceivedAt.IsZero(), ShouldBeTrue)
+				So(actual.Valid(), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestEvent_ReadFromVersion2(t *testing.T) {
+	Convey("Given a version 2 event with an IPv6 submitter", t, func() {
+		e := &Event{
+			Version:      Version2,
+			NodeID:       4,
+			TimeStamp:    1602720000,
+			Size:         14,
+			PayloadBytes: []byte("username:aiden"),
+			Protocol:     SSH,
+			IP:           netip.MustParseAddr("2001:db8::1"),
+		}
+		e.CheckSum = e.ComputeCheckSum()
+		b, err := e.MarshalBinary()
+		So(err, ShouldBeNil)
+
+		Convey("When reading its binary representation as version 2", func() {
+			actual := &Event{Version: Version2}
+			n, err := actual.ReadFrom(bytes.NewReader(b))
+
+			Convey("It should read the wider submitter and the CRC-16 checksum", func() {
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, len(b))
+				So(len(b), ShouldEqual, 2+4+2+16+14+2+16+2)
+				So(e.CheckSum, ShouldBeLessThanOrEqualTo, 0xffff)
+				So(actual.CheckSum, ShouldEqual, e.CheckSum)
+				So(actual.IP, ShouldResemble, e.IP)
+				So(actual.Valid(), ShouldBeTrue)
+				So(actual.Payload, ShouldResemble, map[string]string{"username": "aiden"})
+			})
+		})
+
+		Convey("When reading a corrupted binary representation as version 2", func() {
+			b[len(b)-3] ^= 0x01
+			actual := &Event{Version: Version2}
+			_, err := actual.ReadFrom(bytes.NewReader(b))
+
+			Convey("It should fail to validate", func() {
+				So(err, ShouldBeNil)
+				So(actual.Valid(), ShouldBeFalse)
+				So(actual.Payload, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestEvent_Explain(t *testing.T) {
+	Convey("Given an event read from the server's payload", t, func() {
+		e := new(Event)
+		_, err := e.ReadFrom(bytes.NewBufferString(payload))
+		So(err, ShouldBeNil)
+
+		Convey("When explaining the event", func() {
+			buf := new(bytes.Buffer)
+			err := e.Explain(buf)
+
+			Convey("It should annotate each field", func() {
+				So(err, ShouldBeNil)
+
+				s := buf.String()
+				for _, field := range []string{
+					"NodeID", "TimeStamp", "Size", "EventUUID", "Payload", "Protocol", "Submitter", "CheckSum",
+				} {
+					So(s, ShouldContainSubstring, field)
+				}
+				So(s, ShouldContainSubstring, "000000  NodeID     00 04")
+				So(s, ShouldContainSubstring, "0x750f4797 (valid)")
+				So(s, ShouldContainSubstring, "HTTP")
+			})
+		})
+	})
+}
+
 func TestEvent_Valid(t *testing.T) {
 	Convey("Given a payload of an event emitted by the server", t, func() {
 		buf := bytes.NewBufferString(payload)
@@ -168,10 +471,121 @@ func TestEvent_Valid(t *testing.T) {
 				e.CheckSum++
 				So(e.Valid(), ShouldBeFalse)
 			})
+
+			Convey("It should skip parsing the payload of an event with a bad checksum", func() {
+				b := []byte(payload)
+				b[len(b)-1] ^= 0xff
+
+				e := new(Event)
+				n, err := e.ReadFrom(bytes.NewReader(b))
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, len(payload))
+				So(e.Valid(), ShouldBeFalse)
+				So(e.Payload, ShouldBeNil)
+				So(e.PayloadBytes, ShouldHaveLength, e.Size)
+			})
 		})
 	})
 }
 
+func TestEvent_ValidReceived(t *testing.T) {
+	Convey("Given an event whose payload has several keys", t, func() {
+		e := &Event{
+			NodeID:       4,
+			TimeStamp:    1602720000,
+			PayloadBytes: []byte("username:root,password:toor,email:root@example.com"),
+			Protocol:     SSH,
+			Submitter:    0xc0000201,
+		}
+		e.Size = uint16(len(e.PayloadBytes))
+		e.CheckSum = e.ComputeCheckSum()
+		b, err := e.MarshalBinary()
+		So(err, ShouldBeNil)
+
+		// reorder re-renders the PayloadBytes from the Payload in key order,
+		// as iterating over the map might, so they no longer match the wire.
+		reorder := func(e *Event) {
+			keys := make([]string, 0, len(e.Payload))
+			for key := range e.Payload {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			e.PayloadOrder = keys
+			e.PayloadBytes = []byte(e.FormatPayload())
+		}
+
+		Convey("When reading it and re-rendering its payload", func() {
+			actual := new(Event)
+			_, err := actual.ReadFrom(bytes.NewReader(b))
+			So(err, ShouldBeNil)
+			reorder(actual)
+
+			Convey("It should remain valid, verified against the received bytes", func() {
+				So(string(actual.PayloadBytes), ShouldNotEqual, string(e.PayloadBytes))
+				So(actual.Valid(), ShouldBeTrue)
+				So(actual.ValidWith(crc32.IEEETable), ShouldBeTrue)
+			})
+		})
+
+		Convey("When re-rendering the payload of an event it didn't read", func() {
+			actual := *e
+			actual.Payload = map[string]string{"username": "root", "password": "toor", "email": "root@example.com"}
+			reorder(&actual)
+
+			Convey("It should fall back to re-marshaling, which no longer verifies", func() {
+				So(actual.Valid(), ShouldBeFalse)
+			})
+		})
+
+		Convey("When reading a corrupted copy", func() {
+			corrupted := bytes.Clone(b)
+			corrupted[len(corrupted)-5] ^= 0xff
+			actual := new(Event)
+			_, err := actual.ReadFrom(bytes.NewReader(corrupted))
+			So(err, ShouldBeNil)
+
+			Convey("It should be invalid", func() {
+				So(actual.Valid(), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given a version 2 event with a CRC-16 checksum", t, func() {
+		e := &Event{
+			NodeID:       4,
+			TimeStamp:    1602720000,
+			PayloadBytes: []byte("username:root,password:toor"),
+			Protocol:     SSH,
+			IP:           netip.MustParseAddr("2001:db8::1"),
+			Version:      Version2,
+		}
+		e.Size = uint16(len(e.PayloadBytes))
+		e.CheckSum = e.ComputeCheckSum()
+		b, err := e.MarshalBinary()
+		So(err, ShouldBeNil)
+
+		Convey("When reading it", func() {
+			actual := &Event{Version: Version2}
+			_, err := actual.ReadFrom(bytes.NewReader(b))
+			So(err, ShouldBeNil)
+
+			Convey("It should keep the CRC-16 checksum of the bytes received", func() {
+				So(actual.ComputeCheckSum(), ShouldEqual, e.CheckSum)
+				So(actual.Valid(), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+// asRead returns a copy of the event as ReadFrom leaves it, holding the
+// checksum of the bytes received.
+func asRead(e *Event) *Event {
+	c := *e
+	c.sum, c.summed = e.ComputeCheckSum(), true
+
+	return &c
+}
+
 func TestProtocol_String(t *testing.T) {
 	Convey("Given a Protocol constant", t, func() {
 		Convey("When calling its String method", func() {
@@ -197,3 +611,27 @@ func TestProtocol_String(t *testing.T) {
 		})
 	})
 }
+
+func TestEvent_ValidWith(t *testing.T) {
+	Convey("Given an event checksummed with the Castagnoli polynomial", t, func() {
+		e := &Event{
+			NodeID:       4,
+			TimeStamp:    1602720000,
+			Size:         14,
+			PayloadBytes: []byte("username:aiden"),
+			Protocol:     SSH,
+			Submitter:    0xc0000201,
+		}
+		castagnoli := crc32.MakeTable(crc32.Castagnoli)
+		e.CheckSum = e.ComputeCheckSumWith(castagnoli)
+
+		Convey("When validating it with each polynomial", func() {
+			Convey("It should match only the Castagnoli polynomial", func() {
+				So(e.ValidWith(castagnoli), ShouldBeTrue)
+				So(e.ValidWith(crc32.IEEETable), ShouldBeFalse)
+				So(e.Valid(), ShouldBeFalse)
+				So(e.ComputeCheckSumWith(crc32.IEEETable), ShouldEqual, e.ComputeCheckSum())
+			})
+		})
+	})
+}
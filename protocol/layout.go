@@ -0,0 +1,141 @@
+package protocol
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Layout describes the byte widths of an Event's variable-width fields in its
+// binary representation. The EventUUID, PayloadBytes, and Protocol fields
+// have fixed widths.
+type Layout struct {
+	NodeID    int // 1, 2, 4, or 8
+	TimeStamp int // 1, 2, 4, or 8
+	Size      int // 1, 2, 4, or 8
+	Submitter int // 4 for an IPv4 address; 16 for an IPv6 address
+	CheckSum  int // 4 for a CRC-32; 2 for a CRC-16/CCITT-FALSE
+}
+
+var (
+	// Version1Layout is the original event layout.
+	Version1Layout = Layout{NodeID: 2, TimeStamp: 4, Size: 2, Submitter: 4, CheckSum: 4}
+
+	// Version2Layout is the event layout with an IPv6 Submitter and a CRC-16
+	// CheckSum.
+	Version2Layout = Layout{NodeID: 2, TimeStamp: 4, Size: 2, Submitter: 16, CheckSum: 2}
+)
+
+// ParseLayout returns the base Layout with the widths in s overridden. The
+// string s is comma-separated field=width pairs, where the field names are
+// those of the Layout, ignoring case (e.g., "NodeID=4,Size=4").
+func ParseLayout(base Layout, s string) (Layout, error) {
+	l := base
+	if s == "" {
+		return l, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		field, width, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return l, fmt.Errorf("field width %q isn't field=width", pair)
+		}
+		w, err := strconv.Atoi(strings.TrimSpace(width))
+		if err != nil {
+			return l, fmt.Errorf("field width %q: %w", pair, err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "nodeid":
+			l.NodeID = w
+		case "timestamp":
+			l.TimeStamp = w
+		case "size":
+			l.Size = w
+		case "submitter":
+			l.Submitter = w
+		case "checksum":
+			l.CheckSum = w
+		default:
+			return l, fmt.Errorf("unknown field %q; use NodeID, TimeStamp, Size, Submitter, or CheckSum", field)
+		}
+	}
+
+	return l, l.Validate()
+}
+
+// Validate returns an error if any of the Layout's widths is unsupported.
+func (l Layout) Validate() error {
+	for _, f := range []struct {
+		name  string
+		width int
+	}{{"NodeID", l.NodeID}, {"TimeStamp", l.TimeStamp}, {"Size", l.Size}} {
+		switch f.width {
+		case 1, 2, 4, 8:
+		default:
+			return fmt.Errorf("%s width %d isn't 1, 2, 4, or 8 bytes", fieldDescriptions[f.name], f.width)
+		}
+	}
+	if l.Submitter != 4 && l.Submitter != 16 {
+		return fmt.Errorf("%s width %d isn't 4 or 16 bytes", fieldDescriptions["Submitter"], l.Submitter)
+	}
+	if l.CheckSum != 2 && l.CheckSum != 4 {
+		return fmt.Errorf("%s width %d isn't 2 or 4 bytes", fieldDescriptions["CheckSum"], l.CheckSum)
+	}
+
+	return nil
+}
+
+// FixedBytes returns the length of an Event laid out as l, less its payload.
+func (l Layout) FixedBytes() int {
+	// The EventUUID is 16 bytes and the Protocol 2.
+	return l.NodeID + l.TimeStamp + l.Size + 16 + 2 + l.Submitter + l.CheckSum
+}
+
+// String returns the Layout in the form ParseLayout accepts.
+func (l Layout) String() string {
+	return fmt.Sprintf("NodeID=%d,TimeStamp=%d,Size=%d,Submitter=%d,CheckSum=%d",
+		l.NodeID, l.TimeStamp, l.Size, l.Submitter, l.CheckSum)
+}
+
+// readUint reads an unsigned integer of the given width in bytes, returning
+// an error if its value overflows the given number of bits.
+func readUint(r io.Reader, order ByteOrder, width, bits int) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:width]); err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	switch width {
+	case 1:
+		v = uint64(b[0])
+	case 2:
+		v = uint64(order.Uint16(b[:]))
+	case 4:
+		v = uint64(order.Uint32(b[:]))
+	default:
+		v = order.Uint64(b[:])
+	}
+	if bits < 64 && v>>bits != 0 {
+		return 0, fmt.Errorf("%d overflows %d bits", v, bits)
+	}
+
+	return v, nil
+}
+
+// appendUint appends v as an unsigned integer of the given width in bytes,
+// truncating it should it not fit.
+func appendUint(b []byte, order ByteOrder, width int, v uint64) []byte {
+	switch width {
+	case 1:
+		return append(b, byte(v))
+	case 2:
+		return order.AppendUint16(b, uint16(v))
+	case 4:
+		return order.AppendUint32(b, uint32(v))
+	default:
+		return order.AppendUint64(b, v)
+	}
+}
@@ -0,0 +1,125 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseLayout(t *testing.T) {
+	Convey("Given field width overrides", t, func() {
+		Convey("When parsing them over the version 1 layout", func() {
+			l, err := ParseLayout(Version1Layout, "nodeid=4, CheckSum=2")
+
+			Convey("It should override only the given fields", func() {
+				So(err, ShouldBeNil)
+				So(l, ShouldResemble, Layout{NodeID: 4, TimeStamp: 4, Size: 2, Submitter: 4, CheckSum: 2})
+				So(l.String(), ShouldEqual, "NodeID=4,TimeStamp=4,Size=2,Submitter=4,CheckSum=2")
+			})
+		})
+
+		Convey("When parsing malformed overrides", func() {
+			for _, s := range []string{"NodeID", "NodeID=four", "NodeID=3", "Protocol=4", "Submitter=8", "CheckSum=8"} {
+				_, err := ParseLayout(Version1Layout, s)
+
+				Convey("It should reject "+s, func() {
+					So(err, ShouldBeError)
+				})
+			}
+		})
+	})
+}
+
+func TestLayout_FixedBytes(t *testing.T) {
+	Convey("Given events laid out by each version and a wider layout", t, func() {
+		wide := Layout{NodeID: 8, TimeStamp: 8, Size: 4, Submitter: 4, CheckSum: 4}
+		for _, e := range []*Event{
+			{Version: Version1, PayloadBytes: []byte("username:aiden")},
+			{Version: Version2, PayloadBytes: []byte("username:aiden")},
+			{Layout: &wide, PayloadBytes: []byte("username:aiden")},
+		} {
+			e.Size = uint16(len(e.PayloadBytes))
+
+			Convey("When marshaling one laid out as "+e.layout().String(), func() {
+				b, err := e.MarshalBinary()
+				So(err, ShouldBeNil)
+
+				Convey("It should take the layout's fixed bytes besides its payload", func() {
+					So(e.layout().FixedBytes(), ShouldEqual, len(b)-len(e.PayloadBytes))
+				})
+			})
+		}
+	})
+}
+
+func TestEvent_ReadFromLayout(t *testing.T) {
+	Convey("Given an event from an emitter with a 4-byte NodeID", t, func() {
+		layout := Layout{NodeID: 4, TimeStamp: 4, Size: 2, Submitter: 4, CheckSum: 4}
+		e := &Event{
+			Layout:       &layout,
+			NodeID:       0xbeef,
+			TimeStamp:    0x5f87000a, // misread by the default layout as a Size of 10
+			Size:         14,
+			PayloadBytes: []byte("username:aiden"),
+			Protocol:     SSH,
+			Submitter:    0xc0000201,
+		}
+		e.CheckSum = e.ComputeCheckSum()
+		b, err := e.MarshalBinary()
+		So(err, ShouldBeNil)
+		So(b[:4], ShouldResemble, []byte{0x00, 0x00, 0xbe, 0xef})
+
+		Convey("When reading it with the same layout", func() {
+			actual := &Event{Layout: &layout}
+			n, err := actual.ReadFrom(bytes.NewReader(b))
+
+			Convey("It should read the wider NodeID and validate", func() {
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, len(b))
+				So(len(b), ShouldEqual, 4+4+2+16+14+2+4+4)
+				So(actual.NodeID, ShouldEqual, 0xbeef)
+				So(actual.TimeStamp, ShouldEqual, e.TimeStamp)
+				So(actual.IP.String(), ShouldEqual, "192.0.2.1")
+				So(actual.Valid(), ShouldBeTrue)
+				So(actual.Payload, ShouldResemble, map[string]string{"username": "aiden"})
+			})
+		})
+
+		Convey("When reading it with the default layout", func() {
+			actual := new(Event)
+			_, err := actual.ReadFrom(bytes.NewReader(b))
+
+			Convey("It should misread it", func() {
+				So(err, ShouldBeNil)
+				So(actual.NodeID, ShouldEqual, 0)
+				So(actual.TimeStamp, ShouldEqual, 0xbeef5f87)
+				So(actual.Size, ShouldEqual, 0x000a)
+				So(actual.Valid(), ShouldBeFalse)
+				So(actual.Payload, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When reading a NodeID too wide for the Event", func() {
+			b[0] = 0x01
+			_, err := (&Event{Layout: &layout}).ReadFrom(bytes.NewReader(b))
+
+			Convey("It should report the overflow", func() {
+				var fe *FieldError
+				So(errors.As(err, &fe), ShouldBeTrue)
+				So(fe.Field, ShouldEqual, "NodeID")
+				So(err.Error(), ShouldEqual, "reading node ID: 16826095 overflows 16 bits")
+			})
+		})
+
+		Convey("When reading a truncated CheckSum", func() {
+			_, err := (&Event{Layout: &layout}).ReadFrom(bytes.NewReader(b[:len(b)-1]))
+
+			Convey("It should fail to read the CheckSum", func() {
+				So(errors.Is(err, io.ErrUnexpectedEOF), ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -13,6 +13,11 @@ const (
 	pairSeparator = ","
 	separator     = ":"
 
+	// Nested pairs within a value use distinct separators, e.g.,
+	// user-agent:browser=Firefox;os=Linux.
+	nestedPairSeparator = ";"
+	nestedSeparator     = "="
+
 	eof = -1
 )
 
@@ -33,12 +38,14 @@ type stateFn func(*lexer) stateFn
 // This is based on Rob Pike's Lexical Scanning talk:
 // https://www.youtube.com/watch?v=HxaD_trXwRE
 type lexer struct {
-	input  string
-	start  int
-	pos    int
-	width  int
-	state  stateFn
-	tokens chan token
+	input   string
+	sep     string
+	pairSep string
+	start   int
+	pos     int
+	width   int
+	state   stateFn
+	tokens  chan token
 }
 
 func (l *lexer) acceptUntil(c string) {
@@ -111,10 +118,15 @@ func (l *lexer) run() {
 	close(l.tokens)
 }
 
-func lex(input string) *lexer {
+func lex(input string) *lexer { return lexWith(input, separator, pairSeparator) }
+
+// lexWith lexes the input using the given key:value and pair separators.
+func lexWith(input, sep, pairSep string) *lexer {
 	l := &lexer{
-		input:  input,
-		tokens: make(chan token),
+		input:   input,
+		sep:     sep,
+		pairSep: pairSep,
+		tokens:  make(chan token),
 	}
 
 	go l.run()
@@ -123,21 +135,29 @@ func lex(input string) *lexer {
 }
 
 func lexKey(l *lexer) stateFn {
-	l.acceptUntil(separator)
+	l.acceptUntil(l.sep)
 	l.emit(tokenKey)
 
+	if l.isEOF() {
+		// The key has no separator or value. There's nothing left to lex, and
+		// advancing past the missing separator would overrun the input.
+		l.emit(tokenEOF)
+
+		return nil
+	}
+
 	return lexSeparator
 }
 
 func lexPairSeparator(l *lexer) stateFn {
-	l.pos += len(pairSeparator)
+	l.pos += len(l.pairSep)
 	l.ignore()
 
 	return lexKey
 }
 
 func lexSeparator(l *lexer) stateFn {
-	l.pos += len(separator)
+	l.pos += len(l.sep)
 	l.ignore()
 
 	return lexValue
@@ -149,10 +169,10 @@ func lexValue(l *lexer) stateFn {
 		nextState stateFn
 	)
 
-	if l.index(separator) >= 0 && l.first(pairSeparator, separator) == pairSeparator {
+	if l.index(l.sep) >= 0 && l.first(l.pairSep, l.sep) == l.pairSep {
 		// There are multiple key:value pairs in the input. Lex the key:value
 		// pair separator.
-		tok = pairSeparator
+		tok = l.pairSep
 		nextState = lexPairSeparator
 	}
 
@@ -39,6 +39,21 @@ func Test_lex(t *testing.T) {
 				}
 			})
 
+			Convey("It should return a key and EOF given a key with no separator", func() {
+				input := "like Gecko) Version/9"
+				expected := []token{
+					{typ: tokenKey, pos: 21, val: "like Gecko) Version/9"},
+					{typ: tokenEOF, pos: 21},
+				}
+
+				l := lex(input)
+				for _, tok := range expected {
+					So(<-l.tokens, ShouldResemble, tok)
+				}
+				_, ok := <-l.tokens
+				So(ok, ShouldBeFalse)
+			})
+
 			Convey("It should return expected user-agent tokens", func() {
 				input := "user-agent:Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_1) AppleWebKit/602.2.14 (KHTML, like Gecko) Version/10.0.1 Safari/602.2.14"
 				expected := []token{
@@ -1,27 +1,128 @@
 package protocol
 
+import (
+	"encoding/base64"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
 // parsePayloadRaw parses the key:value pairs from the Event.PayloadBytes field
 // and stores them in the Event.Payload map.
 //
 // Here, too, we're expecting well-formed tokenKey:tokenValue pairs before
 // encountering a tokenEOF. Were this a real-world function, we'd expect the
 // lexer to emit errors we'd handle here.
+//
+// If the Event's Base64Payload field is true, the PayloadBytes are decoded
+// before lexing. PayloadBytes that fail to decode are lexed as is. If its
+// NormalizeKeys field is true, keys are lowercased and trimmed. Values of the
+// keys in its Flatten field are then flattened (see flattenPayload).
 func parsePayloadRaw(e *Event) {
 	e.Payload = make(map[string]string)
+	e.PayloadOrder = nil
+
+	input := string(e.PayloadBytes)
+	if e.Base64Payload {
+		if b, err := base64.StdEncoding.DecodeString(input); err == nil {
+			input = string(b)
+		}
+	}
 
-	var (
-		key string
-		l   = lex(string(e.PayloadBytes))
-	)
+	lexPairs(lex(input), func(key, value string) {
+		if e.NormalizeKeys {
+			key = strings.ToLower(strings.TrimSpace(key))
+		}
+		if _, ok := e.Payload[key]; !ok {
+			e.PayloadOrder = append(e.PayloadOrder, key)
+		}
+		e.Payload[key] = value
+	})
 
+	flattenPayload(e)
+}
+
+// flattenPayload re-lexes the values of the keys in the Event's Flatten field
+// as nested key=value pairs separated by semicolons, promoting each into the
+// Payload under the key's prefix and a dot, e.g., user-agent:browser=Firefox
+// yields ua.browser:Firefox given the prefix "ua". The original pair remains,
+// and promoted keys are absent from the PayloadOrder, so FormatPayload still
+// reproduces the PayloadBytes.
+func flattenPayload(e *Event) {
+	for key, prefix := range e.Flatten {
+		value, ok := e.Payload[key]
+		if !ok {
+			continue
+		}
+
+		lexPairs(lexWith(value, nestedSeparator, nestedPairSeparator), func(k, v string) {
+			if k = strings.TrimSpace(k); k != "" {
+				e.Payload[prefix+"."+k] = strings.TrimSpace(v)
+			}
+		})
+	}
+}
+
+// lexPairs calls fn with each key and value the lexer emits. A key without a
+// value is ignored.
+func lexPairs(l *lexer, fn func(key, value string)) {
+	var key string
 	for t := range l.tokens {
 		switch t.typ {
 		case tokenEOF:
-			return
+			// The lexer closes its tokens after the EOF, ending the range.
 		case tokenKey:
 			key = t.val
 		case tokenValue:
-			e.Payload[key] = t.val
+			fn(key, t.val)
+		}
+	}
+}
+
+// FormatPayload returns the Payload's key:value pairs in PayloadOrder, joined
+// as they're lexed. Given a Payload parsed from PayloadBytes without
+// normalizing keys or repeating any, it reproduces the PayloadBytes, decoded
+// if the Event's Base64Payload field is true.
+func (e *Event) FormatPayload() string {
+	var b strings.Builder
+	for i, key := range e.PayloadOrder {
+		if i > 0 {
+			b.WriteString(pairSeparator)
+		}
+		b.WriteString(key)
+		b.WriteString(separator)
+		b.WriteString(e.Payload[key])
+	}
+
+	return b.String()
+}
+
+// PayloadCharsetValid returns true if every key and value in the Payload is
+// valid UTF-8 free of control characters and other non-printable runes. An
+// emitter sends printable text, so violations suggest corruption or injection
+// even when the event's checksum is valid.
+func (e *Event) PayloadCharsetValid() bool {
+	for key, value := range e.Payload {
+		if !printable(key) || !printable(value) {
+			return false
 		}
 	}
+
+	return true
+}
+
+// printable returns true if s is valid UTF-8 made up of printable runes.
+func printable(s string) bool {
+	for len(s) > 0 {
+		r, w := utf8.DecodeRuneInString(s)
+		if r == utf8.RuneError && w <= 1 {
+			return false
+		}
+		if !unicode.IsPrint(r) {
+			return false
+		}
+		s = s[w:]
+	}
+
+	return true
 }
@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"encoding/base64"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -22,6 +23,25 @@ func Test_parsePayload(t *testing.T) {
 				So(e.Payload, ShouldResemble, expected)
 			})
 
+			Convey("It should record the keys in lex order", func() {
+				e := &Event{
+					PayloadBytes: []byte("zeta:1,alpha:2,mu:3,alpha:4"),
+				}
+
+				parsePayloadRaw(e)
+				So(e.PayloadOrder, ShouldResemble, []string{"zeta", "alpha", "mu"})
+				So(e.Payload["alpha"], ShouldEqual, "4")
+			})
+
+			Convey("It should reproduce the PayloadBytes when formatted", func() {
+				e := &Event{
+					PayloadBytes: []byte("username:alexander,password:Scribeapple,email:a@example.net"),
+				}
+
+				parsePayloadRaw(e)
+				So(e.FormatPayload(), ShouldEqual, string(e.PayloadBytes))
+			})
+
 			Convey("It should succeed when parsing an email", func() {
 				e := &Event{
 					PayloadBytes: []byte("email:liamwilson186@example.net"),
@@ -34,6 +54,82 @@ func Test_parsePayload(t *testing.T) {
 				So(e.Payload, ShouldResemble, expected)
 			})
 
+			Convey("It should decode a base64-encoded payload", func() {
+				e := &Event{
+					Base64Payload: true,
+					PayloadBytes:  []byte(base64.StdEncoding.EncodeToString([]byte("username:x,password:y"))),
+				}
+				expected := map[string]string{
+					"username": "x",
+					"password": "y",
+				}
+
+				parsePayloadRaw(e)
+				So(e.Payload, ShouldResemble, expected)
+			})
+
+			Convey("It should fall back to the raw payload if it isn't base64", func() {
+				e := &Event{
+					Base64Payload: true,
+					PayloadBytes:  []byte("username:x,password:y"),
+				}
+				expected := map[string]string{
+					"username": "x",
+					"password": "y",
+				}
+
+				parsePayloadRaw(e)
+				So(e.Payload, ShouldResemble, expected)
+			})
+
+			Convey("It should normalize mixed-case keys", func() {
+				e := &Event{
+					NormalizeKeys: true,
+					PayloadBytes:  []byte(" UserName :x,PASSWORD:Y"),
+				}
+				expected := map[string]string{
+					"username": "x",
+					"password": "Y",
+				}
+
+				parsePayloadRaw(e)
+				So(e.Payload, ShouldResemble, expected)
+			})
+
+			Convey("It should leave keys as is without normalization", func() {
+				e := &Event{PayloadBytes: []byte("User-Agent:x")}
+
+				parsePayloadRaw(e)
+				So(e.Payload, ShouldResemble, map[string]string{"User-Agent": "x"})
+			})
+
+			Convey("It should flatten nested pairs under a prefix, keeping the raw bytes", func() {
+				raw := "user-agent:browser=Firefox; os=Linux,email:a@example.com,other:plain"
+				e := &Event{
+					Flatten:      map[string]string{"user-agent": "ua", "other": "other", "absent": "x"},
+					PayloadBytes: []byte(raw),
+				}
+				expected := map[string]string{
+					"user-agent": "browser=Firefox; os=Linux",
+					"ua.browser": "Firefox",
+					"ua.os":      "Linux",
+					"email":      "a@example.com",
+					"other":      "plain",
+				}
+
+				parsePayloadRaw(e)
+				So(e.Payload, ShouldResemble, expected)
+				So(string(e.PayloadBytes), ShouldEqual, raw)
+				So(e.FormatPayload(), ShouldEqual, raw)
+			})
+
+			Convey("It should ignore a key with no value", func() {
+				e := &Event{PayloadBytes: []byte("username")}
+
+				parsePayloadRaw(e)
+				So(e.Payload, ShouldBeEmpty)
+			})
+
 			Convey("It should succeed when parsing a user-agent", func() {
 				e := &Event{
 					PayloadBytes: []byte("user-agent:Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_1) AppleWebKit/602.2.14 (KHTML, like Gecko) Version/10.0.1 Safari/602.2.14"),
@@ -48,3 +144,25 @@ func Test_parsePayload(t *testing.T) {
 		})
 	})
 }
+
+func TestEvent_PayloadCharsetValid(t *testing.T) {
+	Convey("Given Events with printable and non-printable payloads", t, func() {
+		cases := map[string]bool{
+			"username:root,password:hunter2":     true,
+			"username:josé,email:a@example.com":  true,
+			"username:root,password:hun\x00ter2": false,
+			"username:ro\x1bot":                  false,
+			"password:\xff\xfe":                  false,
+		}
+
+		Convey("When checking their payload charsets", func() {
+			Convey("It should reject control characters and invalid UTF-8", func() {
+				for payload, valid := range cases {
+					e := &Event{PayloadBytes: []byte(payload)}
+					parsePayloadRaw(e)
+					So(e.PayloadCharsetValid(), ShouldEqual, valid)
+				}
+			})
+		})
+	})
+}
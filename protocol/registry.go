@@ -0,0 +1,93 @@
+package protocol
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// builtins are the protocols the event server is known to emit.
+var builtins = []Protocol{HTTP, SMTP, SSH, TELNET}
+
+// registry holds site-specific protocols registered by RegisterProtocol.
+var registry = struct {
+	sync.RWMutex
+	names map[Protocol]string
+}{names: make(map[Protocol]string)}
+
+// RegisterProtocol associates the given name with the protocol value so
+// Protocol.String and ParseProtocol recognize it. Registering the value of a
+// built-in protocol overrides its name. It returns an error if the name is
+// empty or, ignoring case, is built in or registered for another value, so
+// each name parses to one protocol. It's safe for concurrent use.
+func RegisterProtocol(value Protocol, name string) error {
+	if name == "" {
+		return fmt.Errorf("protocol name for 0x%04x is empty", uint16(value))
+	}
+	for _, v := range builtins {
+		if v != value && strings.EqualFold(builtinName(v), name) {
+			return fmt.Errorf("protocol name %q is built in for 0x%04x", name, uint16(v))
+		}
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	for v, n := range registry.names {
+		if v != value && strings.EqualFold(n, name) {
+			return fmt.Errorf("protocol name %q is registered to 0x%04x", name, uint16(v))
+		}
+	}
+	registry.names[value] = name
+
+	return nil
+}
+
+// Known returns true if the protocol is built in or registered.
+func (p Protocol) Known() bool {
+	if _, ok := registeredName(p); ok {
+		return true
+	}
+
+	for _, value := range builtins {
+		if p == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseProtocol returns the Protocol with the given name, ignoring case.
+// Registered protocols take precedence over built-in protocols. Since
+// RegisterProtocol registers each name to one protocol at most, the result is
+// deterministic.
+func ParseProtocol(name string) (Protocol, error) {
+	registry.RLock()
+	for value, n := range registry.names {
+		if strings.EqualFold(n, name) {
+			registry.RUnlock()
+
+			return value, nil
+		}
+	}
+	registry.RUnlock()
+
+	for _, value := range builtins {
+		if strings.EqualFold(value.String(), name) {
+			return value, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown protocol %q", name)
+}
+
+// registeredName returns the registered name for the protocol value, if any.
+func registeredName(value Protocol) (string, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	name, ok := registry.names[value]
+
+	return name, ok
+}
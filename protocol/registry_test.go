@@ -0,0 +1,128 @@
+package protocol
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseProtocol(t *testing.T) {
+	Convey("Given a protocol name", t, func() {
+		Convey("When parsing it", func() {
+			Convey("It should return the built-in protocol regardless of case", func() {
+				actual, err := ParseProtocol("ssh")
+				So(err, ShouldBeNil)
+				So(actual, ShouldEqual, SSH)
+			})
+
+			Convey("It should return an error for an unknown name", func() {
+				_, err := ParseProtocol("GOPHER")
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func TestProtocol_Known(t *testing.T) {
+	Convey("Given protocol values", t, func() {
+		Convey("When calling their Known method", func() {
+			Convey("It should recognize built-in protocols", func() {
+				So(SSH.Known(), ShouldBeTrue)
+			})
+
+			Convey("It should recognize registered protocols", func() {
+				So(RegisterProtocol(0x41, "VNC"), ShouldBeNil)
+				defer func() {
+					registry.Lock()
+					delete(registry.names, 0x41)
+					registry.Unlock()
+				}()
+
+				So(Protocol(0x41).Known(), ShouldBeTrue)
+			})
+
+			Convey("It should not recognize other values", func() {
+				So(Protocol(0x99).Known(), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestRegisterProtocol(t *testing.T) {
+	Convey("Given a site-specific protocol", t, func() {
+		rdp := Protocol(0x40)
+
+		Convey("When registering it", func() {
+			So(RegisterProtocol(rdp, "RDP"), ShouldBeNil)
+			defer func() {
+				registry.Lock()
+				delete(registry.names, rdp)
+				registry.Unlock()
+			}()
+
+			Convey("It should round-trip between its name and value", func() {
+				So(rdp.String(), ShouldEqual, "RDP")
+
+				actual, err := ParseProtocol(rdp.String())
+				So(err, ShouldBeNil)
+				So(actual, ShouldEqual, rdp)
+			})
+
+			Convey("It should leave the built-in protocols intact", func() {
+				So(HTTP.String(), ShouldEqual, "HTTP")
+			})
+
+			Convey("It should refuse the name for another value, ignoring case", func() {
+				err := RegisterProtocol(0x42, "rdp")
+				So(err, ShouldBeError)
+				So(Protocol(0x42).Known(), ShouldBeFalse)
+
+				actual, err := ParseProtocol("RDP")
+				So(err, ShouldBeNil)
+				So(actual, ShouldEqual, rdp)
+			})
+
+			Convey("It should refuse a built-in protocol's name, ignoring case", func() {
+				So(RegisterProtocol(rdp, "ssh"), ShouldBeError)
+				So(rdp.String(), ShouldEqual, "RDP")
+
+				actual, err := ParseProtocol("SSH")
+				So(err, ShouldBeNil)
+				So(actual, ShouldEqual, SSH)
+			})
+
+			Convey("It should refuse an empty name", func() {
+				So(RegisterProtocol(rdp, ""), ShouldBeError)
+				So(rdp.String(), ShouldEqual, "RDP")
+			})
+
+			Convey("It should allow renaming it", func() {
+				So(RegisterProtocol(rdp, "Remote Desktop"), ShouldBeNil)
+				So(rdp.String(), ShouldEqual, "Remote Desktop")
+			})
+		})
+
+		Convey("When registering it concurrently", func() {
+			done := make(chan struct{})
+			for i := 0; i < 10; i++ {
+				go func() {
+					_ = RegisterProtocol(rdp, "RDP")
+					_ = rdp.String()
+					done <- struct{}{}
+				}()
+			}
+			for i := 0; i < 10; i++ {
+				<-done
+			}
+			defer func() {
+				registry.Lock()
+				delete(registry.names, rdp)
+				registry.Unlock()
+			}()
+
+			Convey("It should register the protocol", func() {
+				So(rdp.String(), ShouldEqual, "RDP")
+			})
+		})
+	})
+}
@@ -3,10 +3,16 @@ package protocol
 import (
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"time"
 )
 
+// uuidEpochOffset is the number of 100-nanosecond intervals between the UUID
+// epoch (1582-10-15) and the Unix epoch.
+const uuidEpochOffset = 0x01b21dd213814000
+
 var _ io.ReaderFrom = (*UUID)(nil)
 
 // UUID is a 128-bit universally unique identifier using the format described
@@ -25,49 +31,65 @@ type UUID struct {
 
 // ReadFrom implements the io.ReaderFrom interface.
 func (u *UUID) ReadFrom(r io.Reader) (n int64, err error) {
+	return u.readFrom(r, binary.BigEndian)
+}
+
+// readFrom reads the UUID's integer fields from r using the given byte order.
+func (u *UUID) readFrom(r io.Reader, order binary.ByteOrder) (n int64, err error) {
 	// TimeLow
-	if err = binary.Read(r, binary.BigEndian, &u.TimeLow); err != nil {
+	if err = binary.Read(r, order, &u.TimeLow); err != nil {
 		return n, fmt.Errorf("reading time low: %w", err)
 	}
 	n += 4
 
 	// TimeMid
-	if err = binary.Read(r, binary.BigEndian, &u.TimeMid); err != nil {
+	if err = binary.Read(r, order, &u.TimeMid); err != nil {
 		return n, fmt.Errorf("reading time mid: %w", err)
 	}
 	n += 2
 
 	// TimeHiAndVersion
-	if err = binary.Read(r, binary.BigEndian, &u.TimeHiAndVersion); err != nil {
+	if err = binary.Read(r, order, &u.TimeHiAndVersion); err != nil {
 		return n, fmt.Errorf("reading time hi and version: %w", err)
 	}
 	n += 2
 
 	// ClockSeqHiAndRes
-	if err = binary.Read(r, binary.BigEndian, &u.ClockSeqHiAndRes); err != nil {
+	if err = binary.Read(r, order, &u.ClockSeqHiAndRes); err != nil {
 		return n, fmt.Errorf("reading clock seq hi and res: %w", err)
 	}
 	n++
 
 	// ClockSeqLow
-	if err = binary.Read(r, binary.BigEndian, &u.ClockSeqLow); err != nil {
+	if err = binary.Read(r, order, &u.ClockSeqLow); err != nil {
 		return n, fmt.Errorf("reading clock seq low: %w", err)
 	}
 	n++
 
-	// Node
-	i, err := r.Read(u.Node[:])
+	// Node, which a streaming reader may return across several reads
+	i, err := io.ReadFull(r, u.Node[:])
 	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return n, fmt.Errorf("reading node: read %d of 6 bytes", i)
 	case err != nil:
 		return n, fmt.Errorf("reading node: %w", err)
-	case i != 6:
-		return n, fmt.Errorf("reading node: read %d of 6 bytes", i)
 	}
 	n += int64(i)
 
 	return n, nil
 }
 
+// NodeIsMAC returns true if the UUID is an RFC 4122 version 1 UUID whose Node
+// field holds an IEEE 802 MAC address. Per the RFC, a version 1 UUID generated
+// without a MAC address must set the multicast bit of the node's first octet.
+func (u *UUID) NodeIsMAC() bool {
+	return u.Version() == 1 && u.ClockSeqHiAndRes&0xc0 == 0x80 && u.Node[0]&0x01 == 0
+}
+
+// NodeString returns the Node field's bytes as a string. The event server
+// appears to embed ASCII text in the Node field rather than a MAC address.
+func (u *UUID) NodeString() string { return string(u.Node[:]) }
+
 // String implements the fmt.Stringer interface.
 func (u *UUID) String() string {
 	dst := make([]byte, 36)
@@ -95,10 +117,31 @@ func (u *UUID) String() string {
 	return string(dst)
 }
 
-func (u *UUID) marshalBinary() []byte {
-	b := binary.BigEndian.AppendUint32([]byte{}, u.TimeLow)
-	b = binary.BigEndian.AppendUint16(b, u.TimeMid)
-	b = binary.BigEndian.AppendUint16(b, u.TimeHiAndVersion)
+// Time returns the timestamp embedded in a version 1 UUID. The boolean is false
+// if the UUID isn't version 1 and therefore has no timestamp.
+func (u *UUID) Time() (time.Time, bool) {
+	if u.Version() != 1 {
+		return time.Time{}, false
+	}
+
+	ts := int64(u.TimeHiAndVersion&0x0fff)<<48 | int64(u.TimeMid)<<32 | int64(u.TimeLow)
+	ts -= uuidEpochOffset
+
+	return time.Unix(ts/1e7, ts%1e7*100).UTC(), true
+}
+
+// Version returns the UUID version stored in the high nibble of the
+// TimeHiAndVersion field.
+func (u *UUID) Version() int { return int(u.TimeHiAndVersion >> 12) }
+
+func (u *UUID) marshalBinary() []byte { return u.appendBinary([]byte{}, binary.BigEndian) }
+
+// appendBinary appends the UUID's binary equivalent to b using the given byte
+// order for its integer fields.
+func (u *UUID) appendBinary(b []byte, order binary.AppendByteOrder) []byte {
+	b = order.AppendUint32(b, u.TimeLow)
+	b = order.AppendUint16(b, u.TimeMid)
+	b = order.AppendUint16(b, u.TimeHiAndVersion)
 	b = append(b, u.ClockSeqHiAndRes, u.ClockSeqLow)
 	b = append(b, u.Node[:]...)
 
@@ -3,6 +3,8 @@ package protocol
 import (
 	"bytes"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -36,6 +38,13 @@ func TestUUID_ReadFrom(t *testing.T) {
 				So(u, ShouldResemble, uuid)
 			})
 
+			Convey("It should read a Node returned one byte per read", func() {
+				n, err := u.ReadFrom(iotest.OneByteReader(buf))
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, 16)
+				So(u, ShouldResemble, uuid)
+			})
+
 			Convey("It should return an error on a short read of the Node", func() {
 				buf.Truncate(buf.Len() - 2)
 				_, err := u.ReadFrom(buf)
@@ -88,6 +97,44 @@ func TestUUID_ReadFrom(t *testing.T) {
 	})
 }
 
+func TestUUID_NodeIsMAC(t *testing.T) {
+	Convey("Given a UUID", t, func() {
+		Convey("When checking whether its node is a MAC address", func() {
+			Convey("It should return false for the event server's UUID", func() {
+				So(uuid.NodeIsMAC(), ShouldBeFalse)
+			})
+
+			Convey("It should return true for a version 1 UUID with a unicast MAC", func() {
+				u := &UUID{
+					TimeHiAndVersion: 0x11ed,
+					ClockSeqHiAndRes: 0x80,
+					Node:             [6]uint8{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e},
+				}
+				So(u.NodeIsMAC(), ShouldBeTrue)
+			})
+
+			Convey("It should return false for a version 1 UUID with a random node", func() {
+				u := &UUID{
+					TimeHiAndVersion: 0x11ed,
+					ClockSeqHiAndRes: 0x80,
+					Node:             [6]uint8{0x01, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e},
+				}
+				So(u.NodeIsMAC(), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestUUID_NodeString(t *testing.T) {
+	Convey("Given a valid UUID", t, func() {
+		Convey("When converting its node to a string", func() {
+			Convey("It should return the expected ASCII", func() {
+				So(uuid.NodeString(), ShouldEqual, "f58-11")
+			})
+		})
+	})
+}
+
 func TestUUID_String(t *testing.T) {
 	Convey("Given a valid UUID", t, func() {
 		Convey("When converting its value to a string", func() {
@@ -98,3 +145,40 @@ func TestUUID_String(t *testing.T) {
 		})
 	})
 }
+
+func TestUUID_Time(t *testing.T) {
+	Convey("Given a UUID", t, func() {
+		Convey("When calling its Time method", func() {
+			Convey("It should return false for a non-version 1 UUID", func() {
+				_, ok := uuid.Time()
+				So(ok, ShouldBeFalse)
+			})
+
+			Convey("It should return the embedded time of a version 1 UUID", func() {
+				// c232ab00-9414-11ec-b3c8-9f6bdeced846
+				u := &UUID{
+					TimeLow:          0xc232ab00,
+					TimeMid:          0x9414,
+					TimeHiAndVersion: 0x11ec,
+					ClockSeqHiAndRes: 0xb3,
+					ClockSeqLow:      0xc8,
+					Node:             [6]uint8{0x9f, 0x6b, 0xde, 0xce, 0xd8, 0x46},
+				}
+
+				actual, ok := u.Time()
+				So(ok, ShouldBeTrue)
+				So(actual, ShouldEqual, time.Date(2022, 2, 22, 19, 22, 22, 0, time.UTC))
+			})
+		})
+	})
+}
+
+func TestUUID_Version(t *testing.T) {
+	Convey("Given a valid UUID", t, func() {
+		Convey("When calling its Version method", func() {
+			Convey("It should return the version nibble", func() {
+				So(uuid.Version(), ShouldEqual, 3)
+			})
+		})
+	})
+}
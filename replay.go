@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// replayConn replays the events of a capture written by -events-out, one event
+// per datagram, in place of the event server. Having replayed the capture the
+// given number of times, or endlessly given zero, it reports net.ErrClosed as
+// a closed connection would.
+type replayConn struct {
+	path  string
+	loops int
+
+	mu     sync.Mutex
+	closed bool
+	file   *os.File
+	events *p.EventReader
+	loop   int  // the loop in progress, counting from 1
+	read   bool // whether the loop in progress read any events
+}
+
+// newReplayConn returns a connection replaying the capture at path the given
+// number of times, or endlessly given zero. It opens the capture upon the
+// first read.
+func newReplayConn(path string, loops int) *replayConn {
+	return &replayConn{path: path, loops: loops}
+}
+
+// Read implements the io.Reader interface, reading the next event of the
+// capture into b. It refuses an event larger than b rather than truncate it.
+func (c *replayConn) Read(b []byte) (int, error) {
+	mb, err := c.next()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(mb) > len(b) {
+		// Don't hand a partial event to the parser. The next read replays
+		// the next event.
+		return 0, fmt.Errorf("event of %d bytes exceeds %d-byte datagram; increase -datagram-size", len(mb), len(b))
+	}
+
+	return copy(b, mb), nil
+}
+
+// ReadDatagram reads the next event of the capture into b as a single
+// datagram, reporting whether the event was larger than b and therefore
+// truncated (see readDatagram).
+func (c *replayConn) ReadDatagram(b []byte) (n int, truncated bool, err error) {
+	mb, err := c.next()
+	if err != nil {
+		return 0, false, err
+	}
+
+	return copy(b, mb), len(mb) > len(b), nil
+}
+
+// next returns the binary equivalent of the next event of the capture,
+// reopening the capture to begin each loop.
+func (c *replayConn) next() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		if c.closed {
+			return nil, net.ErrClosed
+		}
+
+		if c.file == nil {
+			if c.loops > 0 && c.loop >= c.loops {
+				return nil, net.ErrClosed
+			}
+
+			f, err := os.Open(c.path)
+			if err != nil {
+				return nil, c.fail(fmt.Errorf("opening capture: %w", err))
+			}
+			c.file, c.events, c.read = f, p.NewEventReader(f), false
+			c.loop++
+			log.Debugf("replaying %q (loop %d)", c.path, c.loop)
+		}
+
+		e, err := c.events.Read()
+		switch {
+		case errors.Is(err, io.EOF):
+			if err = c.closeFile(); err != nil {
+				return nil, c.fail(err)
+			}
+			if !c.read {
+				// An empty capture would otherwise loop endlessly.
+				return nil, net.ErrClosed
+			}
+
+			continue
+		case err != nil:
+			return nil, c.fail(fmt.Errorf("reading capture: %w", err))
+		}
+
+		c.read = true
+
+		mb, err := e.MarshalBinary()
+		if err != nil {
+			return nil, c.fail(err)
+		}
+
+		return mb, nil
+	}
+}
+
+// fail logs the error and closes the connection, since retrying a failed read
+// of the capture would fail again. It returns net.ErrClosed.
+func (c *replayConn) fail(err error) error {
+	log.Errorf("replaying %q: %v", c.path, err)
+	c.closed = true
+	_ = c.closeFile()
+
+	return net.ErrClosed
+}
+
+// closeFile closes the capture, ending the loop in progress.
+func (c *replayConn) closeFile() error {
+	if c.file == nil {
+		return nil
+	}
+
+	err := c.file.Close()
+	c.file, c.events = nil, nil
+
+	return err
+}
+
+// Write implements the io.Writer interface, discarding the introduction.
+func (c *replayConn) Write(b []byte) (int, error) { return len(b), nil }
+
+// LocalAddr implements the net.Conn interface, returning the capture's path.
+func (c *replayConn) LocalAddr() net.Addr { return replayAddr(c.path) }
+
+// RemoteAddr implements the net.Conn interface, returning the capture's path.
+func (c *replayConn) RemoteAddr() net.Addr { return replayAddr(c.path) }
+
+// SetDeadline implements the net.Conn interface. A capture never idles, so it
+// ignores the deadline.
+func (c *replayConn) SetDeadline(time.Time) error { return nil }
+
+// SetReadDeadline implements the net.Conn interface. A capture never idles, so
+// it ignores the deadline.
+func (c *replayConn) SetReadDeadline(time.Time) error { return nil }
+
+// SetWriteDeadline implements the net.Conn interface. Writes never block, so it
+// ignores the deadline.
+func (c *replayConn) SetWriteDeadline(time.Time) error { return nil }
+
+// Close implements the io.Closer interface.
+func (c *replayConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	return c.closeFile()
+}
+
+// replayAddr is the path of a replayed capture, serving as both ends of a
+// replayConn.
+type replayAddr string
+
+// Network implements the net.Addr interface.
+func (a replayAddr) Network() string { return "replay" }
+
+// String implements the net.Addr interface.
+func (a replayAddr) String() string { return string(a) }
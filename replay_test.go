@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_replayConn(t *testing.T) {
+	Convey("Given a capture of events", t, func() {
+		path := filepath.Join(t.TempDir(), "capture.bin")
+		So(writeEventsFile(path, validEvents, 0), ShouldBeNil)
+
+		Convey("When collecting events replayed in two loops", func() {
+			conn := newReplayConn(path, 2)
+			defer func() { _ = conn.Close() }()
+
+			f := new(findings)
+			err := collectEvents(context.Background(), conn, config{
+				Datagrams: len(validEvents) * 3,
+				Quiet:     true,
+				Size:      512,
+				Strict:    true,
+			}, f)
+
+			Convey("It should replay the events twice, then close", func() {
+				So(err, ShouldBeNil)
+				So(withoutReceivedAt(f.Events), ShouldResemble, append(append([]*p.Event{}, validEvents...), validEvents...))
+				So(conn.loop, ShouldEqual, 2)
+
+				_, err := conn.Read(make([]byte, 512))
+				So(errors.Is(err, net.ErrClosed), ShouldBeTrue)
+			})
+		})
+
+		Convey("When replaying it endlessly", func() {
+			conn := newReplayConn(path, 0)
+			defer func() { _ = conn.Close() }()
+
+			b := make([]byte, 512)
+			for i := 0; i < len(validEvents)*5; i++ {
+				_, err := conn.Read(b)
+				So(err, ShouldBeNil)
+			}
+
+			Convey("It should reopen the capture for each loop until closed", func() {
+				So(conn.loop, ShouldEqual, 5)
+				So(conn.Close(), ShouldBeNil)
+
+				_, err := conn.Read(b)
+				So(errors.Is(err, net.ErrClosed), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a capture of events larger than the datagram size", t, func() {
+		path := filepath.Join(t.TempDir(), "capture.bin")
+		So(writeEventsFile(path, validEvents, 0), ShouldBeNil)
+		conn := newReplayConn(path, 1)
+		defer func() { _ = conn.Close() }()
+
+		Convey("When reading an event into a small buffer", func() {
+			b := make([]byte, 8)
+			n, err := conn.Read(b)
+
+			Convey("It should refuse the event rather than truncate it", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "-datagram-size")
+				So(n, ShouldEqual, 0)
+			})
+
+			Convey("It should carry on with the next event", func() {
+				mb, err := validEvents[1].MarshalBinary()
+				So(err, ShouldBeNil)
+
+				b := make([]byte, 512)
+				n, err := conn.Read(b)
+				So(err, ShouldBeNil)
+				So(b[:n], ShouldResemble, mb)
+			})
+		})
+	})
+
+	Convey("Given a capture of an event exactly the datagram size", t, func() {
+		path := filepath.Join(t.TempDir(), "capture.bin")
+		So(writeEventsFile(path, validEvents[:2], 0), ShouldBeNil)
+		conn := newReplayConn(path, 1)
+		defer func() { _ = conn.Close() }()
+
+		mb, err := validEvents[0].MarshalBinary()
+		So(err, ShouldBeNil)
+
+		Convey("When reading it as a datagram", func() {
+			b := make([]byte, len(mb))
+			n, truncated, err := readDatagram(conn, b)
+
+			Convey("It should read the whole event untruncated", func() {
+				So(err, ShouldBeNil)
+				So(truncated, ShouldBeFalse)
+				So(b[:n], ShouldResemble, mb)
+			})
+
+			Convey("It should report a larger event as truncated", func() {
+				n, truncated, err := readDatagram(conn, b)
+				So(err, ShouldBeNil)
+				So(truncated, ShouldBeTrue)
+				So(n, ShouldEqual, len(b))
+			})
+		})
+	})
+
+	Convey("Given a replayed capture", t, func() {
+		path := filepath.Join(t.TempDir(), "capture.bin")
+		conn := newReplayConn(path, 1)
+
+		Convey("When using it as a net.Conn", func() {
+			var nc net.Conn = conn
+
+			Convey("It should name the capture as its addresses and accept deadlines", func() {
+				So(nc.LocalAddr().String(), ShouldEqual, path)
+				So(nc.RemoteAddr().Network(), ShouldEqual, "replay")
+				So(nc.SetDeadline(time.Now()), ShouldBeNil)
+				So(nc.SetWriteDeadline(time.Now()), ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given an empty capture replayed endlessly", t, func() {
+		path := filepath.Join(t.TempDir(), "empty.bin")
+		So(writeEventsFile(path, nil, 0), ShouldBeNil)
+		conn := newReplayConn(path, 0)
+
+		Convey("When reading from it", func() {
+			_, err := conn.Read(make([]byte, 512))
+
+			Convey("It should close rather than loop", func() {
+				So(errors.Is(err, net.ErrClosed), ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/syslog"
+	"strings"
+	"syscall"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// reporter delivers a report of findings.
+type reporter interface {
+	Report(f *findings) error
+}
+
+var (
+	_ reporter = terminalReporter{}
+	_ reporter = markdownReporter{}
+	_ reporter = (*syslogReporter)(nil)
+)
+
+// terminalReporter writes the rendered report of findings to w.
+type terminalReporter struct {
+	w io.Writer
+}
+
+// Report implements the reporter interface.
+func (t terminalReporter) Report(f *findings) error {
+	report, err := f.report()
+	if err != nil && !errors.Is(err, ErrMissingProtocol) {
+		return err
+	}
+
+	if _, werr := fmt.Fprintf(t.w, "\n\n%s\n\n", report); werr != nil {
+		return werr
+	}
+
+	return err
+}
+
+// brokenPipe returns true if err stems from writing to a pipe whose reader
+// closed it, as one reading only the head of the report does.
+func brokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrClosedPipe)
+}
+
+// markdownReporter writes the report of findings to w as GitHub-flavored
+// Markdown, for pasting into tickets and wikis: a heading per section label
+// and a Markdown table per table, holding the same data as the terminal's.
+type markdownReporter struct {
+	w io.Writer
+}
+
+// Report implements the reporter interface.
+func (m markdownReporter) Report(f *findings) error {
+	report, err := f.markdownReport()
+	if err != nil && !errors.Is(err, ErrMissingProtocol) {
+		return err
+	}
+
+	if _, werr := fmt.Fprintf(m.w, "%s\n", report); werr != nil {
+		return werr
+	}
+
+	return err
+}
+
+// syslogReporter sends each top-N finding to a syslog server as an individual
+// message tagged with its protocol.
+type syslogReporter struct {
+	w *syslog.Writer
+}
+
+// newSyslogReporter connects to the syslog server at target, which takes the
+// form network:host:port (e.g., udp:localhost:514).
+func newSyslogReporter(target string) (*syslogReporter, error) {
+	network, addr, ok := strings.Cut(target, ":")
+	if !ok || network == "" || addr == "" {
+		return nil, fmt.Errorf("syslog target %q is not in network:host:port form", target)
+	}
+
+	w, err := syslog.Dial(network, addr, syslog.LOG_NOTICE|syslog.LOG_DAEMON, "event-emitter-client")
+	if err != nil {
+		return nil, fmt.Errorf("dialing syslog %q: %w", target, err)
+	}
+
+	return &syslogReporter{w: w}, nil
+}
+
+// Close closes the connection to the syslog server.
+func (s *syslogReporter) Close() error { return s.w.Close() }
+
+// Report implements the reporter interface.
+func (s *syslogReporter) Report(f *findings) error {
+	if f.ByProtocol == nil {
+		f.populate()
+	}
+
+	sections := []struct {
+		proto   p.Protocol
+		finding string
+		m       map[p.Protocol]itemOccurrenceMap
+		count   int
+	}{
+		{p.SSH, "password", f.Passwords, 5},
+		{p.SSH, "username", f.Usernames, 5},
+		{p.TELNET, "password", f.Passwords, 5},
+		{p.TELNET, "username", f.Usernames, 5},
+		{p.HTTP, "user-agent", f.UserAgents, 30},
+		{p.SMTP, "email", f.Emails, 20},
+	}
+	for _, x := range sections {
+		if err := s.send(x.proto.String(), x.finding, x.m[x.proto].top(x.count)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.send("ALL", "submitter", f.submitterOccurrences().top(15)); err != nil {
+		return err
+	}
+
+	if f.ClusterPrefix > 0 || f.ClusterPrefix6 > 0 {
+		m, err := f.subnetOccurrences()
+		if err != nil {
+			return err
+		}
+
+		return s.send("ALL", "subnet", m.top(15))
+	}
+
+	return nil
+}
+
+func (s *syslogReporter) send(proto, finding string, items itemOccurrences) error {
+	for i, item := range items {
+		if item.Occurrence == 0 {
+			// padding
+			break
+		}
+
+		err := s.w.Notice(
+			fmt.Sprintf("protocol=%s finding=%s rank=%d value=%q count=%d",
+				proto, finding, i+1, item.Item, item.Occurrence,
+			),
+		)
+		if err != nil {
+			return fmt.Errorf("sending %s %s finding to syslog: %w", proto, finding, err)
+		}
+	}
+
+	return nil
+}
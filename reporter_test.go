@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_syslogReporter(t *testing.T) {
+	Convey("Given a local UDP syslog listener", t, func() {
+		l, err := net.ListenPacket("udp", "localhost:")
+		So(err, ShouldBeNil)
+		defer func() { _ = l.Close() }()
+
+		Convey("When reporting findings to it", func() {
+			sr, err := newSyslogReporter("udp:" + l.LocalAddr().String())
+			So(err, ShouldBeNil)
+			defer func() { _ = sr.Close() }()
+
+			So(sr.Report(&findings{Events: validEvents}), ShouldBeNil)
+
+			Convey("It should send one message per finding tagged with its protocol", func() {
+				var messages []string
+				b := make([]byte, 1024)
+				for {
+					_ = l.SetReadDeadline(time.Now().Add(250 * time.Millisecond))
+					n, _, err := l.ReadFrom(b)
+					if err != nil {
+						break
+					}
+					messages = append(messages, string(b[:n]))
+				}
+
+				// SSH: 2 passwords, 2 usernames; TELNET: 1 password, 1
+				// username; HTTP: 1 user-agent; SMTP: 1 email; 5 submitters
+				So(messages, ShouldHaveLength, 13)
+
+				all := strings.Join(messages, "\n")
+				So(all, ShouldContainSubstring, `protocol=SSH finding=password rank=1 value="Jackallava" count=1`)
+				So(all, ShouldContainSubstring, `protocol=SMTP finding=email rank=1 value="chloesmith263@test.net" count=1`)
+				So(all, ShouldContainSubstring, `protocol=ALL finding=submitter`)
+			})
+		})
+	})
+
+	Convey("Given a malformed syslog target", t, func() {
+		Convey("When creating a syslog reporter", func() {
+			Convey("It should return an error", func() {
+				_, err := newSyslogReporter("localhost")
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func Test_terminalReporter(t *testing.T) {
+	Convey("Given findings", t, func() {
+		f := &findings{Events: validEvents}
+
+		Convey("When reporting them to a writer", func() {
+			buf := new(bytes.Buffer)
+			err := terminalReporter{w: buf}.Report(f)
+
+			Convey("It should write the rendered report", func() {
+				So(err, ShouldBeNil)
+				So(buf.String(), ShouldContainSubstring, "What are the top 5 SSH passwords and users?")
+			})
+		})
+	})
+}
+
+// brokenPipeWriter accepts up to n bytes, then fails as a write to a pipe
+// whose reader closed it does.
+type brokenPipeWriter struct {
+	n       int
+	written bytes.Buffer
+}
+
+func (w *brokenPipeWriter) Write(b []byte) (int, error) {
+	if len(b) > w.n {
+		w.written.Write(b[:w.n])
+		n := w.n
+		w.n = 0
+
+		return n, &os.PathError{Op: "write", Path: "/dev/stdout", Err: syscall.EPIPE}
+	}
+	w.written.Write(b)
+	w.n -= len(b)
+
+	return len(b), nil
+}
+
+func Test_brokenPipe(t *testing.T) {
+	Convey("Given findings and a writer whose pipe breaks partway through the report", t, func() {
+		f := &findings{Events: validEvents}
+		w := &brokenPipeWriter{n: 64}
+
+		Convey("When reporting them to the writer", func() {
+			err := terminalReporter{w: w}.Report(f)
+
+			Convey("It should return an error recognized as a broken pipe", func() {
+				So(err, ShouldBeError)
+				So(brokenPipe(err), ShouldBeTrue)
+				So(brokenPipe(fmt.Errorf("generating report: %w", err)), ShouldBeTrue)
+				So(w.written.Len(), ShouldEqual, 64)
+			})
+		})
+	})
+
+	Convey("Given other errors", t, func() {
+		Convey("When checking whether they're broken pipes", func() {
+			Convey("It should recognize only closed pipes", func() {
+				So(brokenPipe(nil), ShouldBeFalse)
+				So(brokenPipe(errors.New("disk full")), ShouldBeFalse)
+				So(brokenPipe(io.ErrClosedPipe), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func Test_markdownReporter(t *testing.T) {
+	Convey("Given findings with a user-agent containing Markdown", t, func() {
+		ua := "Bot|v1 *beta* <x>"
+		events := append([]*p.Event{}, validEvents...)
+		for i := 0; i < 2; i++ {
+			events = append(events, &p.Event{
+				Protocol:     p.HTTP,
+				IP:           netip.MustParseAddr("1.2.3.4"),
+				Payload:      map[string]string{"user-agent": ua},
+				PayloadOrder: []string{"user-agent"},
+			})
+		}
+		f := &findings{Events: events, Width: 40}
+
+		Convey("When reporting them as Markdown", func() {
+			buf := new(bytes.Buffer)
+			err := markdownReporter{w: buf}.Report(f)
+			So(err, ShouldBeNil)
+			report := buf.String()
+
+			Convey("It should render headed Markdown tables without terminal colors", func() {
+				So(report, ShouldContainSubstring, "### What are the top 30 HTTP user-agents?")
+				So(report, ShouldContainSubstring, "| # | User-Agents | Count | % |\n| --- | --- | --- | --- |\n")
+				So(report, ShouldNotContainSubstring, "\u001B[")
+
+				for _, line := range strings.Split(report, "\n") {
+					if strings.HasPrefix(line, "|") {
+						So(line, ShouldEndWith, "|")
+					}
+				}
+			})
+
+			Convey("It should escape the user-agent in full alongside its count", func() {
+				So(report, ShouldContainSubstring, `| 1 | Bot\|v1 \*beta\* \<x\> | 2 | 66.7% |`)
+				So(report, ShouldContainSubstring, "OPR/47.0.2631.55 | 1 | 33.3% |")
+				So(f.Width, ShouldEqual, 40)
+			})
+		})
+	})
+}
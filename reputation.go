@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// knownBad labels submitters on the reputation list.
+const knownBad = "KNOWN BAD"
+
+// reputation is a list of known-malicious addresses and networks.
+type reputation struct {
+	addrs    map[netip.Addr]bool
+	prefixes []netip.Prefix
+}
+
+// readReputation reads IP addresses and CIDR networks, one per line. Blank
+// lines and lines beginning with # are ignored.
+func readReputation(r io.Reader) (*reputation, error) {
+	rep := &reputation{addrs: make(map[netip.Addr]bool)}
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.Contains(text, "/") {
+			prefix, err := netip.ParsePrefix(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rep.prefixes = append(rep.prefixes, prefix.Masked())
+
+			continue
+		}
+
+		addr, err := netip.ParseAddr(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rep.addrs[addr.Unmap()] = true
+	}
+
+	return rep, s.Err()
+}
+
+// readReputationFile reads the reputation list in the file at path.
+func readReputationFile(path string) (*reputation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return readReputation(f)
+}
+
+// bad reports whether the address, or a network containing it, is on the list.
+func (r *reputation) bad(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if r.addrs[addr] {
+		return true
+	}
+	for _, prefix := range r.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reputationOf returns the label of the submitter's reputation, if the
+// findings have a Reputation list.
+func (f *findings) reputationOf(addr netip.Addr) string {
+	if f.Reputation != nil && f.Reputation.bad(addr) {
+		return knownBad
+	}
+
+	return ""
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pterm/pterm"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_readReputation(t *testing.T) {
+	Convey("Given a list of addresses and networks with a comment", t, func() {
+		r := strings.NewReader("# known bad\n233.20.0.0/16\n\n192.0.2.7\n2001:db8::/32\n")
+
+		Convey("When reading and matching addresses against it", func() {
+			rep, err := readReputation(r)
+			So(err, ShouldBeNil)
+
+			Convey("It should match addresses within the networks and listed addresses", func() {
+				for addr, want := range map[string]bool{
+					"233.20.181.96":        true,
+					"::ffff:233.20.181.96": true,
+					"192.0.2.7":            true,
+					"192.0.2.8":            false,
+					"2001:db8::1":          true,
+					"106.67.111.15":        false,
+				} {
+					So(rep.bad(netip.MustParseAddr(addr)), ShouldEqual, want)
+				}
+			})
+		})
+	})
+
+	Convey("Given a malformed entry", t, func() {
+		r := strings.NewReader("192.0.2.7\n233.20.0.0/33\n")
+
+		Convey("When reading it", func() {
+			_, err := readReputation(r)
+
+			Convey("It should return an error citing the line", func() {
+				So(err, ShouldBeError)
+				So(err.Error(), ShouldStartWith, "line 2:")
+			})
+		})
+	})
+}
+
+func Test_findings_reputation(t *testing.T) {
+	Convey("Given findings with a reputation list matching one submitter", t, func() {
+		rep, err := readReputation(strings.NewReader("233.20.0.0/16\n"))
+		So(err, ShouldBeNil)
+
+		f := &findings{
+			Detail:     netip.MustParseAddr("233.20.181.96"),
+			Location:   time.UTC,
+			Reputation: rep,
+		}
+		for _, e := range validEvents {
+			f.Add(e)
+		}
+
+		Convey("When rendering the top submitters", func() {
+			s, err := f.topSubmitters(reportFormat{}, 10)
+			So(err, ShouldBeNil)
+			s = pterm.RemoveColorFromString(s)
+
+			Convey("It should mark only the matching submitter as known bad", func() {
+				So(s, ShouldContainSubstring, "Reputation")
+				So(strings.Count(s, knownBad), ShouldEqual, 1)
+				for _, line := range strings.Split(s, "\n") {
+					if strings.Contains(line, knownBad) {
+						So(line, ShouldContainSubstring, "233.20.181.96")
+					}
+				}
+			})
+		})
+
+		Convey("When rendering the detailed submitter", func() {
+			s, err := f.submitter(reportFormat{}, f.Detail)
+
+			Convey("It should mark its events as known bad", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "Reputation")
+				So(s, ShouldContainSubstring, knownBad)
+			})
+		})
+
+		Convey("When rendering the report without a list", func() {
+			f.Reputation = nil
+			s, err := f.topSubmitters(reportFormat{}, 10)
+
+			Convey("It should omit the column", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldNotContainSubstring, "Reputation")
+			})
+		})
+	})
+}
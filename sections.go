@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// reportPart is a labeled table of the report.
+type reportPart struct {
+	label string
+	body  string
+}
+
+// reportSection renders one or more parts of the report. A section whose
+// enabled func returns false is omitted, even if selected.
+type reportSection struct {
+	name    string
+	enabled func(f *findings) bool
+	render  func(f *findings, rf reportFormat) ([]reportPart, error)
+}
+
+// part renders a single-part section in the given format.
+func part(label string, rf reportFormat, render func(rf reportFormat) (string, error)) ([]reportPart, error) {
+	s, err := render(rf)
+	if err != nil {
+		return nil, err
+	}
+
+	return []reportPart{{label: label, body: s}}, nil
+}
+
+// reportSections are the report's sections in their default order.
+var reportSections = []reportSection{
+	{
+		name: "passwords",
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			var parts []reportPart
+			for _, proto := range []p.Protocol{p.SSH, p.TELNET} {
+				s, err := f.topPasswordsUsers(rf, proto, 5)
+				if s, err = f.soft(rf, s, err); err != nil {
+					return nil, err
+				}
+				parts = append(parts, reportPart{
+					label: fmt.Sprintf("What are the top 5 %s passwords and users?", proto.String()),
+					body:  s,
+				})
+			}
+
+			return parts, nil
+		},
+	},
+	{
+		name: "passwordlengths",
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part(fmt.Sprintf("What are the top %s and %s password lengths?", p.SSH.String(), p.TELNET.String()), rf,
+				f.passwordLengths,
+			)
+		},
+	},
+	{
+		name: "useragents",
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part(fmt.Sprintf("What are the top 30 %s user-agents?", p.HTTP.String()), rf,
+				func(rf reportFormat) (string, error) {
+					s, err := f.topUserAgents(rf, p.HTTP, 30)
+
+					return f.soft(rf, s, err)
+				},
+			)
+		},
+	},
+	{
+		name: "emails",
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part(fmt.Sprintf("What are the top 20 %s emails?", p.SMTP.String()), rf,
+				func(rf reportFormat) (string, error) {
+					s, err := f.topEmails(rf, p.SMTP, 20)
+
+					return f.soft(rf, s, err)
+				},
+			)
+		},
+	},
+	{
+		name:    "across",
+		enabled: func(f *findings) bool { return len(f.AcrossProtocols) > 0 },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			parts := make([]reportPart, 0, len(f.AcrossProtocols))
+			for _, key := range f.AcrossProtocols {
+				s, err := f.topAcrossProtocols(rf, key, 10)
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, reportPart{
+					label: fmt.Sprintf("What are the top 10 %ss across all protocols?", key),
+					body:  s,
+				})
+			}
+
+			return parts, nil
+		},
+	},
+	{
+		name:    "classify",
+		enabled: func(f *findings) bool { return f.Classify },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("How are payload values classified?", rf, f.classifications)
+		},
+	},
+	{
+		name:    "cardinality",
+		enabled: func(f *findings) bool { return f.Cardinality },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("How many distinct payload values were seen?", rf, f.cardinality)
+		},
+	},
+	{
+		name: "submitters",
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("Who are the top 15 subitters?", rf, func(rf reportFormat) (string, error) { return f.topSubmitters(rf, 15) })
+		},
+	},
+	{
+		name:    "subnets",
+		enabled: func(f *findings) bool { return f.ClusterPrefix > 0 || f.ClusterPrefix6 > 0 },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part(f.subnetsLabel(), rf, func(rf reportFormat) (string, error) { return f.topSubnets(rf, 15) })
+		},
+	},
+	{
+		name:    "countries",
+		enabled: func(f *findings) bool { return f.Geo != nil },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("Which countries are the top sources?", rf, func(rf reportFormat) (string, error) { return f.topCountries(rf, 15) })
+		},
+	},
+	{
+		name:    "latency",
+		enabled: func(f *findings) bool { return f.Latency.Count > 0 },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("How long did events take to arrive?", rf, f.latencyStats)
+		},
+	},
+	{
+		name:    "sizes",
+		enabled: func(f *findings) bool { return f.PayloadSizes },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("What are the payload sizes by protocol?", rf, f.sizeStatsByProtocol)
+		},
+	},
+	{
+		name:    "topology",
+		enabled: func(f *findings) bool { return f.Topology },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("Which protocols does each node emit?", rf, f.topology)
+		},
+	},
+	{
+		name:    "detail",
+		enabled: func(f *findings) bool { return f.Detail.IsValid() },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part(fmt.Sprintf("What events did %s submit?", f.Detail.String()), rf,
+				func(rf reportFormat) (string, error) { return f.submitter(rf, f.Detail) },
+			)
+		},
+	},
+	{
+		name:    "unknown",
+		enabled: func(f *findings) bool { return len(f.Unknown) > 0 },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("Which UNKNOWN protocol events arrived?", rf, f.unknownProtocols)
+		},
+	},
+	{
+		name:    "expectations",
+		enabled: func(f *findings) bool { return f.Expected != nil },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("Which events differ from expectations?", rf, f.expectations)
+		},
+	},
+	{
+		name:    "retransmissions",
+		enabled: func(f *findings) bool { return f.Copies != nil },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			var parts []reportPart
+			for _, section := range []struct {
+				label  string
+				render func(rf reportFormat) (string, error)
+			}{
+				{"How many copies of each event arrived?", f.retransmissionHistogram},
+				{"Which protocols' events were retransmitted most?", f.retransmissionsByProtocol},
+				{"Whose events were retransmitted most?", func(rf reportFormat) (string, error) { return f.retransmissionsBySubmitter(rf, 15) }},
+			} {
+				part, err := part(section.label, rf, section.render)
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, part...)
+			}
+
+			return parts, nil
+		},
+	},
+	{
+		name:    "invalid",
+		enabled: func(f *findings) bool { return f.ExplainInvalid },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("Why did events fail validation?", rf, func(rf reportFormat) (string, error) { return f.invalidSummary(rf, 15) })
+		},
+	},
+	{
+		name:    "crc",
+		enabled: func(f *findings) bool { return f.CRCSample > 0 },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("Which CRC-32 polynomial do invalid events match?", rf,
+				func(rf reportFormat) (string, error) { return f.checkSumPolynomials(rf, f.CRCSample) },
+			)
+		},
+	},
+	{
+		name:    "skew",
+		enabled: func(f *findings) bool { return f.SkewThreshold > 0 },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("Which events show clock skew?", rf, f.skewedEvents)
+		},
+	},
+	{
+		name:    "charset",
+		enabled: func(f *findings) bool { return f.CheckCharset },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("Which events have unprintable payloads?", rf, f.unprintablePayloads)
+		},
+	},
+	{
+		name:    "sequential",
+		enabled: func(f *findings) bool { return f.SequentialUUIDs },
+		render: func(f *findings, rf reportFormat) ([]reportPart, error) {
+			return part("Are event UUIDs predictably sequential?", rf, f.sequentialUUIDs)
+		},
+	},
+}
+
+// sectionNames returns the names of the report sections, comma-separated.
+func sectionNames() string {
+	names := make([]string, 0, len(reportSections))
+	for _, s := range reportSections {
+		names = append(names, s.name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// selectSections returns the named report sections in the given order, or all
+// of them in their default order given no names.
+func selectSections(names []string) ([]reportSection, error) {
+	if len(names) == 0 {
+		return reportSections, nil
+	}
+
+	selected := make([]reportSection, 0, len(names))
+NAMES:
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, s := range reportSections {
+			if s.name == name {
+				selected = append(selected, s)
+				continue NAMES
+			}
+		}
+
+		return nil, fmt.Errorf("unknown report section %q; use %s", name, sectionNames())
+	}
+
+	return selected, nil
+}
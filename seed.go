@@ -0,0 +1,18 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// newRand returns the random source shared by all randomized behavior, such
+// as -corrupt and -benchmark, seeded with seed for reproducible runs. A zero
+// seed is replaced by one from the clock. It also returns the seed used, so
+// a run can be reproduced.
+func newRand(seed int64) (*rand.Rand, int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return rand.New(rand.NewSource(seed)), seed
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_newRand(t *testing.T) {
+	Convey("Given two random sources with the same seed", t, func() {
+		a, seedA := newRand(1938)
+		b, seedB := newRand(1938)
+		So(seedA, ShouldEqual, 1938)
+		So(seedB, ShouldEqual, 1938)
+
+		Convey("When generating and corrupting events with each", func() {
+			run := func() [][]byte {
+				rng, _ := newRand(1938)
+				c := newCorrupter(0.5, rng)
+
+				var out [][]byte
+				for _, e := range generateEvents(rng, 20, 1602720000) {
+					b, err := e.MarshalBinary()
+					So(err, ShouldBeNil)
+					r, _ := c.corrupt(bytes.NewBuffer(b))
+					out = append(out, r.(*bytes.Buffer).Bytes())
+				}
+
+				return out
+			}
+
+			Convey("It should produce identical results", func() {
+				So(a.Int63(), ShouldEqual, b.Int63())
+				So(run(), ShouldResemble, run())
+			})
+		})
+	})
+
+	Convey("Given no seed", t, func() {
+		Convey("When creating a random source", func() {
+			_, seed := newRand(0)
+
+			Convey("It should seed it from the clock", func() {
+				So(seed, ShouldNotEqual, 0)
+			})
+		})
+	})
+}
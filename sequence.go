@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/binary"
+	"sort"
+	"strconv"
+
+	"github.com/pterm/pterm"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+const (
+	// sequentialMaxStep is the largest step between sorted UUIDs considered
+	// near-sequential. Version 1 UUIDs count time in 100-nanosecond intervals,
+	// so it spans 1.6 microseconds, and events emitted even a few microseconds
+	// apart step further.
+	sequentialMaxStep = 16
+
+	// sequentialMinRun is the fewest consecutive near-sequential UUIDs
+	// reported as a run.
+	sequentialMinRun = 4
+)
+
+// uuidRun is a run of sequential or near-sequential UUIDs.
+type uuidRun struct {
+	First, Last p.UUID
+	Length      int
+	MaxStep     uint64 // the largest step between adjacent UUIDs in the run
+}
+
+// uuidKey returns the UUID as a 128-bit value, split in halves: its version
+// and time, with the TimeLow least significant, and its clock sequence and
+// node. Incrementing a version 1 UUID's time or node increments the value.
+func uuidKey(u p.UUID) (hi, lo uint64) {
+	hi = uint64(u.TimeHiAndVersion)<<48 | uint64(u.TimeMid)<<32 | uint64(u.TimeLow)
+
+	var node [8]byte
+	copy(node[2:], u.Node[:])
+	lo = uint64(u.ClockSeqHiAndRes)<<56 | uint64(u.ClockSeqLow)<<48 | binary.BigEndian.Uint64(node[:])
+
+	return hi, lo
+}
+
+// uuidStep returns the step from UUID a to the greater UUID b if they differ
+// in only one half, as UUIDs generated by incrementing their time or their
+// node do. Otherwise, it returns false.
+func uuidStep(aHi, aLo, bHi, bLo uint64) (uint64, bool) {
+	switch {
+	case aHi == bHi:
+		return bLo - aLo, true
+	case aLo == bLo:
+		return bHi - aHi, true
+	}
+
+	return 0, false
+}
+
+// sequentialRuns sorts the UUIDs and returns the runs of at least minRun
+// distinct UUIDs each stepping no more than maxStep from the last.
+func sequentialRuns(uuids []p.UUID, maxStep uint64, minRun int) []uuidRun {
+	type value struct {
+		uuid   p.UUID
+		hi, lo uint64
+	}
+
+	values := make([]value, 0, len(uuids))
+	for _, u := range uuids {
+		hi, lo := uuidKey(u)
+		values = append(values, value{uuid: u, hi: hi, lo: lo})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].hi != values[j].hi {
+			return values[i].hi < values[j].hi
+		}
+
+		return values[i].lo < values[j].lo
+	})
+
+	var (
+		runs []uuidRun
+		run  = uuidRun{Length: 1}
+	)
+	flush := func() {
+		if run.Length >= minRun {
+			runs = append(runs, run)
+		}
+	}
+	for i, v := range values {
+		if i == 0 {
+			run.First, run.Last = v.uuid, v.uuid
+			continue
+		}
+
+		prev := values[i-1]
+		step, ok := uuidStep(prev.hi, prev.lo, v.hi, v.lo)
+		switch {
+		case ok && step == 0:
+			// A duplicate UUID neither extends nor breaks the run.
+		case ok && step <= maxStep:
+			run.Last = v.uuid
+			run.Length++
+			if step > run.MaxStep {
+				run.MaxStep = step
+			}
+		default:
+			flush()
+			run = uuidRun{First: v.uuid, Last: v.uuid, Length: 1}
+		}
+	}
+	if len(values) > 0 {
+		flush()
+	}
+
+	return runs
+}
+
+// sequentialUUIDs renders the runs of near-sequential UUIDs among the events,
+// which suggest the emitter's UUIDs are predictable.
+func (f *findings) sequentialUUIDs(rf reportFormat) (string, error) {
+	uuids := make([]p.UUID, 0, len(f.Events))
+	for _, e := range f.Events {
+		uuids = append(uuids, e.EventUUID)
+	}
+	runs := sequentialRuns(uuids, sequentialMaxStep, sequentialMinRun)
+
+	d := pterm.TableData{{"#", "First UUID", "Last UUID", "Length", "Max Step"}}
+	for i, run := range runs {
+		d = append(d,
+			[]string{
+				strconv.Itoa(i + 1),
+				run.First.String(),
+				run.Last.String(),
+				thousands(run.Length),
+				strconv.FormatUint(run.MaxStep, 10),
+			},
+		)
+	}
+	if len(runs) == 0 {
+		d = append(d, []string{"", "NO SEQUENTIAL", "UUIDS FOUND", "", ""})
+	}
+
+	return rf.table(d)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_sequentialRuns(t *testing.T) {
+	Convey("Given deliberately sequential UUIDs among unrelated ones", t, func() {
+		base := validEvents[0].EventUUID
+		var uuids []p.UUID
+		for i := 0; i < 10; i++ {
+			u := base
+			u.TimeLow += uint32(i * 3)
+			uuids = append(uuids, u)
+		}
+		for _, e := range validEvents[1:] {
+			uuids = append(uuids, e.EventUUID)
+		}
+
+		// shuffle the UUIDs, since detection mustn't depend on arrival order
+		rng := rand.New(rand.NewSource(1))
+		rng.Shuffle(len(uuids), func(i, j int) { uuids[i], uuids[j] = uuids[j], uuids[i] })
+
+		Convey("When detecting runs of sequential UUIDs", func() {
+			runs := sequentialRuns(uuids, sequentialMaxStep, sequentialMinRun)
+
+			Convey("It should detect the run", func() {
+				So(runs, ShouldHaveLength, 1)
+				So(runs[0].Length, ShouldEqual, 10)
+				So(runs[0].MaxStep, ShouldEqual, 3)
+				So(runs[0].First, ShouldResemble, base)
+				So(runs[0].Last.TimeLow, ShouldEqual, base.TimeLow+27)
+			})
+		})
+
+		Convey("When rendering the section for events with those UUIDs", func() {
+			f := &findings{SequentialUUIDs: true, Sections: []string{"sequential"}}
+			for _, u := range uuids {
+				e := *validEvents[0]
+				e.EventUUID = u
+				f.Events = append(f.Events, &e)
+			}
+			s, err := f.report()
+
+			Convey("It should report the run", func() {
+				So(err, ShouldBeNil)
+				So(s, ShouldContainSubstring, "Are event UUIDs predictably sequential?")
+				So(s, ShouldContainSubstring, base.String())
+			})
+		})
+	})
+
+	Convey("Given random UUIDs", t, func() {
+		rng := rand.New(rand.NewSource(1))
+		uuids := make([]p.UUID, 1000)
+		for i := range uuids {
+			u := &uuids[i]
+			u.TimeLow = rng.Uint32()
+			u.TimeMid = uint16(rng.Uint32())
+			u.TimeHiAndVersion = uint16(rng.Uint32())&0x0fff | 0x4000
+			u.ClockSeqHiAndRes = byte(rng.Uint32())&0x3f | 0x80
+			u.ClockSeqLow = byte(rng.Uint32())
+			rng.Read(u.Node[:])
+		}
+
+		Convey("When detecting runs of sequential UUIDs", func() {
+			runs := sequentialRuns(uuids, sequentialMaxStep, sequentialMinRun)
+
+			Convey("It shouldn't flag any", func() {
+				So(runs, ShouldBeEmpty)
+			})
+		})
+	})
+}
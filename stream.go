@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+const (
+	minReconnectBackoff = 100 * time.Millisecond
+	maxReconnectBackoff = 5 * time.Second
+)
+
+// dialFunc dials the event server.
+type dialFunc func(ctx context.Context) (net.Conn, error)
+
+// ErrNoResponse indicates the server sent nothing in response to the
+// introduction, as when the address is wrong. A UDP write succeeds whether or
+// not anyone is listening, so silence is the only sign.
+var ErrNoResponse = errors.New("no response to introduction; is the server running?")
+
+// introduce writes the introduction the server expects before it emits events.
+func introduce(conn net.Conn) error {
+	n, err := conn.Write([]byte("Feed me, Seymour!"))
+	if err != nil {
+		return fmt.Errorf("writing introduction: %w", err)
+	}
+	log.Debugf("wrote %d-byte introduction to the server", n)
+
+	return nil
+}
+
+// readFrame reads the bytes of a single event laid out as l from a stream,
+// relying on the event's Size field, which follows its NodeID and TimeStamp, to
+// find its end.
+func readFrame(r io.Reader, order binary.ByteOrder, l p.Layout) ([]byte, error) {
+	header := l.NodeID + l.TimeStamp + l.Size
+	b := make([]byte, header)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	size := frameUint(b[l.NodeID+l.TimeStamp:], order)
+	if size > math.MaxUint16 {
+		return nil, fmt.Errorf("event size %d overflows 16 bits", size)
+	}
+
+	b = append(b, make([]byte, l.FixedBytes()-header+int(size))...)
+	if _, err := io.ReadFull(r, b[header:]); err != nil {
+		// A stream ending mid-event is unexpected.
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// frameUint returns the unsigned integer in b, which is 1, 2, 4, or 8 bytes
+// wide.
+func frameUint(b []byte, order binary.ByteOrder) uint64 {
+	switch len(b) {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(order.Uint16(b))
+	case 4:
+		return uint64(order.Uint32(b))
+	default:
+		return order.Uint64(b)
+	}
+}
+
+// readStream reads events laid out as l from a stream connection, and writes
+// them wrapped in a bytes.Buffer to the datagrams channel. Should the server
+// close or reset the connection, readStream redials it, backing off between
+// attempts, and reintroduces itself. It gives up after the given number of
+// consecutive reconnects without receiving an event. A positive idle duration
+// closes the channel once no event follows the last within it, as does a
+// positive limit once the events read total at least that many bytes.
+func readStream(ctx context.Context, conn net.Conn, chDatagrams chan<- io.Reader, order binary.ByteOrder,
+	l p.Layout, dial dialFunc, reconnects int, idle time.Duration, limit int64,
+) {
+	defer close(chDatagrams)
+
+	log.Debug("reading events from the server stream")
+
+	// Close the connection readStream last dialed once the context is done,
+	// unblocking any pending read. The caller owns the original connection.
+	var (
+		mu       sync.Mutex
+		redialed net.Conn
+	)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		if redialed != nil {
+			_ = redialed.Close()
+		}
+		mu.Unlock()
+	}()
+
+	var (
+		attempts int
+		total    int64
+		received bool
+	)
+	for {
+		if idle > 0 && received {
+			if err := conn.SetReadDeadline(time.Now().Add(idle)); err != nil {
+				log.Errorf("setting read deadline: %v", err)
+				return
+			}
+		}
+
+		b, err := readFrame(conn, order, l)
+		switch {
+		case err == nil:
+			attempts = 0
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			log.Debugf("no events received in %s", idle)
+			return
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed),
+			errors.Is(err, syscall.ECONNRESET):
+			if attempts >= reconnects {
+				log.Debugf("connection closed after %d reconnects", attempts)
+				return
+			}
+			attempts++
+
+			if conn, err = redial(ctx, dial, attempts); err != nil {
+				log.Errorf("reconnecting: %v", err)
+				return
+			}
+
+			mu.Lock()
+			if redialed != nil {
+				_ = redialed.Close()
+			}
+			redialed = conn
+			if ctx.Err() != nil {
+				// The context finished while dialing.
+				_ = conn.Close()
+			}
+			mu.Unlock()
+
+			continue
+		default:
+			log.Errorf("reading event from stream: %v", err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case chDatagrams <- bytes.NewBuffer(b):
+		}
+		received = true
+
+		total += int64(len(b))
+		if limit > 0 && total >= limit {
+			log.Warnf("ingested %d bytes, reaching the %d-byte limit; reporting on what arrived", total, limit)
+			return
+		}
+	}
+}
+
+// redial waits for an exponential backoff given the attempt, then dials and
+// introduces itself to the server.
+func redial(ctx context.Context, dial dialFunc, attempt int) (net.Conn, error) {
+	backoff := minReconnectBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > maxReconnectBackoff {
+		backoff = maxReconnectBackoff
+	}
+	log.Debugf("reconnecting in %s (attempt %d)", backoff, attempt)
+
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.C:
+	}
+
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = introduce(conn); err != nil {
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	return conn, nil
+}
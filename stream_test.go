@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_readFrame(t *testing.T) {
+	Convey("Given a stream of marshaled events", t, func() {
+		buf := new(bytes.Buffer)
+		_, err := archiveEvents(buf, validEvents, 0, 0)
+		So(err, ShouldBeNil)
+
+		Convey("When calling the readFrame function", func() {
+			Convey("It should read each event's bytes", func() {
+				for _, e := range validEvents {
+					b, err := readFrame(buf, binary.BigEndian, p.Version1Layout)
+					So(err, ShouldBeNil)
+
+					mb, err := e.MarshalBinary()
+					So(err, ShouldBeNil)
+					So(b, ShouldResemble, mb)
+				}
+
+				_, err := readFrame(buf, binary.BigEndian, p.Version1Layout)
+				So(err, ShouldEqual, io.EOF)
+			})
+
+			Convey("It should return io.ErrUnexpectedEOF given a partial event", func() {
+				buf.Truncate(10)
+				_, err := readFrame(buf, binary.BigEndian, p.Version1Layout)
+				So(err, ShouldEqual, io.ErrUnexpectedEOF)
+			})
+		})
+	})
+}
+
+func Test_readFrame_layout(t *testing.T) {
+	Convey("Given a stream of events with a version 2 layout", t, func() {
+		buf := new(bytes.Buffer)
+		var want [][]byte
+		for _, e := range validEvents {
+			v2 := *e
+			v2.Version = p.Version2
+			v2.CheckSum = v2.ComputeCheckSum()
+
+			b, err := v2.MarshalBinary()
+			So(err, ShouldBeNil)
+			buf.Write(b)
+			want = append(want, b)
+		}
+
+		Convey("When calling the readFrame function with that layout", func() {
+			Convey("It should read each event's bytes", func() {
+				for _, b := range want {
+					actual, err := readFrame(buf, binary.BigEndian, p.Version2Layout)
+					So(err, ShouldBeNil)
+					So(actual, ShouldResemble, b)
+				}
+			})
+		})
+	})
+}
+
+func Test_collectEventsStream(t *testing.T) {
+	Convey("Given a TCP server that closes the connection after half the events", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		half := len(validEvents) / 2
+		addr, err := tcpServer(validEvents[:half], validEvents[half:])
+		So(err, ShouldBeNil)
+
+		conn, err := net.Dial("tcp", addr.String())
+		So(err, ShouldBeNil)
+		defer func() { _ = conn.Close() }()
+
+		Convey("When collecting every event", func() {
+			f := new(findings)
+			err := collectEvents(ctx, conn, config{
+				Address:    addr.String(),
+				Datagrams:  len(validEvents),
+				Network:    "tcp",
+				Quiet:      true,
+				Reconnects: 1,
+				Size:       minDatagramBytes,
+			}, f)
+
+			Convey("It should reconnect to receive the rest", func() {
+				So(err, ShouldBeNil)
+				So(withoutReceivedAt(f.Events), ShouldResemble, validEvents)
+			})
+		})
+
+		Convey("When collecting events up to a byte limit", func() {
+			f := new(findings)
+			err := collectEvents(ctx, conn, config{
+				Address:    addr.String(),
+				Datagrams:  len(validEvents),
+				LimitBytes: 1,
+				Network:    "tcp",
+				Quiet:      true,
+				Reconnects: 1,
+				Size:       minDatagramBytes,
+			}, f)
+
+			Convey("It should stop after the event reaching the limit", func() {
+				So(err, ShouldBeNil)
+				So(withoutReceivedAt(f.Events), ShouldResemble, validEvents[:1])
+			})
+		})
+	})
+}
+
+func Test_collectEventsStream_reset(t *testing.T) {
+	Convey("Given a TCP server that resets the connection after half the events", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		l, err := net.Listen("tcp", "localhost:")
+		So(err, ShouldBeNil)
+
+		half := len(validEvents) / 2
+		go func() {
+			c, err := l.Accept()
+			if err != nil {
+				panic(err)
+			}
+
+			if _, err = c.Read(make([]byte, 1024)); err != nil {
+				panic(err)
+			}
+			if _, err = archiveEvents(c, validEvents[:half], 0, 0); err != nil {
+				panic(err)
+			}
+
+			// Give the client time to read the events, then abort.
+			time.Sleep(100 * time.Millisecond)
+			_ = c.(*net.TCPConn).SetLinger(0)
+			_ = c.Close()
+
+			serveBatches(l, validEvents[half:])
+		}()
+
+		conn, err := net.Dial("tcp", l.Addr().String())
+		So(err, ShouldBeNil)
+		defer func() { _ = conn.Close() }()
+
+		Convey("When collecting every event", func() {
+			f := new(findings)
+			err := collectEvents(ctx, conn, config{
+				Address:    l.Addr().String(),
+				Datagrams:  len(validEvents),
+				Network:    "tcp",
+				Quiet:      true,
+				Reconnects: 1,
+				Size:       minDatagramBytes,
+			}, f)
+
+			Convey("It should reconnect to receive the rest", func() {
+				So(err, ShouldBeNil)
+				So(withoutReceivedAt(f.Events), ShouldResemble, validEvents)
+			})
+		})
+	})
+}
+
+func Test_collectEventsStream_idle(t *testing.T) {
+	Convey("Given a TCP server that goes idle without closing the connection", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		l, err := net.Listen("tcp", "localhost:")
+		So(err, ShouldBeNil)
+		defer func() { _ = l.Close() }()
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer func() { _ = c.Close() }()
+
+			if _, err = c.Read(make([]byte, 1024)); err != nil {
+				return
+			}
+			if _, err = archiveEvents(c, validEvents, 0, 0); err != nil {
+				return
+			}
+			<-done
+		}()
+
+		conn, err := net.Dial("tcp", l.Addr().String())
+		So(err, ShouldBeNil)
+		defer func() { _ = conn.Close() }()
+
+		Convey("When auto-counting the events", func() {
+			f := new(findings)
+			start := time.Now()
+			err := collectEvents(ctx, conn, config{
+				Address:     l.Addr().String(),
+				AutoCount:   true,
+				IdleTimeout: 100 * time.Millisecond,
+				Network:     "tcp",
+				Quiet:       true,
+				Reconnects:  1,
+				Size:        minDatagramBytes,
+			}, f)
+
+			Convey("It should stop once the server goes idle", func() {
+				So(err, ShouldBeNil)
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+				So(withoutReceivedAt(f.Events), ShouldResemble, validEvents)
+			})
+		})
+	})
+}
+
+// tcpServer accepts a connection for each batch of events, waits for the
+// client's introduction, writes the batch, and closes the connection.
+func tcpServer(batches ...[]*p.Event) (net.Addr, error) {
+	l, err := net.Listen("tcp", "localhost:")
+	if err != nil {
+		return nil, fmt.Errorf("binding to tcp localhost: %w", err)
+	}
+	serveBatches(l, batches...)
+
+	return l.Addr(), nil
+}
+
+// serveBatches serves each batch of events to a connection accepted on l, as
+// tcpServer describes, closing l afterward.
+func serveBatches(l net.Listener, batches ...[]*p.Event) {
+	go func() {
+		defer func() { _ = l.Close() }()
+
+		for _, batch := range batches {
+			c, err := l.Accept()
+			if err != nil {
+				panic(err)
+			}
+
+			if _, err = c.Read(make([]byte, 1024)); err != nil {
+				panic(err)
+			}
+			if _, err = archiveEvents(c, batch, 0, 0); err != nil {
+				panic(err)
+			}
+
+			_ = c.Close()
+		}
+	}()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+// summaryProtocols are the protocols OneLine always counts, in order.
+var summaryProtocols = []p.Protocol{p.SSH, p.TELNET, p.HTTP, p.SMTP}
+
+// OneLine summarizes the findings on a single line of space-separated
+// key=value pairs, such as:
+//
+//	events=5000 valid=4998 invalid=2 ssh=1200 telnet=800 http=2000 smtp=998 top_submitter=1.2.3.4(500)
+//
+// The invalid count includes malformed datagrams. Events discarded for
+// exceeding -max-payload-bytes are counted apart, as oversized following
+// invalid, if there are any. Protocols beyond the built-in four follow them, if
+// present, and the top submitter is omitted given no events.
+func (f *findings) OneLine() string {
+	if f.ByProtocol == nil {
+		f.populate()
+	}
+
+	valid := f.total()
+	invalid := f.Discarded["invalid"] + f.Discarded["malformed"]
+	oversized := f.Discarded["oversized"]
+	pairs := []string{
+		fmt.Sprintf("events=%d", valid+invalid+oversized),
+		fmt.Sprintf("valid=%d", valid),
+		fmt.Sprintf("invalid=%d", invalid),
+	}
+	if oversized > 0 {
+		pairs = append(pairs, fmt.Sprintf("oversized=%d", oversized))
+	}
+
+	count := func(proto p.Protocol) int {
+		if item := f.ByProtocol[proto]; item != nil {
+			return item.Occurrence
+		}
+
+		return 0
+	}
+	for _, proto := range summaryProtocols {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", summaryKey(proto), count(proto)))
+	}
+
+	var others []p.Protocol
+	for proto := range f.ByProtocol {
+		if !containsProtocol(summaryProtocols, proto) {
+			others = append(others, proto)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return dotProtocol(others[i]) < dotProtocol(others[j]) })
+	for _, proto := range others {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", summaryKey(proto), count(proto)))
+	}
+
+	if top := f.submitterOccurrences().top(1); len(top) > 0 {
+		pairs = append(pairs, fmt.Sprintf("top_submitter=%s(%d)", top[0].Item, top[0].Occurrence))
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// summaryKey returns the protocol's key in the one-line summary.
+func summaryKey(proto p.Protocol) string {
+	return strings.ToLower(strings.ReplaceAll(dotProtocol(proto), " ", "_"))
+}
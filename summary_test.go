@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	p "github.com/awoodbeck/event-emitter-client/protocol"
+)
+
+func Test_findings_OneLine(t *testing.T) {
+	Convey("Given findings with every valid event and some discarded events", t, func() {
+		f := &findings{Discarded: map[string]int{"invalid": 2, "malformed": 1}}
+		for _, e := range validEvents {
+			f.Add(e)
+		}
+
+		Convey("When summarizing them on one line", func() {
+			s := f.OneLine()
+
+			Convey("It should be a single line of key=value pairs", func() {
+				So(s, ShouldNotContainSubstring, "\n")
+				So(s, ShouldStartWith, fmt.Sprintf("events=%d valid=%d invalid=3 ssh=", len(validEvents)+3, len(validEvents)))
+			})
+
+			Convey("It should count what the aggregates count", func() {
+				pairs := make(map[string]string)
+				for _, pair := range strings.Fields(s) {
+					k, v, ok := strings.Cut(pair, "=")
+					So(ok, ShouldBeTrue)
+					pairs[k] = v
+				}
+
+				for _, proto := range summaryProtocols {
+					want := 0
+					if item := f.ByProtocol[proto]; item != nil {
+						want = item.Occurrence
+					}
+					So(pairs[strings.ToLower(proto.String())], ShouldEqual, strconv.Itoa(want))
+				}
+
+				top := f.submitterOccurrences().top(1)[0]
+				So(pairs["top_submitter"], ShouldEqual, fmt.Sprintf("%s(%d)", top.Item, top.Occurrence))
+			})
+		})
+	})
+
+	Convey("Given findings with oversized events discarded", t, func() {
+		f := &findings{Discarded: map[string]int{"invalid": 2, "malformed": 1, "oversized": 4}}
+		f.Add(validEvents[0])
+
+		Convey("When summarizing them on one line", func() {
+			s := f.OneLine()
+
+			Convey("It should count the oversized events apart from the invalid", func() {
+				So(s, ShouldStartWith, "events=8 valid=1 invalid=3 oversized=4 ssh=")
+			})
+		})
+	})
+
+	Convey("Given findings with an unknown protocol", t, func() {
+		f := new(findings)
+		f.Add(&p.Event{Protocol: 0x7777})
+
+		Convey("When summarizing them on one line", func() {
+			s := f.OneLine()
+
+			Convey("It should count the unknown protocol after the built-in four", func() {
+				So(s, ShouldContainSubstring, "smtp=0 unknown_0x7777=1 top_submitter=")
+			})
+		})
+	})
+}
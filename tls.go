@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+// newTLSConfig returns a TLS configuration verifying the server's certificate
+// against the CA certificates in the PEM file at caCert, or the system's if
+// caCert is empty. Given a client certificate and key, it presents them to the
+// server for mutual TLS.
+func newTLSConfig(caCert, clientCert, clientKey string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caCert != "" {
+		b, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificate: %w", err)
+		}
+
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no PEM-encoded certificates in %q", caCert)
+		}
+	}
+
+	switch {
+	case clientCert != "" && clientKey != "":
+		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	case clientCert != "" || clientKey != "":
+		return nil, fmt.Errorf("mutual TLS requires both a client certificate and key")
+	}
+
+	return cfg, nil
+}
+
+// dialServer dials the event server per the configuration, wrapping the
+// connection in TLS if the configuration calls for it.
+func dialServer(ctx context.Context, cfg config) (net.Conn, error) {
+	if cfg.Network == "unixgram" {
+		return dialUnixgram(ctx, cfg.Address)
+	}
+
+	var d net.Dialer
+	if !cfg.TLS {
+		return d.DialContext(ctx, cfg.Network, cfg.Address)
+	}
+
+	tlsCfg, err := newTLSConfig(cfg.CACert, cfg.ClientCert, cfg.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+
+	td := &tls.Dialer{NetDialer: &d, Config: tlsCfg}
+	conn, err := td.DialContext(ctx, cfg.Network, cfg.Address)
+	if err != nil {
+		return nil, explainTLSError(err)
+	}
+
+	return conn, nil
+}
+
+// explainTLSError adds the likely remedy to common TLS handshake errors.
+func explainTLSError(err error) error {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+		recordHeader     tls.RecordHeaderError
+	)
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return fmt.Errorf("TLS handshake: server certificate isn't signed by a trusted CA; check -ca-cert: %w", err)
+	case errors.As(err, &hostname):
+		return fmt.Errorf("TLS handshake: server certificate doesn't match the -address host: %w", err)
+	case errors.As(err, &invalid):
+		return fmt.Errorf("TLS handshake: server certificate is invalid (e.g., expired): %w", err)
+	case errors.As(err, &recordHeader):
+		return fmt.Errorf("TLS handshake: server doesn't appear to speak TLS: %w", err)
+	}
+
+	return fmt.Errorf("TLS handshake: %w", err)
+}
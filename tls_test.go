@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_dialServerTLS(t *testing.T) {
+	Convey("Given a TLS server with a self-signed certificate", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		dir := t.TempDir()
+		certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+		So(writeSelfSigned(certPath, keyPath), ShouldBeNil)
+
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		So(err, ShouldBeNil)
+
+		l, err := tls.Listen("tcp", "localhost:", &tls.Config{Certificates: []tls.Certificate{cert}})
+		So(err, ShouldBeNil)
+		defer func() { _ = l.Close() }()
+
+		cfg := config{
+			Address:   l.Addr().String(),
+			CACert:    certPath,
+			Datagrams: len(validEvents),
+			Network:   "tcp",
+			Quiet:     true,
+			Size:      minDatagramBytes,
+			TLS:       true,
+		}
+
+		Convey("When dialing it trusting its certificate", func() {
+			serveBatches(l, validEvents)
+			conn, err := dialServer(ctx, cfg)
+			So(err, ShouldBeNil)
+			defer func() { _ = conn.Close() }()
+
+			Convey("It should receive every event over TLS", func() {
+				f := new(findings)
+				So(collectEvents(ctx, conn, cfg, f), ShouldBeNil)
+				So(withoutReceivedAt(f.Events), ShouldResemble, validEvents)
+			})
+		})
+
+		Convey("When dialing it without trusting its certificate", func() {
+			go func() {
+				if c, err := l.Accept(); err == nil {
+					_ = c.(*tls.Conn).Handshake()
+					_ = c.Close()
+				}
+			}()
+			cfg.CACert = ""
+			_, err := dialServer(ctx, cfg)
+
+			Convey("It should explain the server certificate isn't trusted", func() {
+				So(err, ShouldBeError)
+				So(err.Error(), ShouldContainSubstring, "-ca-cert")
+			})
+		})
+	})
+
+	Convey("Given a client certificate without a key", t, func() {
+		Convey("When building the TLS configuration", func() {
+			_, err := newTLSConfig("", "cert.pem", "")
+
+			Convey("It should return an error", func() {
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+// writeSelfSigned writes a self-signed ECDSA certificate for localhost and its
+// private key as PEM files.
+func writeSelfSigned(certPath, keyPath string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// unixgramConn is a Unix datagram connection bound to a temporary socket
+// file, which it removes when closed.
+type unixgramConn struct {
+	*net.UnixConn
+
+	dir string
+}
+
+// Close closes the connection and removes its socket file.
+func (c *unixgramConn) Close() error {
+	err := c.UnixConn.Close()
+	if rmErr := os.RemoveAll(c.dir); err == nil {
+		err = rmErr
+	}
+
+	return err
+}
+
+// dialUnixgram connects to the Unix datagram socket at address. Unlike UDP, an
+// unbound Unix datagram socket has no address the server can reply to, so the
+// connection binds to a socket file in a temporary directory first.
+func dialUnixgram(ctx context.Context, address string) (net.Conn, error) {
+	dir, err := os.MkdirTemp("", "event-emitter-client-")
+	if err != nil {
+		return nil, err
+	}
+
+	d := net.Dialer{LocalAddr: &net.UnixAddr{Name: filepath.Join(dir, "client.sock"), Net: "unixgram"}}
+	conn, err := d.DialContext(ctx, "unixgram", address)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+
+		return nil, err
+	}
+
+	return &unixgramConn{UnixConn: conn.(*net.UnixConn), dir: dir}, nil
+}
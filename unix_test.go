@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// unixgramServer sends the events to the first client to introduce itself on
+// a Unix datagram socket in dir, returning the socket's path.
+func unixgramServer(dir string) (string, error) {
+	path := filepath.Join(dir, "server.sock")
+	s, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return "", err
+	}
+
+	go func() {
+		defer func() { _ = s.Close() }()
+
+		_, clientAddr, err := s.ReadFromUnix(make([]byte, 1024))
+		if err != nil {
+			panic(err)
+		}
+
+		for _, e := range validEvents {
+			b, err := e.MarshalBinary()
+			if err != nil {
+				panic(err)
+			}
+			if _, err = s.WriteToUnix(b, clientAddr); err != nil {
+				panic(err)
+			}
+		}
+	}()
+
+	return path, nil
+}
+
+func Test_dialUnixgram(t *testing.T) {
+	Convey("Given an event server on a Unix datagram socket", t, func() {
+		// Socket paths are limited to about 100 bytes, so keep them short.
+		dir, err := os.MkdirTemp("", "eec-")
+		So(err, ShouldBeNil)
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		path, err := unixgramServer(dir)
+		So(err, ShouldBeNil)
+
+		Convey("When collecting events from it", func() {
+			cfg := config{
+				Address:   path,
+				Datagrams: len(validEvents),
+				Network:   "unixgram",
+				Quiet:     true,
+				Size:      512,
+			}
+			conn, err := dialServer(context.Background(), cfg)
+			So(err, ShouldBeNil)
+			defer func() { _ = conn.Close() }()
+			local := conn.LocalAddr().String()
+
+			f := new(findings)
+			err = collectEvents(context.Background(), conn, cfg, f)
+
+			Convey("It should receive every event", func() {
+				So(err, ShouldBeNil)
+				So(withoutReceivedAt(f.Events), ShouldResemble, validEvents)
+			})
+
+			Convey("It should remove its socket file once closed", func() {
+				_, err := os.Stat(local)
+				So(err, ShouldBeNil)
+				So(conn.Close(), ShouldBeNil)
+				_, err = os.Stat(local)
+				So(os.IsNotExist(err), ShouldBeTrue)
+			})
+		})
+	})
+}